@@ -0,0 +1,274 @@
+// Command fakeoidc est un fournisseur OpenID Connect minimal pour tester la
+// connexion externe en local, sans compte chez un vrai fournisseur.
+//
+//	go run ./cmd/fakeoidc -addr :9000
+//
+// puis lancer l'application avec :
+//
+//	OIDC_PROVIDERS=fake
+//	OIDC_FAKE_ISSUER=http://localhost:9000
+//	OIDC_FAKE_CLIENT_ID=groupietracker
+//	OIDC_FAKE_CLIENT_SECRET=secret
+//	OIDC_FAKE_LABEL="Fake SSO"
+//
+// La page d'autorisation permet de choisir librement le sujet et l'email
+// renvoyés. Les clés et les codes ne vivent qu'en mémoire.
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyID = "fake-1"
+
+type authCode struct {
+	ClientID      string
+	RedirectURI   string
+	Challenge     string
+	Nonce         string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	ExpiresAt     time.Time
+}
+
+type provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!doctype html>
+<html lang="fr">
+<head><meta charset="utf-8"><title>Fake OIDC</title></head>
+<body style="font-family: sans-serif; max-width: 30rem; margin: 3rem auto;">
+  <h1>Fake OIDC</h1>
+  <p>Connexion pour <code>{{.Get "client_id"}}</code></p>
+  <form method="POST">
+    {{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+    {{end}}
+    <p><label>Sujet<br><input name="sub" value="user-1" required></label></p>
+    <p><label>Email<br><input name="email" type="email" value="user1@example.com"></label></p>
+    <p><label><input type="checkbox" name="email_verified" value="true" checked> Email vérifié</label></p>
+    <button type="submit">Autoriser</button>
+    <button type="submit" name="deny" value="1">Refuser</button>
+  </form>
+</body>
+</html>`))
+
+func main() {
+	addr := flag.String("addr", ":9000", "adresse d'écoute")
+	issuer := flag.String("issuer", "http://localhost:9000", "émetteur annoncé")
+	clientID := flag.String("client-id", "groupietracker", "client_id accepté")
+	clientSecret := flag.String("client-secret", "secret", "client_secret accepté")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("génération clé: %v", err)
+	}
+	p := &provider{
+		issuer:       strings.TrimSuffix(*issuer, "/"),
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		key:          key,
+		codes:        make(map[string]authCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+
+	log.Printf("Fournisseur OIDC factice sur %s (émetteur %s)", *addr, p.issuer)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (p *provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (p *provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "requête invalide", http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+	if params.Get("client_id") != p.clientID || params.Get("redirect_uri") == "" {
+		http.Error(w, "client_id ou redirect_uri invalide", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodGet {
+		_ = authorizePage.Execute(w, r.URL.Query())
+		return
+	}
+
+	redirect, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "redirect_uri invalide", http.StatusBadRequest)
+		return
+	}
+	query := redirect.Query()
+	query.Set("state", params.Get("state"))
+	switch {
+	case params.Get("deny") != "":
+		query.Set("error", "access_denied")
+	case params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "":
+		query.Set("error", "invalid_request")
+		query.Set("error_description", "PKCE S256 requis")
+	default:
+		code := randomString()
+		p.mu.Lock()
+		p.codes[code] = authCode{
+			ClientID:      p.clientID,
+			RedirectURI:   params.Get("redirect_uri"),
+			Challenge:     params.Get("code_challenge"),
+			Nonce:         params.Get("nonce"),
+			Subject:       params.Get("sub"),
+			Email:         params.Get("email"),
+			EmailVerified: params.Get("email_verified") == "true",
+			ExpiresAt:     time.Now().Add(time.Minute),
+		}
+		p.mu.Unlock()
+		query.Set("code", code)
+	}
+	redirect.RawQuery = query.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID != p.clientID || clientSecret != p.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	code, found := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !found || time.Now().After(code.ExpiresAt) ||
+		r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != code.RedirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != code.Challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":            p.issuer,
+		"sub":            code.Subject,
+		"aud":            code.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          code.Nonce,
+		"email":          code.Email,
+		"email_verified": code.EmailVerified,
+	}
+	idToken, err := p.sign(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// sign produit un JWT RS256
+func (p *provider) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,213 @@
+package src
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWrongPassword = errors.New("mot de passe actuel incorrect")
+	ErrNoPassword    = errors.New("votre compte n'a pas de mot de passe : utilisez « Mot de passe oublié » pour en définir un")
+	ErrEmailNotSent  = errors.New("l'email de confirmation n'a pas pu être envoyé, réessayez plus tard")
+)
+
+// ─── Base de données ─────────────────────────────────────────
+
+// ChangePassword remplace le mot de passe après vérification de l'actuel
+// et ferme toutes les sessions ouvertes
+func ChangePassword(db *sql.DB, user User, current, password string) error {
+	if user.PasswordHash == noPasswordHash {
+		return ErrNoPassword
+	}
+	if checkPassword(user.PasswordHash, current) != nil {
+		return ErrWrongPassword
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction mot de passe: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET password_hash = ?, updated_at = NOW() WHERE id = ?", hashed, user.ID); err != nil {
+		return fmt.Errorf("mise à jour mot de passe: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL", time.Now(), user.ID); err != nil {
+		return fmt.Errorf("annulation réinitialisations: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", user.ID); err != nil {
+		return fmt.Errorf("révocation sessions: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("validation mot de passe: %w", err)
+	}
+	return nil
+}
+
+// PendingEmailChange renvoie la nouvelle adresse en attente de confirmation
+func PendingEmailChange(db *sql.DB, userID int) (string, error) {
+	var email string
+	const query = `SELECT v.email FROM email_verifications v JOIN users u ON u.id = v.user_id
+WHERE v.user_id = ? AND v.used_at IS NULL AND v.expires_at > ? AND v.email <> u.email
+ORDER BY v.created_at DESC LIMIT 1`
+	err := db.QueryRow(query, userID, time.Now()).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("lecture changement email: %w", err)
+	}
+	return email, nil
+}
+
+// RequestEmailChange envoie un lien de confirmation à la nouvelle adresse ;
+// l'adresse du compte ne change qu'à son ouverture (voir VerifyEmail)
+func RequestEmailChange(db *sql.DB, user User, password, newEmail string) error {
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+	if addr, err := mail.ParseAddress(newEmail); err != nil || addr.Address != newEmail {
+		return errors.New("adresse email invalide")
+	}
+	if newEmail == user.Email {
+		return errors.New("c'est déjà l'adresse de votre compte")
+	}
+	if user.PasswordHash == noPasswordHash {
+		return ErrNoPassword
+	}
+	if checkPassword(user.PasswordHash, password) != nil {
+		return ErrWrongPassword
+	}
+	if _, err := GetUserByEmail(db, newEmail); err == nil {
+		return errors.New("un compte existe déjà avec cet email")
+	}
+
+	if err := SendEmailVerification(user, newEmail); err != nil {
+		return fmt.Errorf("%w: %v", ErrEmailNotSent, err)
+	}
+	go notifyEmailChange(user, newEmail)
+	return nil
+}
+
+func notifyEmailChange(user User, newEmail string) {
+	body := fmt.Sprintf(`Bonjour %s,
+
+Un changement de l'adresse email de votre compte Groupie Tracker vers %s a été demandé.
+Il ne prendra effet qu'une fois confirmé depuis la nouvelle adresse.
+
+Si vous n'êtes pas à l'origine de cette demande, changez votre mot de passe dès que possible : il a été utilisé pour la faire.
+`, user.Username, newEmail)
+
+	if err := CurrentMailer().Send(Mail{To: user.Email, Subject: "Changement d'adresse email demandé", Body: body}); err != nil {
+		log.Printf("Erreur envoi notification changement email à l'utilisateur %d: %v", user.ID, err)
+	}
+}
+
+func notifyPasswordChange(user User) {
+	body := fmt.Sprintf(`Bonjour %s,
+
+Le mot de passe de votre compte Groupie Tracker vient d'être modifié et toutes vos sessions ont été fermées.
+
+Si vous n'êtes pas à l'origine de ce changement, réinitialisez votre mot de passe immédiatement :
+
+%s/password/forgot
+`, user.Username, BaseURL)
+
+	if err := CurrentMailer().Send(Mail{To: user.Email, Subject: "Votre mot de passe a été modifié", Body: body}); err != nil {
+		log.Printf("Erreur envoi notification mot de passe à l'utilisateur %d: %v", user.ID, err)
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// profileUser renvoie l'utilisateur connecté pour les formulaires du profil
+func profileUser(w http.ResponseWriter, r *http.Request) (User, bool) {
+	userID, ok := SessionUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return User{}, false
+	}
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return User{}, false
+	}
+	return user, true
+}
+
+func profileNotice(w http.ResponseWriter, r *http.Request, notice, anchor string) {
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/profile"+anchor, http.StatusSeeOther)
+}
+
+func (s *Server) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	password := r.FormValue("password")
+	if password != r.FormValue("confirm_password") {
+		profileNotice(w, r, "Les mots de passe ne correspondent pas", "#security")
+		return
+	}
+	if err := ChangePassword(DB, user, r.FormValue("current_password"), password); err != nil {
+		notice := err.Error()
+		if !errors.Is(err, ErrWrongPassword) && !errors.Is(err, ErrNoPassword) && validatePassword(password) == nil {
+			log.Printf("Erreur changement mot de passe utilisateur %d: %v", user.ID, err)
+			notice = "Une erreur est survenue, veuillez réessayer"
+		}
+		profileNotice(w, r, notice, "#security")
+		return
+	}
+
+	Audit(r, AuditEvent{ActorID: user.ID, ActorName: user.Username, Action: AuditPasswordChange, TargetType: "user", TargetID: strconv.Itoa(user.ID)})
+
+	// Nouvelle session pour ce navigateur, les autres ont été fermées
+	if err := StartUserSession(w, r, user); err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	go notifyPasswordChange(user)
+	profileNotice(w, r, "Mot de passe modifié. Vos autres appareils ont été déconnectés.", "#security")
+}
+
+func (s *Server) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	newEmail := strings.ToLower(strings.TrimSpace(r.FormValue("email")))
+	if err := RequestEmailChange(DB, user, r.FormValue("password"), newEmail); err != nil {
+		notice := err.Error()
+		if errors.Is(err, ErrEmailNotSent) {
+			log.Printf("Erreur changement email utilisateur %d: %v", user.ID, err)
+			notice = ErrEmailNotSent.Error()
+		}
+		profileNotice(w, r, notice, "#security")
+		return
+	}
+	profileNotice(w, r, "Un lien de confirmation a été envoyé à "+newEmail+". Votre adresse actuelle reste active jusqu'à sa confirmation.", "#security")
+}
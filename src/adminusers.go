@@ -0,0 +1,368 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminUsersPageSize = 50
+
+// Statuts proposés dans le filtre de /admin/users
+const (
+	UserStatusActive     = "active"
+	UserStatusUnverified = "unverified"
+	UserStatusLocked     = "locked"
+	UserStatusSuspended  = "suspended"
+	UserStatusBanned     = "banned"
+	UserStatusDeleting   = "deleting"
+)
+
+type UserStatusOption struct {
+	Value string
+	Label string
+}
+
+var UserStatuses = []UserStatusOption{
+	{UserStatusActive, "Actif"},
+	{UserStatusUnverified, "Email non vérifié"},
+	{UserStatusLocked, "Verrouillé"},
+	{UserStatusSuspended, "Suspendu"},
+	{UserStatusBanned, "Banni"},
+	{UserStatusDeleting, "Suppression demandée"},
+}
+
+// Tris proposés, associés à une clause ORDER BY fixe
+var userSorts = map[string]string{
+	"recent":   "created_at DESC, id DESC",
+	"oldest":   "created_at ASC, id ASC",
+	"email":    "email ASC",
+	"username": "username ASC",
+	"role":     "role ASC, username ASC",
+}
+
+// UserFilter décrit la recherche de /admin/users, reprise par l'export CSV
+type UserFilter struct {
+	Search string // email, nom d'utilisateur ou pseudo
+	Role   string
+	Status string
+	Sort   string
+	Page   int
+}
+
+// ParseUserFilter lit les filtres depuis la query string
+func ParseUserFilter(q url.Values) UserFilter {
+	f := UserFilter{
+		Search: strings.TrimSpace(q.Get("q")),
+		Role:   strings.TrimSpace(q.Get("role")),
+		Status: q.Get("status"),
+		Sort:   q.Get("sort"),
+		Page:   1,
+	}
+	if _, ok := userSorts[f.Sort]; !ok {
+		f.Sort = "recent"
+	}
+	if page, err := strconv.Atoi(q.Get("page")); err == nil && page > 1 {
+		f.Page = page
+	}
+	return f
+}
+
+// Query renvoie les filtres sous forme de query string, sans la page
+func (f UserFilter) Query() string {
+	q := url.Values{}
+	if f.Search != "" {
+		q.Set("q", f.Search)
+	}
+	if f.Role != "" {
+		q.Set("role", f.Role)
+	}
+	if f.Status != "" {
+		q.Set("status", f.Status)
+	}
+	if f.Sort != "recent" {
+		q.Set("sort", f.Sort)
+	}
+	return q.Encode()
+}
+
+func (f UserFilter) pageURL(page int) string {
+	q, _ := url.ParseQuery(f.Query())
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if len(q) == 0 {
+		return "/admin/users"
+	}
+	return "/admin/users?" + q.Encode()
+}
+
+func (f UserFilter) where(now time.Time) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		clauses = append(clauses, "(email LIKE ? OR username LIKE ? OR pseudo LIKE ?)")
+		args = append(args, like, like, like)
+	}
+	if f.Role != "" {
+		clauses = append(clauses, "role = ?")
+		args = append(args, f.Role)
+	}
+	switch f.Status {
+	case UserStatusActive:
+		clauses = append(clauses, "banned_at IS NULL AND (suspended_until IS NULL OR suspended_until <= ?) AND (locked_until IS NULL OR locked_until <= ?) AND deletion_requested_at IS NULL")
+		args = append(args, now, now)
+	case UserStatusUnverified:
+		clauses = append(clauses, "email_verified_at IS NULL")
+	case UserStatusLocked:
+		clauses = append(clauses, "locked_until > ?")
+		args = append(args, now)
+	case UserStatusSuspended:
+		clauses = append(clauses, "banned_at IS NULL AND suspended_until > ?")
+		args = append(args, now)
+	case UserStatusBanned:
+		clauses = append(clauses, "banned_at IS NOT NULL")
+	case UserStatusDeleting:
+		clauses = append(clauses, "deletion_requested_at IS NOT NULL")
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// Colonnes lues pour l'administration : jamais le hash du mot de passe ni le secret TOTP
+const userListColumns = `id, username, email, pseudo, bio, photo_profil, COALESCE(role, 'user'), created_at, email_verified_at, locked_until, suspended_until, banned_at, sanction_reason, deletion_requested_at`
+
+func scanUserDisplay(rows *sql.Rows, now time.Time) (UserDisplay, error) {
+	var d UserDisplay
+	var u User
+	var createdAt time.Time
+	if err := rows.Scan(&d.ID, &d.Username, &d.Email, &u.Pseudo, &u.Bio, &u.PhotoProfil, &d.Role, &createdAt, &u.EmailVerifiedAt, &u.LockedUntil, &u.SuspendedUntil, &u.BannedAt, &u.SanctionReason, &u.DeletionRequestedAt); err != nil {
+		return UserDisplay{}, err
+	}
+	d.Pseudo = getStringValue(u.Pseudo)
+	d.Bio = getStringValue(u.Bio)
+	d.PhotoProfil = getStringValue(u.PhotoProfil)
+	d.CreatedAt = createdAt.Format("02/01/2006")
+	d.EmailVerified = u.EmailVerifiedAt.Valid
+	d.DeletionPending = u.DeletionRequestedAt.Valid
+	d.Impersonable = !IsStaffRole(d.Role)
+	if u.LockedUntil.Valid && u.LockedUntil.Time.After(now) {
+		d.Locked = true
+		d.LockedUntil = u.LockedUntil.Time.Format("02/01/2006 15:04")
+	}
+	if sanction, ok := u.ActiveSanction(now); ok {
+		d.Banned = sanction.Banned
+		if !sanction.Banned {
+			d.SuspendedUntil = sanction.Until.Format("02/01/2006 15:04")
+		}
+		d.SanctionReason = sanction.Reason
+	}
+	return d, nil
+}
+
+// SearchUsers renvoie une page d'utilisateurs ; limit <= 0 renvoie tous les résultats
+func SearchUsers(db *sql.DB, f UserFilter, limit int) ([]UserDisplay, error) {
+	now := time.Now()
+	where, args := f.where(now)
+	query := "SELECT " + userListColumns + " FROM users" + where + " ORDER BY " + userSorts[f.Sort]
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, (f.Page-1)*limit)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("liste utilisateurs: %w", err)
+	}
+	defer rows.Close()
+
+	var users []UserDisplay
+	for rows.Next() {
+		d, err := scanUserDisplay(rows, now)
+		if err != nil {
+			return nil, fmt.Errorf("scan utilisateur: %w", err)
+		}
+		users = append(users, d)
+	}
+	return users, rows.Err()
+}
+
+func CountUsers(db *sql.DB, f UserFilter) (int, error) {
+	where, args := f.where(time.Now())
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("comptage utilisateurs: %w", err)
+	}
+	return count, nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func adminUsersNotice(w http.ResponseWriter, r *http.Request, notice, redirect string) {
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// HandleAdminBulkUsers applique un changement de rôle ou une suppression à
+// plusieurs utilisateurs ; le compte de l'administrateur est toujours ignoré
+func (s *Server) HandleAdminBulkUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête invalide", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := SessionUserID(r)
+	returnQuery, _ := url.ParseQuery(r.FormValue("return"))
+	redirect := ParseUserFilter(returnQuery).pageURL(ParseUserFilter(returnQuery).Page)
+
+	var ids []int
+	for _, raw := range r.Form["user_ids"] {
+		if id, err := strconv.Atoi(raw); err == nil && id > 0 && id != adminID {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		adminUsersNotice(w, r, "Aucun utilisateur sélectionné", redirect)
+		return
+	}
+
+	// Chaque compte est vérifié : on ne retire ni ne supprime un rôle plus
+	// large que le sien
+	actorRole := CurrentUserRole(r)
+	allowed := func(from, to string) bool {
+		err := CanChangeRole(actorRole, from, to)
+		if err != nil && !roleChangeDenied(err) {
+			log.Printf("Erreur vérification changement de rôle: %v", err)
+		}
+		return err == nil
+	}
+	done, denied := 0, 0
+	switch r.FormValue("action") {
+	case "role":
+		role := strings.TrimSpace(r.FormValue("role"))
+		if exists, err := RoleExists(DB, role); err != nil || !exists {
+			http.Error(w, "Rôle invalide", http.StatusBadRequest)
+			return
+		}
+		if err := CanChangeRole(actorRole, RoleUser, role); roleChangeDenied(err) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		for _, id := range ids {
+			target, err := GetUserByID(DB, id)
+			if err != nil || target.Role == role {
+				continue
+			}
+			if !allowed(target.Role, role) {
+				denied++
+				continue
+			}
+			if err := UpdateUserRole(DB, id, role); err != nil {
+				log.Printf("Erreur mise à jour rôle utilisateur %d: %v", id, err)
+				continue
+			}
+			Audit(r, AuditEvent{Action: AuditRoleChange, TargetType: "user", TargetID: strconv.Itoa(id), Before: target.Role, After: role})
+			if err := RevokeUserSessions(DB, id); err != nil {
+				log.Printf("Erreur révocation sessions utilisateur %d: %v", id, err)
+			}
+			done++
+		}
+		adminUsersNotice(w, r, fmt.Sprintf("Rôle « %s » attribué à %d utilisateur(s)", role, done)+deniedNotice(denied), redirect)
+
+	case "delete":
+		// Confirmation explicite, en plus de celle demandée par le navigateur
+		if r.FormValue("confirm") != "yes" {
+			adminUsersNotice(w, r, "Cochez la case de confirmation pour supprimer les comptes sélectionnés", redirect)
+			return
+		}
+		for _, id := range ids {
+			target, err := GetUserByID(DB, id)
+			if err != nil {
+				continue
+			}
+			if !allowed(target.Role, "") {
+				denied++
+				continue
+			}
+			if err := DeleteUser(DB, id); err != nil {
+				log.Printf("Erreur suppression utilisateur %d: %v", id, err)
+				continue
+			}
+			Audit(r, AuditEvent{Action: AuditUserDelete, TargetType: "user", TargetID: strconv.Itoa(id), Before: target.Username + " <" + target.Email + ">"})
+			done++
+		}
+		adminUsersNotice(w, r, fmt.Sprintf("%d utilisateur(s) supprimé(s)", done)+deniedNotice(denied), redirect)
+
+	default:
+		http.Error(w, "Action inconnue", http.StatusBadRequest)
+	}
+}
+
+// deniedNotice complète le message d'une action groupée par le nombre de
+// comptes ignorés faute de droits suffisants
+func deniedNotice(denied int) string {
+	if denied == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d ignoré(s) : rôle réservé ou plus large que le vôtre", denied)
+}
+
+// HandleAdminUsersExport télécharge au format CSV les utilisateurs correspondant aux filtres
+func (s *Server) HandleAdminUsersExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	filter := ParseUserFilter(r.URL.Query())
+	users, err := SearchUsers(DB, filter, 0)
+	if err != nil {
+		log.Printf("Erreur export utilisateurs: %v", err)
+		http.Error(w, "Erreur lors de l'export des utilisateurs", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("utilisateurs-%s.csv", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	// BOM pour qu'Excel détecte l'UTF-8
+	io.WriteString(w, "\ufeff")
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"ID", "Nom d'utilisateur", "Pseudo", "Email", "Email vérifié", "Rôle", "Statut", "Inscription"})
+	for _, u := range users {
+		verified := "non"
+		if u.EmailVerified {
+			verified = "oui"
+		}
+		record := []string{strconv.Itoa(u.ID), u.Username, u.Pseudo, u.Email, verified, u.Role, u.StatusLabel(), u.CreatedAt}
+		for i := range record {
+			record[i] = csvSafe(record[i])
+		}
+		if err := cw.Write(record); err != nil {
+			log.Printf("Erreur écriture export utilisateurs: %v", err)
+			return
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Erreur écriture export utilisateurs: %v", err)
+	}
+	Audit(r, AuditEvent{Action: AuditUsersExport, After: filter.Query()})
+}
@@ -0,0 +1,478 @@
+package src
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	APIVersionPrefix = "/api/v1"
+	DefaultPerPage   = 20
+	MaxPerPage       = 100
+)
+
+// APIError est l'enveloppe d'erreur commune à toutes les routes /api/v1
+type APIError struct {
+	Error APIErrorBody `json:"error"`
+}
+
+type APIErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIPage est l'enveloppe des réponses paginées
+type APIPage struct {
+	Data interface{} `json:"data"`
+	Meta APIPageMeta `json:"meta"`
+}
+
+type APIPageMeta struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// APIItem est l'enveloppe des réponses portant un seul objet
+type APIItem struct {
+	Data interface{} `json:"data"`
+}
+
+type APIArtist struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Image        string   `json:"image"`
+	Members      []string `json:"members"`
+	CreationDate int      `json:"creation_date"`
+	FirstAlbum   string   `json:"first_album"`
+	Locations    []string `json:"locations"`
+	ConcertCount int      `json:"concert_count"`
+}
+
+type APIArtistDetail struct {
+	APIArtist
+	ConcertDates []string      `json:"concert_dates"`
+	Relations    []APIRelation `json:"relations"`
+}
+
+type APIRelation struct {
+	Location string   `json:"location"`
+	Label    string   `json:"label"`
+	Dates    []string `json:"dates"`
+}
+
+type APILocation struct {
+	Location     string `json:"location"`
+	Label        string `json:"label"`
+	ArtistIDs    []int  `json:"artist_ids"`
+	ConcertCount int    `json:"concert_count"`
+}
+
+type APIConcert struct {
+	ArtistID   int    `json:"artist_id"`
+	ArtistName string `json:"artist_name"`
+	Location   string `json:"location"`
+	Label      string `json:"label"`
+	Date       string `json:"date"`
+}
+
+type APIMember struct {
+	Name       string `json:"name"`
+	ArtistID   int    `json:"artist_id"`
+	ArtistName string `json:"artist_name"`
+}
+
+type APIUser struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Pseudo      string `json:"pseudo"`
+	Bio         string `json:"bio"`
+	PhotoProfil string `json:"photo_profil"`
+	Role        string `json:"role"`
+}
+
+type APISearchResult struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	ArtistID   int    `json:"artist_id"`
+	ArtistName string `json:"artist_name"`
+}
+
+// HandleAPIArtists liste les artistes avec recherche, filtres et pagination
+func (s *Server) HandleAPIArtists(w http.ResponseWriter, r *http.Request) {
+	// L'API publique ne connaît pas l'utilisateur : ses favoris sont servis
+	// par /me/favorites, plutôt que d'ignorer le filtre sans prévenir
+	if r.URL.Query().Has("favorites") {
+		WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "Paramètre favorites non supporté, utilisez "+APIVersionPrefix+"/me/favorites")
+		return
+	}
+	filters, err := ParseArtistFilters(r.URL.Query())
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+	page, perPage, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	filtered := ApplyArtistFilters(s.ListArtists(), filters)
+	items := make([]APIArtist, 0, len(filtered))
+	for _, art := range filtered {
+		items = append(items, NewAPIArtist(art))
+	}
+	WriteAPIJSON(w, r, http.StatusOK, paginate(items, page, perPage))
+}
+
+// HandleAPIArtist renvoie le détail d'un artiste et ses relations dates / lieux
+func (s *Server) HandleAPIArtist(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "Identifiant invalide")
+		return
+	}
+	art, ok := s.FindArtist(id)
+	if !ok {
+		WriteAPIError(w, http.StatusNotFound, "not_found", "Artiste introuvable")
+		return
+	}
+	WriteAPIJSON(w, r, http.StatusOK, APIItem{Data: NewAPIArtistDetail(art)})
+}
+
+// HandleAPILocations liste tous les lieux de concert connus
+func (s *Server) HandleAPILocations(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	needle := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+
+	byLocation := make(map[string]*APILocation)
+	for _, art := range s.ListArtists() {
+		for location, dates := range art.DatesLocations {
+			entry, exists := byLocation[location]
+			if !exists {
+				entry = &APILocation{Location: location, Label: FormatLocation(location)}
+				byLocation[location] = entry
+			}
+			entry.ArtistIDs = append(entry.ArtistIDs, art.ID)
+			entry.ConcertCount += len(CleanDates(dates))
+		}
+	}
+
+	items := make([]APILocation, 0, len(byLocation))
+	for _, entry := range byLocation {
+		if needle != "" && !strings.Contains(strings.ToLower(entry.Label), needle) {
+			continue
+		}
+		sort.Ints(entry.ArtistIDs)
+		items = append(items, *entry)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	WriteAPIJSON(w, r, http.StatusOK, paginate(items, page, perPage))
+}
+
+// HandleAPIConcerts liste les concerts, filtrables par artiste, lieu et période
+func (s *Server) HandleAPIConcerts(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	artistID := 0
+	if raw := query.Get("artist_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "paramètre artist_id invalide")
+			return
+		}
+		artistID = id
+	}
+	location := strings.ToLower(strings.TrimSpace(query.Get("location")))
+	from, to, ok := parseDateRange(w, query.Get("from"), query.Get("to"))
+	if !ok {
+		return
+	}
+
+	items := make([]APIConcert, 0)
+	for _, art := range s.ListArtists() {
+		if artistID != 0 && art.ID != artistID {
+			continue
+		}
+		for loc, dates := range art.DatesLocations {
+			label := FormatLocation(loc)
+			if location != "" && !strings.Contains(strings.ToLower(label), location) && !strings.Contains(strings.ToLower(loc), location) {
+				continue
+			}
+			for _, date := range CleanDates(dates) {
+				if !from.IsZero() || !to.IsZero() {
+					when, err := ParseConcertDate(date)
+					if err != nil || (!from.IsZero() && when.Before(from)) || (!to.IsZero() && when.After(to)) {
+						continue
+					}
+				}
+				items = append(items, APIConcert{
+					ArtistID:   art.ID,
+					ArtistName: art.Name,
+					Location:   loc,
+					Label:      label,
+					Date:       date,
+				})
+			}
+		}
+	}
+	sortConcerts(items)
+	WriteAPIJSON(w, r, http.StatusOK, paginate(items, page, perPage))
+}
+
+// HandleAPIMembers liste les membres de tous les groupes
+func (s *Server) HandleAPIMembers(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	needle := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+
+	items := make([]APIMember, 0)
+	for _, art := range s.ListArtists() {
+		for _, member := range art.Members {
+			if needle != "" && !strings.Contains(strings.ToLower(member), needle) {
+				continue
+			}
+			items = append(items, APIMember{Name: member, ArtistID: art.ID, ArtistName: art.Name})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].ArtistID < items[j].ArtistID
+	})
+	WriteAPIJSON(w, r, http.StatusOK, paginate(items, page, perPage))
+}
+
+// HandleAPISearch renvoie des suggestions typées (artiste, membre, lieu, dates)
+func (s *Server) HandleAPISearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "paramètre q manquant")
+		return
+	}
+	page, perPage, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	WriteAPIJSON(w, r, http.StatusOK, paginate(SearchArtists(s.ListArtists(), query), page, perPage))
+}
+
+// HandleAPINotFound répond aux routes /api/v1 inconnues avec l'enveloppe d'erreur
+func (s *Server) HandleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		WriteAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Méthode non supportée")
+		return
+	}
+	WriteAPIError(w, http.StatusNotFound, "not_found", "Ressource introuvable")
+}
+
+// SearchArtists construit les suggestions de recherche pour une requête
+func SearchArtists(artists []Artist, query string) []APISearchResult {
+	needle := strings.ToLower(query)
+	results := make([]APISearchResult, 0)
+	for _, art := range artists {
+		add := func(kind, value string) {
+			results = append(results, APISearchResult{Type: kind, Value: value, ArtistID: art.ID, ArtistName: art.Name})
+		}
+		if strings.Contains(strings.ToLower(art.Name), needle) {
+			add("artist", art.Name)
+		}
+		for _, member := range art.Members {
+			if strings.Contains(strings.ToLower(member), needle) {
+				add("member", member)
+			}
+		}
+		for _, location := range art.Locations {
+			if strings.Contains(strings.ToLower(location), needle) || strings.Contains(strings.ToLower(FormatLocation(location)), needle) {
+				add("location", FormatLocation(location))
+			}
+		}
+		if strings.Contains(strconv.Itoa(art.CreationDate), needle) {
+			add("creation_date", strconv.Itoa(art.CreationDate))
+		}
+		if strings.Contains(strings.ToLower(art.FirstAlbum), needle) {
+			add("first_album", art.FirstAlbum)
+		}
+	}
+	return results
+}
+
+func NewAPIArtist(art Artist) APIArtist {
+	members := art.Members
+	if members == nil {
+		members = []string{}
+	}
+	locations := art.Locations
+	if locations == nil {
+		locations = []string{}
+	}
+	return APIArtist{
+		ID:           art.ID,
+		Name:         art.Name,
+		Image:        art.Image,
+		Members:      members,
+		CreationDate: art.CreationDate,
+		FirstAlbum:   art.FirstAlbum,
+		Locations:    locations,
+		ConcertCount: len(art.ConcertDates),
+	}
+}
+
+func NewAPIArtistDetail(art Artist) APIArtistDetail {
+	dates := art.ConcertDates
+	if dates == nil {
+		dates = []string{}
+	}
+	relations := make([]APIRelation, 0, len(art.DatesLocations))
+	for _, ld := range BuildLocationDates(art.DatesLocations) {
+		relations = append(relations, APIRelation{Location: ld.Raw, Label: ld.Pretty, Dates: ld.Dates})
+	}
+	return APIArtistDetail{
+		APIArtist:    NewAPIArtist(art),
+		ConcertDates: dates,
+		Relations:    relations,
+	}
+}
+
+// WriteAPIJSON encode la réponse, pose un ETag et répond 304 si le client est à jour.
+// Les réponses sont publiques en cache sauf si le handler a déjà posé Cache-Control.
+func WriteAPIJSON(w http.ResponseWriter, r *http.Request, status int, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("encodage JSON API: %v", err)
+		WriteAPIError(w, http.StatusInternalServerError, "internal_error", "Une erreur est survenue")
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+	}
+	if status == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// WriteAPIError renvoie une erreur dans l'enveloppe commune de l'API
+func WriteAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(APIError{Error: APIErrorBody{Code: code, Message: message}})
+}
+
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	page, perPage := 1, DefaultPerPage
+	query := r.URL.Query()
+	if raw := query.Get("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "paramètre page invalide")
+			return 0, 0, false
+		}
+		page = n
+	}
+	if raw := query.Get("per_page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > MaxPerPage {
+			WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "paramètre per_page invalide (1 à "+strconv.Itoa(MaxPerPage)+")")
+			return 0, 0, false
+		}
+		perPage = n
+	}
+	return page, perPage, true
+}
+
+func paginate[T any](items []T, page, perPage int) APIPage {
+	total := len(items)
+	// page est comparée avant la multiplication : une page énorme dépasserait
+	// la capacité d'un int et donnerait un début négatif
+	start := total
+	if page-1 <= total/perPage {
+		start = min((page-1)*perPage, total)
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	return APIPage{
+		Data: items[start:end],
+		Meta: APIPageMeta{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      total,
+			TotalPages: (total + perPage - 1) / perPage,
+		},
+	}
+}
+
+func sortConcerts(items []APIConcert) {
+	sort.SliceStable(items, func(i, j int) bool {
+		di, erri := ParseConcertDate(items[i].Date)
+		dj, errj := ParseConcertDate(items[j].Date)
+		if erri == nil && errj == nil && !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		if items[i].ArtistID != items[j].ArtistID {
+			return items[i].ArtistID < items[j].ArtistID
+		}
+		return items[i].Location < items[j].Location
+	})
+}
+
+// parseDateRange lit les bornes from / to au format AAAA-MM-JJ
+func parseDateRange(w http.ResponseWriter, rawFrom, rawTo string) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	var err error
+	if rawFrom != "" {
+		if from, err = time.Parse("2006-01-02", rawFrom); err != nil {
+			WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "paramètre from invalide (AAAA-MM-JJ)")
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if rawTo != "" {
+		if to, err = time.Parse("2006-01-02", rawTo); err != nil {
+			WriteAPIError(w, http.StatusBadRequest, "invalid_parameter", "paramètre to invalide (AAAA-MM-JJ)")
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	return from, to, true
+}
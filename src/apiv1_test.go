@@ -0,0 +1,34 @@
+package src
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name          string
+		page, perPage int
+		want          []int
+	}{
+		{"première page", 1, 2, []int{1, 2}},
+		{"dernière page incomplète", 3, 2, []int{5}},
+		{"au-delà de la fin", 4, 2, []int{}},
+		{"page énorme", 461168601842738800, 20, []int{}},
+		{"page maximale", math.MaxInt, MaxPerPage, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := paginate(items, tt.page, tt.perPage).Data.([]int)
+			if len(got) != len(tt.want) {
+				t.Fatalf("paginate(%d, %d) = %v, attendu %v", tt.page, tt.perPage, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("paginate(%d, %d) = %v, attendu %v", tt.page, tt.perPage, got, tt.want)
+				}
+			}
+		})
+	}
+}
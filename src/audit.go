@@ -0,0 +1,386 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions enregistrées dans le journal d'audit
+const (
+	AuditLogin           = "auth.login"
+	AuditLoginLocked     = "auth.locked"
+	AuditLogout          = "auth.logout"
+	AuditLogoutAll       = "auth.logout_all"
+	AuditRegister        = "auth.register"
+	AuditPasswordChange  = "auth.password_change"
+	AuditPasswordReset   = "auth.password_reset"
+	AuditEmailChange     = "auth.email_change"
+	AuditTwoFactorOn     = "auth.2fa_enable"
+	AuditTwoFactorOff    = "auth.2fa_disable"
+	AuditSessionRevoke   = "auth.session_revoke"
+	AuditIdentityLink    = "auth.identity_link"
+	AuditIdentityUnlink  = "auth.identity_unlink"
+	AuditTokenCreate     = "token.create"
+	AuditTokenRevoke     = "token.revoke"
+	AuditDeletionRequest = "account.deletion_request"
+	AuditDeletionCancel  = "account.deletion_cancel"
+	AuditAccountPurged   = "account.deleted"
+	AuditRoleChange      = "admin.user_role"
+	AuditUserDelete      = "admin.user_delete"
+	AuditUserUnlock      = "admin.user_unlock"
+	AuditUserSuspend     = "admin.user_suspend"
+	AuditUserBan         = "admin.user_ban"
+	AuditUserReinstate   = "admin.user_reinstate"
+	AuditSuspensionEnded = "account.suspension_ended"
+	AuditRoleCreate      = "admin.role_create"
+	AuditRoleUpdate      = "admin.role_update"
+	AuditRoleDelete      = "admin.role_delete"
+	AuditCommentModerate = "admin.comment_delete"
+	AuditDataRefresh     = "admin.data_refresh"
+	AuditExport          = "admin.audit_export"
+	AuditUsersExport     = "admin.users_export"
+	AuditImpersonateOn   = "admin.impersonate_start"
+	AuditImpersonateOff  = "admin.impersonate_stop"
+	AuditArtistOverride  = "admin.artist_override"
+	AuditArtistRevert    = "admin.artist_revert"
+	AuditArtistCreate    = "admin.artist_create"
+	AuditArtistUpdate    = "admin.artist_update"
+	AuditArtistDelete    = "admin.artist_delete"
+
+	auditPageSize = 100
+)
+
+// AuditActions liste les actions proposées dans le filtre de /admin/audit
+var AuditActions = []string{
+	AuditLogin, AuditLoginLocked, AuditLogout, AuditLogoutAll, AuditRegister,
+	AuditPasswordChange, AuditPasswordReset, AuditEmailChange, AuditTwoFactorOn, AuditTwoFactorOff,
+	AuditSessionRevoke, AuditIdentityLink, AuditIdentityUnlink, AuditTokenCreate, AuditTokenRevoke,
+	AuditDeletionRequest, AuditDeletionCancel, AuditAccountPurged,
+	AuditRoleChange, AuditUserDelete, AuditUserUnlock, AuditUserSuspend, AuditUserBan, AuditUserReinstate, AuditSuspensionEnded,
+	AuditRoleCreate, AuditRoleUpdate, AuditRoleDelete,
+	AuditCommentModerate, AuditDataRefresh, AuditExport, AuditUsersExport, AuditImpersonateOn, AuditImpersonateOff,
+	AuditArtistOverride, AuditArtistRevert, AuditArtistCreate, AuditArtistUpdate, AuditArtistDelete,
+}
+
+// AuditEvent est une ligne du journal. Le nom de l'auteur est copié pour
+// rester lisible après la suppression de son compte.
+type AuditEvent struct {
+	ID         int64
+	ActorID    int // 0 : visiteur anonyme ou tâche système
+	ActorName  string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     string
+	After      string
+	IP         string
+	CreatedAt  time.Time
+}
+
+// AuditFilter restreint la liste du journal ; les champs vides sont ignorés
+type AuditFilter struct {
+	Action string
+	Actor  string // identifiant ou nom de l'auteur
+	Target string // identifiant de la cible
+	From   time.Time
+	To     time.Time
+	Page   int
+}
+
+// ParseAuditFilter lit les filtres depuis la query string
+func ParseAuditFilter(q url.Values) AuditFilter {
+	f := AuditFilter{
+		Action: strings.TrimSpace(q.Get("action")),
+		Actor:  strings.TrimSpace(q.Get("actor")),
+		Target: strings.TrimSpace(q.Get("target")),
+		Page:   1,
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("from"), time.Local); err == nil {
+		f.From = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("to"), time.Local); err == nil {
+		f.To = t.AddDate(0, 0, 1) // jour inclus
+	}
+	if page, err := strconv.Atoi(q.Get("page")); err == nil && page > 1 {
+		f.Page = page
+	}
+	return f
+}
+
+// Query renvoie les filtres sous forme de query string, sans la page
+func (f AuditFilter) Query() string {
+	q := url.Values{}
+	if f.Action != "" {
+		q.Set("action", f.Action)
+	}
+	if f.Actor != "" {
+		q.Set("actor", f.Actor)
+	}
+	if f.Target != "" {
+		q.Set("target", f.Target)
+	}
+	if !f.From.IsZero() {
+		q.Set("from", f.FromValue())
+	}
+	if !f.To.IsZero() {
+		q.Set("to", f.ToValue())
+	}
+	return q.Encode()
+}
+
+func (f AuditFilter) pageURL(page int) string {
+	q, _ := url.ParseQuery(f.Query())
+	q.Set("page", strconv.Itoa(page))
+	return "/admin/audit?" + q.Encode()
+}
+
+// FromValue et ToValue remplissent les champs date du formulaire
+func (f AuditFilter) FromValue() string {
+	if f.From.IsZero() {
+		return ""
+	}
+	return f.From.Format("2006-01-02")
+}
+
+func (f AuditFilter) ToValue() string {
+	if f.To.IsZero() {
+		return ""
+	}
+	return f.To.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	if f.Action != "" {
+		clauses = append(clauses, "action = ?")
+		args = append(args, f.Action)
+	}
+	if f.Actor != "" {
+		if id, err := strconv.Atoi(f.Actor); err == nil {
+			clauses = append(clauses, "actor_id = ?")
+			args = append(args, id)
+		} else {
+			clauses = append(clauses, "actor_name LIKE ?")
+			args = append(args, "%"+f.Actor+"%")
+		}
+	}
+	if f.Target != "" {
+		clauses = append(clauses, "target_id = ?")
+		args = append(args, f.Target)
+	}
+	if !f.From.IsZero() {
+		clauses = append(clauses, "created_at >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		clauses = append(clauses, "created_at < ?")
+		args = append(args, f.To)
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// ─── Enregistrement ──────────────────────────────────────────
+
+// Audit enregistre une action. L'auteur et l'IP sont déduits de la requête
+// s'ils ne sont pas fournis ; une erreur est journalisée sans interrompre
+// l'action auditée.
+func Audit(r *http.Request, e AuditEvent) {
+	if r != nil {
+		if e.ActorID == 0 {
+			if userID, ok := CurrentUserID(r); ok {
+				e.ActorID = userID
+			}
+		}
+		// Pendant un emprunt d'identité, l'action revient à l'administrateur
+		if imp, ok := CurrentImpersonation(r); ok && e.ActorID == imp.UserID {
+			e.ActorID = imp.AdminID
+			e.ActorName = imp.AdminName + " (en tant que " + imp.Username + ")"
+		}
+		if e.ActorID != 0 && e.ActorName == "" {
+			e.ActorName = actorName(r, e.ActorID)
+		}
+		if e.IP == "" {
+			e.IP = ClientIP(r)
+		}
+	}
+	if err := RecordAudit(DB, e); err != nil {
+		log.Printf("Erreur journal d'audit (%s): %v", e.Action, err)
+	}
+}
+
+func actorName(r *http.Request, userID int) string {
+	if session, err := GetSession(r); err == nil {
+		if id, _ := session.Values["user_id"].(int); id == userID {
+			if name, ok := session.Values["username"].(string); ok {
+				return name
+			}
+		}
+	}
+	if user, err := GetUserByID(DB, userID); err == nil {
+		return user.Username
+	}
+	return ""
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// RecordAudit ajoute une ligne au journal, qui n'est jamais modifié
+func RecordAudit(db *sql.DB, e AuditEvent) error {
+	var actorID interface{}
+	if e.ActorID != 0 {
+		actorID = e.ActorID
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	const query = `INSERT INTO audit_log (actor_id, actor_name, action, target_type, target_id, before_value, after_value, ip, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, actorID, e.ActorName, e.Action, e.TargetType, e.TargetID, e.Before, e.After, e.IP, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("écriture audit: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEvents renvoie une page du journal, du plus récent au plus ancien ;
+// limit <= 0 renvoie tout (export)
+func GetAuditEvents(db *sql.DB, f AuditFilter, limit int) ([]AuditEvent, error) {
+	where, args := f.where()
+	query := `SELECT id, COALESCE(actor_id, 0), actor_name, action, target_type, target_id, before_value, after_value, ip, created_at FROM audit_log` + where + ` ORDER BY id DESC`
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, (f.Page-1)*limit)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("lecture audit: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID, &e.Before, &e.After, &e.IP, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func CountAuditEvents(db *sql.DB, f AuditFilter) (int, error) {
+	where, args := f.where()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("comptage audit: %w", err)
+	}
+	return count, nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	filter := ParseAuditFilter(r.URL.Query())
+	total, err := CountAuditEvents(DB, filter)
+	if err != nil {
+		log.Printf("Erreur comptage audit: %v", err)
+		http.Error(w, "Erreur lors de la lecture du journal", http.StatusInternalServerError)
+		return
+	}
+	events, err := GetAuditEvents(DB, filter, auditPageSize)
+	if err != nil {
+		log.Printf("Erreur lecture audit: %v", err)
+		http.Error(w, "Erreur lors de la lecture du journal", http.StatusInternalServerError)
+		return
+	}
+
+	data := AdminAuditPageData{
+		Events:    events,
+		Filter:    filter,
+		Actions:   AuditActions,
+		Total:     total,
+		Page:      filter.Page,
+		ExportURL: "/admin/audit/export?" + filter.Query(),
+		User: &UserProfile{
+			ID:          admin.ID,
+			Username:    admin.Username,
+			Email:       admin.Email,
+			Pseudo:      getStringValue(admin.Pseudo),
+			PhotoProfil: getStringValue(admin.PhotoProfil),
+			Role:        admin.Role,
+		},
+	}
+	if filter.Page > 1 {
+		data.PrevURL = filter.pageURL(filter.Page - 1)
+	}
+	if filter.Page*auditPageSize < total {
+		data.NextURL = filter.pageURL(filter.Page + 1)
+	}
+	s.Render(w, r, "admin-audit.html", data)
+}
+
+// HandleAdminAuditExport télécharge le journal filtré au format CSV
+func (s *Server) HandleAdminAuditExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	filter := ParseAuditFilter(r.URL.Query())
+	events, err := GetAuditEvents(DB, filter, 0)
+	if err != nil {
+		log.Printf("Erreur export audit: %v", err)
+		http.Error(w, "Erreur lors de l'export du journal", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	// BOM pour qu'Excel détecte l'UTF-8
+	io.WriteString(w, "\ufeff")
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"ID", "Date", "Auteur (ID)", "Auteur", "Action", "Type de cible", "Cible", "Avant", "Après", "IP"})
+	for _, e := range events {
+		actorID := ""
+		if e.ActorID != 0 {
+			actorID = strconv.Itoa(e.ActorID)
+		}
+		record := []string{
+			strconv.FormatInt(e.ID, 10), e.CreatedAt.Format(time.RFC3339), actorID, e.ActorName, e.Action,
+			e.TargetType, e.TargetID, e.Before, e.After, e.IP,
+		}
+		for i := range record {
+			record[i] = csvSafe(record[i])
+		}
+		if err := cw.Write(record); err != nil {
+			log.Printf("Erreur écriture export audit: %v", err)
+			return
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Erreur écriture export audit: %v", err)
+	}
+	Audit(r, AuditEvent{Action: AuditExport, After: filter.Query()})
+}
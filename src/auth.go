@@ -11,16 +11,26 @@ import (
 )
 
 type User struct {
-	ID           int
-	Username     string
-	Email        string
-	PasswordHash string
-	Pseudo       sql.NullString
-	Bio          sql.NullString
-	PhotoProfil  sql.NullString
-	Role         string
-	CreatedAt    time.Time
-	UpdatedAt    sql.NullTime
+	ID              int
+	Username        string
+	Email           string
+	PasswordHash    string
+	Pseudo          sql.NullString
+	Bio             sql.NullString
+	PhotoProfil     sql.NullString
+	Role            string
+	CreatedAt       time.Time
+	UpdatedAt       sql.NullTime
+	EmailVerifiedAt sql.NullTime
+	TOTPSecret      sql.NullString
+	TOTPEnabledAt   sql.NullTime
+	LockedUntil     sql.NullTime
+
+	DeletionRequestedAt sql.NullTime // suppression programmée (voir AccountDeletionGrace)
+
+	SuspendedUntil sql.NullTime // suspension levée automatiquement à cette date
+	BannedAt       sql.NullTime
+	SanctionReason sql.NullString
 }
 
 func hashPassword(password string) (string, error) {
@@ -37,19 +47,33 @@ func checkPassword(hash string, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// validatePassword applique les règles de mot de passe communes à
+// l'inscription et à la réinitialisation
+func validatePassword(password string) error {
+	if len(password) < 8 {
+		return errors.New("mot de passe trop court (8 caractères min)")
+	}
+	return nil
+}
+
+// usernameFromEmail dérive le nom d'utilisateur de la partie locale de l'email
+func usernameFromEmail(email string) string {
+	if idx := strings.Index(email, "@"); idx > 0 {
+		return email[:idx]
+	}
+	return email
+}
+
 func CreateUser(db *sql.DB, email, password string) error {
 	email = strings.TrimSpace(strings.ToLower(email))
 	if email == "" {
 		return errors.New("email requis")
 	}
-	if len(password) < 8 {
-		return errors.New("mot de passe trop court (8 caractères min)")
+	if err := validatePassword(password); err != nil {
+		return err
 	}
 
-	username := email
-	if idx := strings.Index(email, "@"); idx > 0 {
-		username = email[:idx]
-	}
+	username := usernameFromEmail(email)
 
 	hashed, err := hashPassword(password)
 	if err != nil {
@@ -70,8 +94,8 @@ func GetUserByEmail(db *sql.DB, email string) (User, error) {
 	email = strings.TrimSpace(strings.ToLower(email))
 	var u User
 	var role sql.NullString
-	const query = `SELECT id, username, email, password_hash, pseudo, bio, photo_profil, role, created_at, updated_at FROM users WHERE email = ? LIMIT 1`
-	if err := db.QueryRow(query, email).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Pseudo, &u.Bio, &u.PhotoProfil, &role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	const query = `SELECT id, username, email, password_hash, pseudo, bio, photo_profil, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, locked_until, deletion_requested_at, suspended_until, banned_at, sanction_reason FROM users WHERE email = ? LIMIT 1`
+	if err := db.QueryRow(query, email).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Pseudo, &u.Bio, &u.PhotoProfil, &role, &u.CreatedAt, &u.UpdatedAt, &u.EmailVerifiedAt, &u.TOTPSecret, &u.TOTPEnabledAt, &u.LockedUntil, &u.DeletionRequestedAt, &u.SuspendedUntil, &u.BannedAt, &u.SanctionReason); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return User{}, fmt.Errorf("utilisateur introuvable")
 		}
@@ -88,8 +112,8 @@ func GetUserByEmail(db *sql.DB, email string) (User, error) {
 func GetUserByID(db *sql.DB, id int) (User, error) {
 	var u User
 	var role sql.NullString
-	const query = `SELECT id, username, email, password_hash, pseudo, bio, photo_profil, role, created_at, updated_at FROM users WHERE id = ? LIMIT 1`
-	if err := db.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Pseudo, &u.Bio, &u.PhotoProfil, &role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	const query = `SELECT id, username, email, password_hash, pseudo, bio, photo_profil, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, locked_until, deletion_requested_at, suspended_until, banned_at, sanction_reason FROM users WHERE id = ? LIMIT 1`
+	if err := db.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Pseudo, &u.Bio, &u.PhotoProfil, &role, &u.CreatedAt, &u.UpdatedAt, &u.EmailVerifiedAt, &u.TOTPSecret, &u.TOTPEnabledAt, &u.LockedUntil, &u.DeletionRequestedAt, &u.SuspendedUntil, &u.BannedAt, &u.SanctionReason); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return User{}, fmt.Errorf("utilisateur introuvable")
 		}
@@ -112,32 +136,10 @@ func UpdateUserProfile(db *sql.DB, userID int, pseudo, bio, photoProfil string)
 	return nil
 }
 
-func GetAllUsers(db *sql.DB) ([]User, error) {
-	rows, err := db.Query(`SELECT id, username, email, password_hash, pseudo, bio, photo_profil, role, created_at, updated_at FROM users ORDER BY created_at DESC`)
-	if err != nil {
-		return nil, fmt.Errorf("liste utilisateurs: %w", err)
-	}
-	defer rows.Close()
-
-	var users []User
-	for rows.Next() {
-		var u User
-		var role sql.NullString
-		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Pseudo, &u.Bio, &u.PhotoProfil, &role, &u.CreatedAt, &u.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("scan utilisateur: %w", err)
-		}
-		if role.Valid {
-			u.Role = role.String
-		} else {
-			u.Role = "user"
-		}
-		users = append(users, u)
-	}
-	return users, rows.Err()
-}
-
 func UpdateUserRole(db *sql.DB, userID int, role string) error {
-	if role != "user" && role != "admin" {
+	if exists, err := RoleExists(db, role); err != nil {
+		return err
+	} else if !exists {
 		return fmt.Errorf("rôle invalide: %s", role)
 	}
 	const query = `UPDATE users SET role = ?, updated_at = NOW() WHERE id = ?`
@@ -148,6 +150,8 @@ func UpdateUserRole(db *sql.DB, userID int, role string) error {
 	return nil
 }
 
+// DeleteUser supprime le compte ; ses commentaires et commandes sont
+// conservés mais détachés de l'utilisateur (ON DELETE SET NULL)
 func DeleteUser(db *sql.DB, userID int) error {
 	const query = `DELETE FROM users WHERE id = ?`
 	_, err := db.Exec(query, userID)
@@ -169,7 +173,10 @@ func ToggleFavorite(db *sql.DB, userID, artistID int) (bool, error) {
 		_, err = db.Exec("DELETE FROM favorites WHERE user_id = ? AND artist_id = ?", userID, artistID)
 		return false, err
 	}
-	_, err = db.Exec("INSERT INTO favorites (user_id, artist_id) VALUES (?, ?)", userID, artistID)
+	// le nouveau favori passe en dernier dans l'ordre choisi sur /favorites
+	const insert = `INSERT INTO favorites (user_id, artist_id, position)
+SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM favorites WHERE user_id = ?`
+	_, err = db.Exec(insert, userID, artistID, userID)
 	return true, err
 }
 
@@ -180,7 +187,7 @@ func IsFavorite(db *sql.DB, userID, artistID int) bool {
 }
 
 func GetUserFavorites(db *sql.DB, userID int) ([]int, error) {
-	rows, err := db.Query("SELECT artist_id FROM favorites WHERE user_id = ? ORDER BY created_at DESC", userID)
+	rows, err := db.Query("SELECT artist_id FROM favorites WHERE user_id = ? ORDER BY position, created_at DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -218,15 +225,29 @@ func DeleteComment(db *sql.DB, commentID, userID int) error {
 	return nil
 }
 
+// ModerateComment supprime un commentaire quel que soit son auteur et le
+// renvoie pour le journal d'audit
+func ModerateComment(db *sql.DB, commentID int) (Comment, error) {
+	var c Comment
+	const query = `SELECT id, COALESCE(user_id, 0), artist_id, content FROM comments WHERE id = ?`
+	if err := db.QueryRow(query, commentID).Scan(&c.ID, &c.UserID, &c.ArtistID, &c.Content); err != nil {
+		return c, fmt.Errorf("lecture commentaire: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM comments WHERE id = ?", commentID); err != nil {
+		return c, fmt.Errorf("modération commentaire: %w", err)
+	}
+	return c, nil
+}
+
 func GetCommentsByArtist(db *sql.DB, artistID int) ([]Comment, error) {
 	const query = `
-SELECT c.id, c.user_id, c.artist_id, c.content,
-       COALESCE(u.pseudo, u.username) AS username,
+SELECT c.id, COALESCE(c.user_id, 0), c.artist_id, c.content,
+       COALESCE(u.pseudo, u.username, 'Utilisateur supprimé') AS username,
        COALESCE(u.photo_profil, '') AS photo,
        DATE_FORMAT(c.created_at, '%d/%m/%Y %H:%i') AS created_at
 FROM comments c
-JOIN users u ON u.id = c.user_id
-WHERE c.artist_id = ?
+LEFT JOIN users u ON u.id = c.user_id
+WHERE c.artist_id = ? AND u.banned_at IS NULL
 ORDER BY c.created_at DESC`
 
 	rows, err := db.Query(query, artistID)
@@ -245,3 +266,63 @@ ORDER BY c.created_at DESC`
 	}
 	return comments, rows.Err()
 }
+
+// GetCommentsByArtists lit en une requête les commentaires de plusieurs artistes
+func GetCommentsByArtists(db *sql.DB, artistIDs []int) (map[int][]Comment, error) {
+	byArtist := make(map[int][]Comment, len(artistIDs))
+	if len(artistIDs) == 0 {
+		return byArtist, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(artistIDs)), ",")
+	args := make([]interface{}, len(artistIDs))
+	for i, id := range artistIDs {
+		args[i] = id
+	}
+	query := `
+SELECT c.id, COALESCE(c.user_id, 0), c.artist_id, c.content,
+       COALESCE(u.pseudo, u.username, 'Utilisateur supprimé') AS username,
+       COALESCE(u.photo_profil, '') AS photo,
+       DATE_FORMAT(c.created_at, '%d/%m/%Y %H:%i') AS created_at
+FROM comments c
+LEFT JOIN users u ON u.id = c.user_id
+WHERE c.artist_id IN (` + placeholders + `) AND u.banned_at IS NULL
+ORDER BY c.created_at DESC`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("lecture commentaires: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.UserID, &c.ArtistID, &c.Content, &c.Username, &c.Photo, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan commentaire: %w", err)
+		}
+		byArtist[c.ArtistID] = append(byArtist[c.ArtistID], c)
+	}
+	return byArtist, rows.Err()
+}
+
+// CountCommentsByArtist renvoie le nombre de commentaires de chaque artiste
+func CountCommentsByArtist(db *sql.DB) (map[int]int, error) {
+	const query = `SELECT c.artist_id, COUNT(*) FROM comments c
+LEFT JOIN users u ON u.id = c.user_id
+WHERE u.banned_at IS NULL
+GROUP BY c.artist_id`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("comptage commentaires: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var artistID, count int
+		if err := rows.Scan(&artistID, &count); err != nil {
+			return nil, fmt.Errorf("scan comptage commentaires: %w", err)
+		}
+		counts[artistID] = count
+	}
+	return counts, rows.Err()
+}
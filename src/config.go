@@ -2,6 +2,8 @@ package src
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -24,9 +26,48 @@ const (
 	CertFile           = "server"
 	KeyFile            = "server.key"
 	SessionName        = "groupietracker-session"
-	SessionSecret      = "change-me-to-a-random-secret-key-minimum-32-characters"
 	SessionMaxAge      = 86400 * 7
 	DefaultTicketPrice = 50.00
+	PasswordResetTTL   = time.Hour
+	EmailVerifyTTL     = 48 * time.Hour
+
+	// Protection contre la force brute à la connexion
+	LoginDelayAfter    = 3           // échecs avant le premier délai
+	LoginMaxDelay      = time.Minute // délai maximal entre deux essais
+	LoginLockThreshold = 10          // échecs avant verrouillage du compte
+	LoginLockDuration  = 30 * time.Minute
+	LoginFailureWindow = time.Hour        // échecs pris en compte par compte
+	LoginIPWindow      = 15 * time.Minute // fenêtre d'analyse par adresse IP
+	LoginIPThreshold   = 30               // échecs tolérés par adresse IP
+	LoginIPAccounts    = 5                // comptes distincts visés avant alerte
+	AccountUnlockTTL   = 24 * time.Hour
+	Login2FAAttempts   = 5 // codes 2FA erronés avant d'abandonner la connexion
+
+	// Suppression de compte à la demande de l'utilisateur
+	AccountDeletionGrace = 14 * 24 * time.Hour // délai pendant lequel la demande peut être annulée
+	AccountPurgeInterval = time.Hour
+
+	SuspensionCheckInterval = 5 * time.Minute // levée des suspensions échues
+
+	AdminOrdersLimit = 200 // commandes affichées sur /admin/orders
+	ImpersonationTTL = 30 * time.Minute
+
+	// Les artistes créés sur le site ont un ID à partir de cette valeur
+	// (AUTO_INCREMENT de local_artists), hors de la plage de l'API
+	LocalArtistIDStart = 100000
+
+	// Tableau de bord /admin
+	StatsDays       = 30 // inscriptions et utilisateurs actifs par jour
+	StatsMonths     = 12 // chiffre d'affaires par mois
+	StatsTopArtists = 10
+
+	// Page /favorites
+	FavoriteUpcomingConcerts = 3 // prochains concerts affichés par artiste
+	FavoriteNoteMaxLength    = 1000
+
+	// Listes d'artistes (/lists)
+	ListNameMaxLength = 100
+	ListMaxPerUser    = 50
 )
 
 var (
@@ -34,8 +75,47 @@ var (
 	PayPalSecret   = getEnvOrDefault("PAYPAL_SECRET", "EN_zEbAcKwJluLRQOUJEZbqUmVgRFYxtuy3gD5WoTuLozW8ptEQyp_6uqd3-_6NGQUQxI3h7-88jc-gq")
 	PayPalMode     = getEnvOrDefault("PAYPAL_MODE", "sandbox")
 	PayPalBaseURL  = "https://api-m.sandbox.paypal.com"
+
+	// Nombre de proxys de confiance devant le serveur (TRUST_PROXY_HEADERS=1,
+	// 2…) ; toute autre valeur non vide compte pour un seul proxy
+	TrustedProxyHops = proxyHops(os.Getenv("TRUST_PROXY_HEADERS"))
+
+	// Adresse publique du site, utilisée pour les liens envoyés par email
+	BaseURL = strings.TrimSuffix(getEnvOrDefault("BASE_URL", "http://localhost:8080"), "/")
+
+	SMTPHost     = os.Getenv("SMTP_HOST")
+	SMTPPort     = getEnvOrDefault("SMTP_PORT", "587")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	MailFrom     = getEnvOrDefault("MAIL_FROM", "Groupie Tracker <no-reply@groupietracker.local>")
+	MailDir      = os.Getenv("MAIL_DIR")
+
+	// Commentaires et achats réservés aux comptes dont l'email est vérifié
+	RequireVerifiedEmail = getEnvOrDefault("REQUIRE_VERIFIED_EMAIL", "true") != "false"
+
+	// Double authentification obligatoire pour les administrateurs
+	RequireAdmin2FA = os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+
+	// Clé de signature du cookie de session (qui ne contient que l'identifiant)
+	SessionSecret = getEnvOrDefault("SESSION_SECRET", "change-me-to-a-random-secret-key-minimum-32-characters")
+
+	// Fournisseurs OpenID Connect (OIDC_PROVIDERS=google,entreprise...)
+	OIDCProviders = loadOIDCProviders()
 )
 
+// RateLimitPolicies centralise les limites de débit appliquées par route
+var RateLimitPolicies = map[string]RateLimitPolicy{
+	"login":    {Rate: 5.0 / 60, Burst: 5, WritesOnly: true, ByIP: true},
+	"register": {Rate: 3.0 / 3600, Burst: 5, WritesOnly: true, ByIP: true},
+	"comment":  {Rate: 10.0 / 60, Burst: 5},
+	"geocode":  {Rate: 1, Burst: 10},
+	"payment":  {Rate: 10.0 / 3600, Burst: 5},
+	"api":      {Rate: 10, Burst: 60},
+	"export":   {Rate: 1.0 / 10, Burst: 5},
+	"password": {Rate: 3.0 / 3600, Burst: 3, WritesOnly: true, ByIP: true},
+	"verify":   {Rate: 1.0 / 300, Burst: 2, ByIP: true},
+}
+
 func init() {
 	if PayPalMode == "live" {
 		PayPalBaseURL = "https://api-m.paypal.com"
@@ -48,3 +128,13 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func proxyHops(value string) int {
+	if value == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,108 @@
+package src
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	csrfSessionKey = "csrf_token"
+	csrfFieldName  = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+
+	// Même limite mémoire que le formulaire de profil (photo)
+	csrfMaxFormMemory = 10 << 20
+)
+
+// Routes sans effet de bord acceptant POST, exemptées de jeton. /graphql
+// n'exécute que des opérations query (les mutations sont refusées) et ne
+// renvoie aucun en-tête CORS : un site tiers peut l'appeler avec le cookie
+// de session mais ne peut ni modifier l'état ni lire la réponse.
+var csrfExemptPaths = map[string]bool{
+	"/graphql": true,
+}
+
+// CSRFToken renvoie le jeton anti-CSRF de la session, créé à la première
+// demande. Il est lié à la session et non à la requête.
+func CSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, err := GetSession(r)
+	if err != nil {
+		return "", err
+	}
+	if token, ok := session.Values[csrfSessionKey].(string); ok && token != "" {
+		return token, nil
+	}
+	token, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	session.Values[csrfSessionKey] = token
+	if err := SaveSession(w, r, session); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// validCSRFToken compare le jeton envoyé (en-tête pour fetch(), champ de
+// formulaire sinon) à celui de la session
+func validCSRFToken(r *http.Request) bool {
+	session, err := GetSession(r)
+	if err != nil {
+		return false
+	}
+	expected, _ := session.Values[csrfSessionKey].(string)
+	if expected == "" {
+		return false
+	}
+	sent := r.Header.Get(csrfHeaderName)
+	if sent == "" {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			_ = r.ParseMultipartForm(csrfMaxFormMemory)
+		}
+		sent = r.PostFormValue(csrfFieldName)
+	}
+	return subtle.ConstantTimeCompare([]byte(sent), []byte(expected)) == 1
+}
+
+// CSRFProtect exige un jeton valide sur toutes les requêtes modifiant l'état
+// authentifiées par cookie. Les appels authentifiés par un jeton Bearer
+// valide n'utilisent pas le cookie de session et ne sont pas concernés ; un
+// en-tête Bearer quelconque ne suffit pas à contourner la vérification.
+func (s *Server) CSRFProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if csrfExemptPaths[r.URL.Path] || bearerAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validCSRFToken(r) {
+			log.Printf("Requête %s %s refusée: jeton CSRF invalide (IP %s)", r.Method, r.URL.Path, ClientIP(r))
+			const message = "Votre session a expiré ou la requête ne provient pas de Groupie Tracker. Rechargez la page et réessayez."
+			// fetch() et clients JSON : erreur JSON ; formulaires : page d'erreur
+			if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+				WriteAPIError(w, http.StatusForbidden, "csrf_invalid", message)
+				return
+			}
+			s.RenderError(w, r, http.StatusForbidden, "Requête refusée", message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerAuthenticated indique si la requête porte un jeton d'API existant,
+// non révoqué et non expiré
+func bearerAuthenticated(r *http.Request) bool {
+	raw, ok := BearerToken(r)
+	if !ok {
+		return false
+	}
+	_, err := GetAPITokenByValue(DB, raw)
+	return err == nil
+}
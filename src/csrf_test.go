@@ -0,0 +1,44 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	handler := testServer().CSRFProtect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		name          string
+		method, path  string
+		authorization string
+		want          int
+	}{
+		{"lecture", http.MethodGet, "/api/favorite/toggle", "", http.StatusNoContent},
+		{"sans jeton", http.MethodPost, "/api/favorite/toggle", "", http.StatusForbidden},
+		{"Bearer inventé", http.MethodPost, "/api/favorite/toggle", "Bearer nimporte-quoi", http.StatusForbidden},
+		{"Bearer vide", http.MethodPost, "/api/favorite/toggle", "Bearer ", http.StatusForbidden},
+		{"route exemptée", http.MethodPost, "/graphql", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set(csrfHeaderName, "jeton-sans-session")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("statut %d, attendu %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusForbidden && !strings.Contains(rec.Body.String(), "csrf_invalid") {
+				t.Errorf("corps inattendu: %s", rec.Body.String())
+			}
+		})
+	}
+}
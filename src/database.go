@@ -3,6 +3,7 @@ package src
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"net/url"
 	"os"
 
@@ -81,31 +82,373 @@ CREATE TABLE IF NOT EXISTS favorites (
 	const commentsTable = `
 CREATE TABLE IF NOT EXISTS comments (
     id INT AUTO_INCREMENT PRIMARY KEY,
-    user_id INT NOT NULL,
+    user_id INT DEFAULT NULL,
     artist_id INT NOT NULL,
     content TEXT NOT NULL,
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+    CONSTRAINT fk_comments_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 `
 	if _, err := db.Exec(commentsTable); err != nil {
 		return fmt.Errorf("création table comments: %w", err)
 	}
 
+	const apiTokensTable = `
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    last_used_at DATETIME DEFAULT NULL,
+    expires_at DATETIME DEFAULT NULL,
+    revoked_at DATETIME DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_api_tokens_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(apiTokensTable); err != nil {
+		return fmt.Errorf("création table api_tokens: %w", err)
+	}
+
+	const passwordResetsTable = `
+CREATE TABLE IF NOT EXISTS password_resets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_password_resets_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(passwordResetsTable); err != nil {
+		return fmt.Errorf("création table password_resets: %w", err)
+	}
+
+	const emailVerificationsTable = `
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_email_verifications_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(emailVerificationsTable); err != nil {
+		return fmt.Errorf("création table email_verifications: %w", err)
+	}
+
+	const recoveryCodesTable = `
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at DATETIME DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_recovery_codes_user (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(recoveryCodesTable); err != nil {
+		return fmt.Errorf("création table recovery_codes: %w", err)
+	}
+
+	const sessionsTable = `
+CREATE TABLE IF NOT EXISTS sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_id INT DEFAULT NULL,
+    data BLOB NOT NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    INDEX idx_sessions_user (user_id),
+    INDEX idx_sessions_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(sessionsTable); err != nil {
+		return fmt.Errorf("création table sessions: %w", err)
+	}
+
+	const loginAttemptsTable = `
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    success BOOLEAN NOT NULL DEFAULT FALSE,
+    cleared BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL,
+    INDEX idx_login_attempts_email (email, created_at),
+    INDEX idx_login_attempts_ip (ip, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(loginAttemptsTable); err != nil {
+		return fmt.Errorf("création table login_attempts: %w", err)
+	}
+
+	const accountUnlocksTable = `
+CREATE TABLE IF NOT EXISTS account_unlocks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_account_unlocks_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(accountUnlocksTable); err != nil {
+		return fmt.Errorf("création table account_unlocks: %w", err)
+	}
+
+	const userIdentitiesTable = `
+CREATE TABLE IF NOT EXISTS user_identities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at DATETIME DEFAULT NULL,
+    UNIQUE KEY uniq_identity (provider, subject),
+    INDEX idx_user_identities_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(userIdentitiesTable); err != nil {
+		return fmt.Errorf("création table user_identities: %w", err)
+	}
+
+	const ordersTable = `
+CREATE TABLE IF NOT EXISTS orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT DEFAULT NULL,
+    paypal_order_id VARCHAR(64) NOT NULL UNIQUE,
+    artist_id INT NOT NULL,
+    artist_name VARCHAR(255) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    concert_date VARCHAR(50) NOT NULL DEFAULT '',
+    quantity INT NOT NULL DEFAULT 1,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    captured_at DATETIME DEFAULT NULL,
+    INDEX idx_orders_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(ordersTable); err != nil {
+		return fmt.Errorf("création table orders: %w", err)
+	}
+
+	const rolesTable = `
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(20) PRIMARY KEY,
+    label VARCHAR(100) NOT NULL,
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(rolesTable); err != nil {
+		return fmt.Errorf("création table roles: %w", err)
+	}
+
+	const rolePermissionsTable = `
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(20) NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission),
+    FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(rolePermissionsTable); err != nil {
+		return fmt.Errorf("création table role_permissions: %w", err)
+	}
+	if err := SeedRoles(db); err != nil {
+		return err
+	}
+
+	const auditLogTable = `
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id INT DEFAULT NULL,
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(30) NOT NULL DEFAULT '',
+    target_id VARCHAR(64) NOT NULL DEFAULT '',
+    before_value TEXT NOT NULL,
+    after_value TEXT NOT NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    INDEX idx_audit_created (created_at),
+    INDEX idx_audit_action (action, created_at),
+    INDEX idx_audit_actor (actor_id),
+    INDEX idx_audit_target (target_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(auditLogTable); err != nil {
+		return fmt.Errorf("création table audit_log: %w", err)
+	}
+	// Journal en ajout seul : les triggers refusent toute modification. Leur
+	// création demande des droits que l'utilisateur SQL n'a pas toujours.
+	for _, trigger := range []string{
+		"CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log est en ajout seul'",
+		"CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log est en ajout seul'",
+	} {
+		if _, err := db.Exec(trigger); err != nil {
+			log.Printf("Trigger audit_log non créé: %v", err)
+		}
+	}
+
+	const userActivityTable = `
+CREATE TABLE IF NOT EXISTS user_activity (
+    user_id INT NOT NULL,
+    day DATE NOT NULL,
+    PRIMARY KEY (user_id, day),
+    INDEX idx_user_activity_day (day),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(userActivityTable); err != nil {
+		return fmt.Errorf("création table user_activity: %w", err)
+	}
+
+	const artistOverridesTable = `
+CREATE TABLE IF NOT EXISTS artist_overrides (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    artist_id INT NOT NULL,
+    field VARCHAR(32) NOT NULL,
+    value TEXT NOT NULL,
+    upstream TEXT NOT NULL,
+    stale BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by INT DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reverted_by INT DEFAULT NULL,
+    reverted_at DATETIME DEFAULT NULL,
+    INDEX idx_artist_overrides_artist (artist_id, reverted_at),
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (reverted_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(artistOverridesTable); err != nil {
+		return fmt.Errorf("création table artist_overrides: %w", err)
+	}
+
+	// Les IDs commencent à LocalArtistIDStart pour ne pas croiser ceux de l'API
+	const localArtistsTable = `
+CREATE TABLE IF NOT EXISTS local_artists (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    image VARCHAR(500) NOT NULL,
+    members TEXT NOT NULL,
+    creation_date INT NOT NULL,
+    first_album VARCHAR(10) NOT NULL,
+    relations TEXT NOT NULL,
+    created_by INT DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB AUTO_INCREMENT=100000 DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(localArtistsTable); err != nil {
+		return fmt.Errorf("création table local_artists: %w", err)
+	}
+
+	// Listes d'artistes nommées ; share_token sert d'URL de partage
+	const listsTable = `
+CREATE TABLE IF NOT EXISTS lists (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    share_token VARCHAR(64) NOT NULL,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_list_token (share_token),
+    INDEX idx_lists_user (user_id, position),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(listsTable); err != nil {
+		return fmt.Errorf("création table lists: %w", err)
+	}
+
+	const listItemsTable = `
+CREATE TABLE IF NOT EXISTS list_items (
+    list_id INT NOT NULL,
+    artist_id INT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, artist_id),
+    FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`
+	if _, err := db.Exec(listItemsTable); err != nil {
+		return fmt.Errorf("création table list_items: %w", err)
+	}
+
 	alterQueries := []string{
 		"ALTER TABLE users ADD COLUMN IF NOT EXISTS pseudo VARCHAR(255) DEFAULT NULL",
 		"ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT DEFAULT NULL",
 		"ALTER TABLE users ADD COLUMN IF NOT EXISTS photo_profil VARCHAR(500) DEFAULT NULL",
 		"ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at DATETIME DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP",
 		"ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) DEFAULT 'user'",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64) DEFAULT NULL",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at DATETIME DEFAULT NULL",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until DATETIME DEFAULT NULL",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at DATETIME DEFAULT NULL",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until DATETIME DEFAULT NULL",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at DATETIME DEFAULT NULL",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS sanction_reason VARCHAR(500) DEFAULT NULL",
+		"ALTER TABLE favorites ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0",
+		"ALTER TABLE favorites ADD COLUMN IF NOT EXISTS note TEXT DEFAULT NULL",
 	}
 
 	for _, query := range alterQueries {
 		_, _ = db.Exec(query)
 	}
 
+	// Les comptes antérieurs à la vérification d'email sont considérés vérifiés
+	var verifiedColumn int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = 'email_verified_at'").Scan(&verifiedColumn)
+	if err == nil && verifiedColumn == 0 {
+		if _, err := db.Exec("ALTER TABLE users ADD COLUMN email_verified_at DATETIME DEFAULT NULL"); err != nil {
+			return fmt.Errorf("ajout colonne email_verified_at: %w", err)
+		}
+		_, _ = db.Exec("UPDATE users SET email_verified_at = created_at")
+	}
+
+	// Les commentaires d'un compte supprimé sont anonymisés et non plus effacés
+	var commentsFK, deleteRule string
+	err = db.QueryRow("SELECT CONSTRAINT_NAME, DELETE_RULE FROM information_schema.REFERENTIAL_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = 'comments' AND REFERENCED_TABLE_NAME = 'users'").Scan(&commentsFK, &deleteRule)
+	if err == nil && deleteRule != "SET NULL" {
+		for _, query := range []string{
+			"ALTER TABLE comments DROP FOREIGN KEY `" + commentsFK + "`",
+			"ALTER TABLE comments MODIFY user_id INT DEFAULT NULL",
+			"ALTER TABLE comments ADD CONSTRAINT fk_comments_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL",
+		} {
+			if _, err := db.Exec(query); err != nil {
+				return fmt.Errorf("migration clé étrangère comments: %w", err)
+			}
+		}
+	}
+
 	var columnExists int
-	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = 'role'").Scan(&columnExists)
+	err = db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = 'role'").Scan(&columnExists)
 	if err == nil && columnExists == 0 {
 		_, _ = db.Exec("ALTER TABLE users ADD COLUMN role VARCHAR(20) DEFAULT 'user'")
 	}
@@ -0,0 +1,378 @@
+package src
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExportPath = "/export/artists"
+
+	// Fréquence de vidage du tampon HTTP pendant un export
+	exportFlushEvery = 100
+)
+
+// ExportColumn décrit une colonne exportable. Value renvoie une chaîne,
+// un entier ou une liste de chaînes (jointe pour CSV et XLSX).
+type ExportColumn struct {
+	Key   string
+	Label string
+	Value func(Artist) interface{}
+}
+
+var exportColumns = []ExportColumn{
+	{Key: "name", Label: "Nom", Value: func(a Artist) interface{} { return a.Name }},
+	{Key: "members", Label: "Membres", Value: func(a Artist) interface{} { return a.Members }},
+	{Key: "creation_date", Label: "Création", Value: func(a Artist) interface{} { return a.CreationDate }},
+	{Key: "first_album", Label: "Premier album", Value: func(a Artist) interface{} { return FormatDate(a.FirstAlbum) }},
+	{Key: "locations", Label: "Lieux", Value: func(a Artist) interface{} {
+		labels := make([]string, 0, len(a.Locations))
+		for _, loc := range BuildLocationDates(a.DatesLocations) {
+			labels = append(labels, loc.Pretty)
+		}
+		return labels
+	}},
+	{Key: "concert_count", Label: "Concerts", Value: func(a Artist) interface{} { return len(a.ConcertDates) }},
+}
+
+func ExportColumns() []ExportColumn {
+	return exportColumns
+}
+
+// ParseExportColumns lit les colonnes demandées (paramètre columns répété
+// ou séparé par des virgules) ; toutes les colonnes par défaut
+func ParseExportColumns(raw []string) ([]ExportColumn, error) {
+	var keys []string
+	for _, value := range raw {
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return exportColumns, nil
+	}
+
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		found := false
+		for _, col := range exportColumns {
+			if col.Key == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("colonne inconnue: %s", key)
+		}
+		wanted[key] = true
+	}
+	// On conserve l'ordre canonique des colonnes
+	columns := make([]ExportColumn, 0, len(wanted))
+	for _, col := range exportColumns {
+		if wanted[col.Key] {
+			columns = append(columns, col)
+		}
+	}
+	return columns, nil
+}
+
+// ─── Formats ─────────────────────────────────────────────────
+
+// exportWriter écrit un export ligne par ligne sans tout garder en mémoire
+type exportWriter interface {
+	Header(columns []ExportColumn) error
+	Row(columns []ExportColumn, art Artist) error
+	Flush() error
+	Close() error
+}
+
+type exportFormat struct {
+	ContentType string
+	Extension   string
+	New         func(w io.Writer) exportWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":   {ContentType: "text/csv; charset=utf-8", Extension: "csv", New: newCSVExport},
+	"jsonl": {ContentType: "application/x-ndjson; charset=utf-8", Extension: "jsonl", New: newJSONLinesExport},
+	"xlsx":  {ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extension: "xlsx", New: newXLSXExport},
+}
+
+func exportText(value interface{}) string {
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, "; ")
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// CSV
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer) exportWriter {
+	// BOM pour qu'Excel détecte l'UTF-8
+	io.WriteString(w, "\ufeff")
+	return &csvExport{w: csv.NewWriter(w)}
+}
+
+func (e *csvExport) Header(columns []ExportColumn) error {
+	record := make([]string, len(columns))
+	for i, col := range columns {
+		record[i] = col.Label
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) Row(columns []ExportColumn, art Artist) error {
+	record := make([]string, len(columns))
+	for i, col := range columns {
+		record[i] = csvSafe(exportText(col.Value(art)))
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport) Close() error { return e.Flush() }
+
+// csvSafe neutralise les cellules qu'un tableur interpréterait comme formule
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// JSON lines
+
+type jsonLinesExport struct {
+	enc *json.Encoder
+}
+
+func newJSONLinesExport(w io.Writer) exportWriter {
+	return &jsonLinesExport{enc: json.NewEncoder(w)}
+}
+
+func (e *jsonLinesExport) Header(columns []ExportColumn) error { return nil }
+
+func (e *jsonLinesExport) Row(columns []ExportColumn, art Artist) error {
+	// Les clés sont écrites dans l'ordre des colonnes
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(col.Key)
+		value, err := json.Marshal(col.Value(art))
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return e.enc.Encode(json.RawMessage(b.String()))
+}
+
+func (e *jsonLinesExport) Flush() error { return nil }
+
+func (e *jsonLinesExport) Close() error { return nil }
+
+// XLSX : classeur minimal (une feuille, chaînes en ligne) écrit directement
+// dans l'archive zip, sans dépendance externe
+
+type xlsxExport struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Artistes" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+func newXLSXExport(w io.Writer) exportWriter {
+	return &xlsxExport{zw: zip.NewWriter(w)}
+}
+
+func (e *xlsxExport) Header(columns []ExportColumn) error {
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := e.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	// La feuille est la dernière entrée : ses lignes sont écrites au fil de l'eau
+	sheet, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = sheet
+	if _, err := io.WriteString(e.sheet, xlsxSheetStart); err != nil {
+		return err
+	}
+	labels := make([]interface{}, len(columns))
+	for i, col := range columns {
+		labels[i] = col.Label
+	}
+	return e.writeRow(labels)
+}
+
+func (e *xlsxExport) Row(columns []ExportColumn, art Artist) error {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = col.Value(art)
+	}
+	return e.writeRow(values)
+}
+
+func (e *xlsxExport) writeRow(values []interface{}) error {
+	e.row++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, e.row)
+	for i, value := range values {
+		ref := xlsxColumnName(i) + strconv.Itoa(e.row)
+		if n, ok := value.(int); ok {
+			fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, n)
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xml.EscapeText(&b, []byte(exportText(value)))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(e.sheet, b.String())
+	return err
+}
+
+func (e *xlsxExport) Flush() error { return e.zw.Flush() }
+
+func (e *xlsxExport) Close() error {
+	if _, err := io.WriteString(e.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// xlsxColumnName convertit un index (0 → A, 26 → AA)
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// ─── Handler ─────────────────────────────────────────────────
+
+// HandleExportArtists exporte la liste filtrée (mêmes paramètres que /home)
+// au format csv, jsonl ou xlsx, en flux
+func (s *Server) HandleExportArtists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	formatName := params.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := exportFormats[formatName]
+	if !ok {
+		http.Error(w, "Format d'export inconnu", http.StatusBadRequest)
+		return
+	}
+	columns, err := ParseExportColumns(params["columns"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, err := ParseArtistFilters(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	artists := ApplyArtistFilters(s.ListArtists(), filters)
+	if userID, ok := CurrentUserID(r); ok {
+		if artists, err = filterFavorites(DB, artists, filters, userID); err != nil {
+			log.Printf("Erreur filtre favoris: %v", err)
+			http.Error(w, "Erreur lors de la lecture des favoris", http.StatusInternalServerError)
+			return
+		}
+	}
+	filename := fmt.Sprintf("artistes-%s.%s", time.Now().Format("20060102"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	flusher, _ := w.(http.Flusher)
+	out := format.New(w)
+	if err := out.Header(columns); err != nil {
+		log.Printf("Erreur export %s: %v", formatName, err)
+		return
+	}
+	for i, art := range artists {
+		if err := out.Row(columns, art); err != nil {
+			// L'en-tête est déjà parti : on ne peut qu'interrompre le flux
+			log.Printf("Erreur export %s: %v", formatName, err)
+			return
+		}
+		if flusher != nil && (i+1)%exportFlushEvery == 0 {
+			if err := out.Flush(); err != nil {
+				log.Printf("Erreur export %s: %v", formatName, err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+	if err := out.Close(); err != nil {
+		log.Printf("Erreur export %s: %v", formatName, err)
+	}
+}
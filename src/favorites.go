@@ -0,0 +1,215 @@
+package src
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Favorite est un favori avec sa priorité et la note privée de l'utilisateur
+type Favorite struct {
+	ArtistID  int
+	Position  int
+	Note      string
+	CreatedAt time.Time
+}
+
+// UpcomingConcert est un concert à venir affiché sur /favorites
+type UpcomingConcert struct {
+	Location string
+	Date     time.Time
+}
+
+// UpcomingConcerts renvoie les prochains concerts de l'artiste, du plus proche au plus lointain
+func UpcomingConcerts(a Artist, now time.Time, limit int) []UpcomingConcert {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var concerts []UpcomingConcert
+	for _, ld := range BuildLocationDates(a.DatesLocations) {
+		for _, raw := range ld.Dates {
+			date, err := time.Parse("02-01-2006", raw)
+			if err != nil || date.Before(today) {
+				continue
+			}
+			concerts = append(concerts, UpcomingConcert{Location: ld.Pretty, Date: date})
+		}
+	}
+	sort.Slice(concerts, func(i, j int) bool {
+		return concerts[i].Date.Before(concerts[j].Date)
+	})
+	if len(concerts) > limit {
+		concerts = concerts[:limit]
+	}
+	return concerts
+}
+
+// onlyFavorites garde les artistes favoris de l'utilisateur, dans l'ordre de la liste
+func onlyFavorites(artists []Artist, favoriteIDs []int) []Artist {
+	ids := make(map[int]bool, len(favoriteIDs))
+	for _, id := range favoriteIDs {
+		ids[id] = true
+	}
+	result := make([]Artist, 0, len(favoriteIDs))
+	for _, art := range artists {
+		if ids[art.ID] {
+			result = append(result, art)
+		}
+	}
+	return result
+}
+
+// filterFavorites applique le filtre « mes favoris » quand il est demandé
+func filterFavorites(db *sql.DB, artists []Artist, filters ArtistFilters, userID int) ([]Artist, error) {
+	if !filters.Favorites {
+		return artists, nil
+	}
+	ids, err := GetUserFavorites(db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture favoris: %w", err)
+	}
+	return onlyFavorites(artists, ids), nil
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// GetUserFavoriteDetails renvoie les favoris par ordre de priorité
+func GetUserFavoriteDetails(db *sql.DB, userID int) ([]Favorite, error) {
+	const query = `SELECT artist_id, position, COALESCE(note, ''), created_at FROM favorites WHERE user_id = ? ORDER BY position, created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture favoris: %w", err)
+	}
+	defer rows.Close()
+
+	var favorites []Favorite
+	for rows.Next() {
+		var f Favorite
+		if err := rows.Scan(&f.ArtistID, &f.Position, &f.Note, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan favori: %w", err)
+		}
+		favorites = append(favorites, f)
+	}
+	return favorites, rows.Err()
+}
+
+// ReorderFavorites enregistre l'ordre choisi ; les artistes absents de la
+// liste ou non favoris sont ignorés
+func ReorderFavorites(db *sql.DB, userID int, artistIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("ordre des favoris: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, artistID := range artistIDs {
+		if _, err := tx.Exec("UPDATE favorites SET position = ? WHERE user_id = ? AND artist_id = ?", i+1, userID, artistID); err != nil {
+			return fmt.Errorf("ordre des favoris: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func SetFavoriteNote(db *sql.DB, userID, artistID int, note string) error {
+	var value interface{}
+	if note != "" {
+		value = note
+	}
+	if _, err := db.Exec("UPDATE favorites SET note = ? WHERE user_id = ? AND artist_id = ?", value, userID, artistID); err != nil {
+		return fmt.Errorf("note du favori: %w", err)
+	}
+	return nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleFavorites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	favorites, err := GetUserFavoriteDetails(DB, user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération favoris: %v", err)
+		http.Error(w, "Erreur lors de la récupération des favoris", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	data := FavoritesPageData{User: adminProfile(user), Notice: takeNotice(w, r)}
+	for _, f := range favorites {
+		art, found := s.FindArtist(f.ArtistID)
+		if !found {
+			continue
+		}
+		data.Favorites = append(data.Favorites, FavoriteEntry{
+			Artist:   art,
+			Note:     f.Note,
+			Upcoming: UpcomingConcerts(art, now, FavoriteUpcomingConcerts),
+		})
+	}
+	s.Render(w, r, "favorites.html", data)
+}
+
+// HandleReorderFavorites reçoit l'ordre complet après un glisser-déposer
+func (s *Server) HandleReorderFavorites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	ids, err := parseOrder(r.FormValue("order"))
+	if err != nil {
+		http.Error(w, "Ordre invalide", http.StatusBadRequest)
+		return
+	}
+	if err := ReorderFavorites(DB, userID, ids); err != nil {
+		log.Printf("Erreur ordre favoris: %v", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) HandleFavoriteNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	artistID, err := strconv.Atoi(r.FormValue("artist_id"))
+	if err != nil || artistID <= 0 {
+		http.Error(w, "ID artiste invalide", http.StatusBadRequest)
+		return
+	}
+
+	notice := "Note enregistrée"
+	note := strings.TrimSpace(r.FormValue("note"))
+	if utf8.RuneCountInString(note) > FavoriteNoteMaxLength {
+		notice = fmt.Sprintf("La note ne peut pas dépasser %d caractères", FavoriteNoteMaxLength)
+	} else if err := SetFavoriteNote(DB, userID, artistID, note); err != nil {
+		log.Printf("Erreur note favori: %v", err)
+		notice = "Erreur lors de l'enregistrement de la note"
+	}
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/favorites#artist-"+strconv.Itoa(artistID), http.StatusSeeOther)
+}
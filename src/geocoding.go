@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,8 +30,28 @@ var (
 	// Cache pour stocker les coordonnées géocodées
 	geocodeCache = make(map[string]Coordinates)
 	cacheMutex   sync.RWMutex
+
+	// Compteurs du cache, affichés sur le tableau de bord
+	geocodeHits   atomic.Int64
+	geocodeMisses atomic.Int64
 )
 
+// GeocodeCacheStats renvoie les accès au cache depuis le démarrage et sa taille
+func GeocodeCacheStats() (hits, misses int64, size int) {
+	cacheMutex.RLock()
+	size = len(geocodeCache)
+	cacheMutex.RUnlock()
+	return geocodeHits.Load(), geocodeMisses.Load(), size
+}
+
+// CachedCoordinates renvoie les coordonnées déjà géocodées, sans appeler Nominatim
+func CachedCoordinates(address string) (Coordinates, bool) {
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+	coords, ok := geocodeCache[address]
+	return coords, ok
+}
+
 // GeocodeLocation convertit une adresse en coordonnées géographiques
 // Utilise Nominatim (OpenStreetMap) qui est gratuit et ne nécessite pas de clé API
 func GeocodeLocation(address string) (Coordinates, error) {
@@ -38,9 +59,11 @@ func GeocodeLocation(address string) (Coordinates, error) {
 	cacheMutex.RLock()
 	if coords, exists := geocodeCache[address]; exists {
 		cacheMutex.RUnlock()
+		geocodeHits.Add(1)
 		return coords, nil
 	}
 	cacheMutex.RUnlock()
+	geocodeMisses.Add(1)
 
 	// Nettoyer l'adresse (remplacer _ par des espaces, formater)
 	cleanAddr := CleanAddressForGeocoding(address)
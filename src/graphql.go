@@ -0,0 +1,1050 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Moteur GraphQL minimal : lecture des requêtes (query uniquement, alias,
+// arguments, variables, fragments), contrôle de profondeur et de complexité,
+// puis exécution sur un schéma déclaré en Go (voir graphql_schema.go).
+
+const (
+	GraphQLMaxDepth      = 8
+	GraphQLMaxComplexity = 2000
+	GraphQLMaxSelections = 1000 // champs de l'opération une fois les fragments développés
+	graphQLListFactor    = 10   // coût estimé d'une liste sans argument first
+	graphQLMaxFirst      = MaxPerPage
+	graphQLMaxOffset     = 100000
+)
+
+// ─── Analyse lexicale ────────────────────────────────────────
+
+type gqlTokenKind int
+
+const (
+	gqlEOF gqlTokenKind = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+type gqlToken struct {
+	kind  gqlTokenKind
+	value string
+	pos   int
+}
+
+func gqlLex(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+			tokens = append(tokens, gqlToken{gqlPunct, string(c), i})
+			i++
+		case c == '.':
+			if !strings.HasPrefix(src[i:], "...") {
+				return nil, fmt.Errorf("caractère inattendu '.' à la position %d", i)
+			}
+			tokens = append(tokens, gqlToken{gqlPunct, "...", i})
+			i += 3
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{gqlName, src[start:i], start})
+		case c == '-' || isDigit(c):
+			start := i
+			kind := gqlInt
+			i++
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' || ((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				if !isDigit(src[i]) {
+					kind = gqlFloat
+				}
+				i++
+			}
+			tokens = append(tokens, gqlToken{kind, src[start:i], start})
+		case c == '"':
+			start := i
+			i++
+			var sb strings.Builder
+			for {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, fmt.Errorf("chaîne non terminée à la position %d", start)
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					case 'u':
+						if i+4 >= len(src) {
+							return nil, fmt.Errorf("séquence \\u invalide à la position %d", i)
+						}
+						r, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+						if err != nil {
+							return nil, fmt.Errorf("séquence \\u invalide à la position %d", i)
+						}
+						sb.WriteRune(rune(r))
+						i += 4
+					default:
+						sb.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, gqlToken{gqlString, sb.String(), start})
+		default:
+			return nil, fmt.Errorf("caractère inattendu %q à la position %d", c, i)
+		}
+	}
+	return append(tokens, gqlToken{kind: gqlEOF, pos: len(src)}), nil
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// ─── Analyse syntaxique ──────────────────────────────────────
+
+type gqlSelection struct {
+	Alias         string
+	Name          string
+	Args          map[string]interface{}
+	Selections    []gqlSelection
+	FragmentName  string // ...Fragment
+	TypeCondition string // ... on Type { }
+}
+
+type gqlVariableRef struct{ Name string }
+
+type gqlOperation struct {
+	Kind       string
+	Name       string
+	Variables  map[string]gqlVariableDef
+	Selections []gqlSelection
+}
+
+type gqlVariableDef struct {
+	Type    string
+	Default interface{}
+}
+
+type gqlFragment struct {
+	TypeCondition string
+	Selections    []gqlSelection
+}
+
+type gqlDocument struct {
+	Operations []gqlOperation
+	Fragments  map[string]gqlFragment
+}
+
+type gqlParser struct {
+	tokens []gqlToken
+	pos    int
+}
+
+func ParseGraphQL(src string) (*gqlDocument, error) {
+	tokens, err := gqlLex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{tokens: tokens}
+	doc := &gqlDocument{Fragments: make(map[string]gqlFragment)}
+	for p.peek().kind != gqlEOF {
+		tok := p.peek()
+		switch {
+		case tok.kind == gqlPunct && tok.value == "{":
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, gqlOperation{Kind: "query", Selections: sels})
+		case tok.kind == gqlName && (tok.value == "query" || tok.value == "mutation" || tok.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case tok.kind == gqlName && tok.value == "fragment":
+			p.next()
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("on"); err != nil {
+				return nil, err
+			}
+			typeCond, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Fragments[name] = gqlFragment{TypeCondition: typeCond, Selections: sels}
+		default:
+			return nil, p.errorf("définition attendue")
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("aucune opération dans le document")
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) peek() gqlToken { return p.tokens[p.pos] }
+
+func (p *gqlParser) next() gqlToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != gqlEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("erreur de syntaxe à la position %d: %s", p.peek().pos, fmt.Sprintf(format, args...))
+}
+
+func (p *gqlParser) isPunct(value string) bool {
+	tok := p.peek()
+	return tok.kind == gqlPunct && tok.value == value
+}
+
+func (p *gqlParser) expectPunct(value string) error {
+	if !p.isPunct(value) {
+		return p.errorf("%q attendu", value)
+	}
+	p.next()
+	return nil
+}
+
+func (p *gqlParser) expectName() (string, error) {
+	tok := p.peek()
+	if tok.kind != gqlName {
+		return "", p.errorf("nom attendu")
+	}
+	p.next()
+	return tok.value, nil
+}
+
+func (p *gqlParser) expectKeyword(keyword string) error {
+	tok := p.peek()
+	if tok.kind != gqlName || tok.value != keyword {
+		return p.errorf("%q attendu", keyword)
+	}
+	p.next()
+	return nil
+}
+
+func (p *gqlParser) operation() (gqlOperation, error) {
+	op := gqlOperation{Kind: p.next().value, Variables: make(map[string]gqlVariableDef)}
+	if p.peek().kind == gqlName {
+		op.Name = p.next().value
+	}
+	if p.isPunct("(") {
+		p.next()
+		for !p.isPunct(")") {
+			if err := p.expectPunct("$"); err != nil {
+				return op, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return op, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return op, err
+			}
+			typ, err := p.typeRef()
+			if err != nil {
+				return op, err
+			}
+			def := gqlVariableDef{Type: typ}
+			if p.isPunct("=") {
+				p.next()
+				if def.Default, err = p.value(true); err != nil {
+					return op, err
+				}
+			}
+			op.Variables[name] = def
+		}
+		p.next()
+	}
+	if p.isPunct("@") {
+		return op, p.errorf("directives non supportées")
+	}
+	sels, err := p.selectionSet()
+	op.Selections = sels
+	return op, err
+}
+
+func (p *gqlParser) typeRef() (string, error) {
+	var typ string
+	if p.isPunct("[") {
+		p.next()
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.isPunct("!") {
+		p.next()
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var sels []gqlSelection
+	for !p.isPunct("}") {
+		if p.peek().kind == gqlEOF {
+			return nil, p.errorf("'}' attendu")
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	p.next()
+	if len(sels) == 0 {
+		return nil, p.errorf("sélection vide")
+	}
+	return sels, nil
+}
+
+func (p *gqlParser) selection() (gqlSelection, error) {
+	if p.isPunct("...") {
+		p.next()
+		if p.peek().kind == gqlName && p.peek().value != "on" {
+			name := p.next().value
+			return gqlSelection{FragmentName: name}, nil
+		}
+		var sel gqlSelection
+		if p.peek().kind == gqlName && p.peek().value == "on" {
+			p.next()
+			typeCond, err := p.expectName()
+			if err != nil {
+				return sel, err
+			}
+			sel.TypeCondition = typeCond
+		}
+		sels, err := p.selectionSet()
+		sel.Selections = sels
+		if sel.TypeCondition == "" {
+			sel.TypeCondition = "*"
+		}
+		return sel, err
+	}
+
+	name, err := p.expectName()
+	if err != nil {
+		return gqlSelection{}, err
+	}
+	sel := gqlSelection{Alias: name, Name: name}
+	if p.isPunct(":") {
+		p.next()
+		if sel.Name, err = p.expectName(); err != nil {
+			return sel, err
+		}
+	}
+	if p.isPunct("(") {
+		p.next()
+		sel.Args = make(map[string]interface{})
+		for !p.isPunct(")") {
+			argName, err := p.expectName()
+			if err != nil {
+				return sel, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return sel, err
+			}
+			if sel.Args[argName], err = p.value(false); err != nil {
+				return sel, err
+			}
+		}
+		p.next()
+	}
+	if p.isPunct("@") {
+		return sel, p.errorf("directives non supportées")
+	}
+	if p.isPunct("{") {
+		if sel.Selections, err = p.selectionSet(); err != nil {
+			return sel, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case gqlInt:
+		p.next()
+		n, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, p.errorf("entier invalide %s", tok.value)
+		}
+		return n, nil
+	case gqlFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf("nombre invalide %s", tok.value)
+		}
+		return f, nil
+	case gqlString:
+		p.next()
+		return tok.value, nil
+	case gqlName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return tok.value, nil // valeur d'énumération
+	case gqlPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, p.errorf("variable interdite ici")
+			}
+			p.next()
+			name, err := p.expectName()
+			return gqlVariableRef{Name: name}, err
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.isPunct("]") {
+				if p.peek().kind == gqlEOF {
+					return nil, p.errorf("']' attendu")
+				}
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			p.next()
+			obj := map[string]interface{}{}
+			for !p.isPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+			return obj, nil
+		}
+	}
+	return nil, p.errorf("valeur attendue")
+}
+
+// ─── Schéma ──────────────────────────────────────────────────
+
+var gqlScalars = map[string]bool{"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true}
+
+type gqlObject struct {
+	Name   string
+	Fields map[string]*gqlField
+}
+
+type gqlField struct {
+	Type    string            // type GraphQL, ex. "[Concert!]!"
+	Args    map[string]string // nom -> type GraphQL
+	Cost    int               // coût propre du champ (1 par défaut)
+	Resolve func(p gqlParams) (interface{}, error)
+}
+
+type gqlParams struct {
+	Source interface{}
+	Args   map[string]interface{}
+	Ctx    *gqlContext
+}
+
+type gqlSchema struct {
+	Query *gqlObject
+	Types map[string]*gqlObject
+}
+
+// gqlContext porte l'état d'une requête : utilisateur, chargeurs groupés
+type gqlContext struct {
+	Server   *Server
+	ViewerID int
+	Loader   *gqlLoader
+}
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// ─── Exécution ───────────────────────────────────────────────
+
+type gqlExecutor struct {
+	schema    *gqlSchema
+	doc       *gqlDocument
+	variables map[string]interface{}
+	ctx       *gqlContext
+	errors    []GraphQLError
+}
+
+// ExecuteGraphQL analyse, valide et exécute une requête
+func ExecuteGraphQL(schema *gqlSchema, req GraphQLRequest, ctx *gqlContext) GraphQLResponse {
+	doc, err := ParseGraphQL(req.Query)
+	if err != nil {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	if op.Kind != "query" {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: "seules les opérations query sont supportées"}}}
+	}
+
+	if err := validateFragments(doc, op); err != nil {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	variables, err := coerceVariables(op.Variables, req.Variables)
+	if err != nil {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	ex := &gqlExecutor{schema: schema, doc: doc, variables: variables, ctx: ctx}
+
+	depth, err := ex.depth(op.Selections, 0)
+	if err != nil {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	if depth > GraphQLMaxDepth {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: fmt.Sprintf("profondeur supérieure à la limite de %d", GraphQLMaxDepth)}}}
+	}
+	complexity, err := ex.complexity(schema.Query, op.Selections)
+	if err != nil {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	if complexity > GraphQLMaxComplexity {
+		return GraphQLResponse{Errors: []GraphQLError{{Message: fmt.Sprintf("complexité supérieure à la limite de %d", GraphQLMaxComplexity)}}}
+	}
+
+	data := ex.executeObject(schema.Query, nil, op.Selections, nil)
+	return GraphQLResponse{Data: data, Errors: ex.errors}
+}
+
+// validateFragments refuse les fragments inconnus ou récursifs et les
+// opérations trop grandes une fois les fragments développés : l'expansion
+// faite ensuite par depth, complexity et l'exécution reste ainsi finie et bornée
+func validateFragments(doc *gqlDocument, op gqlOperation) error {
+	sizes := make(map[string]int)
+	visiting := make(map[string]bool)
+
+	var size func(sels []gqlSelection) (int, error)
+	fragmentSize := func(name string) (int, error) {
+		if n, ok := sizes[name]; ok {
+			return n, nil
+		}
+		if visiting[name] {
+			return 0, fmt.Errorf("fragment %q récursif", name)
+		}
+		frag, ok := doc.Fragments[name]
+		if !ok {
+			return 0, fmt.Errorf("fragment %q introuvable", name)
+		}
+		visiting[name] = true
+		n, err := size(frag.Selections)
+		delete(visiting, name)
+		sizes[name] = n
+		return n, err
+	}
+	size = func(sels []gqlSelection) (int, error) {
+		total := 0
+		for _, sel := range sels {
+			var n int
+			var err error
+			if sel.FragmentName != "" {
+				n, err = fragmentSize(sel.FragmentName)
+			} else {
+				n, err = size(sel.Selections)
+				if sel.TypeCondition == "" {
+					n++
+				}
+			}
+			if err != nil {
+				return 0, err
+			}
+			total = min(total+n, GraphQLMaxSelections+1)
+		}
+		return total, nil
+	}
+
+	// Les fragments non utilisés par l'opération sont aussi vérifiés
+	for name := range doc.Fragments {
+		if _, err := fragmentSize(name); err != nil {
+			return err
+		}
+	}
+	n, err := size(op.Selections)
+	if err != nil {
+		return err
+	}
+	if n > GraphQLMaxSelections {
+		return fmt.Errorf("plus de %d champs demandés", GraphQLMaxSelections)
+	}
+	return nil
+}
+
+func selectOperation(doc *gqlDocument, name string) (gqlOperation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return gqlOperation{}, fmt.Errorf("operationName requis quand le document contient plusieurs opérations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return gqlOperation{}, fmt.Errorf("opération %q introuvable", name)
+}
+
+func coerceVariables(defs map[string]gqlVariableDef, provided map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(defs))
+	for name, def := range defs {
+		raw, exists := provided[name]
+		if !exists {
+			raw = def.Default
+		}
+		v, err := coerceValue(def.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", name, err)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// coerceValue convertit une valeur (littérale ou JSON) vers le type GraphQL attendu
+func coerceValue(typ string, value interface{}) (interface{}, error) {
+	nonNull := strings.HasSuffix(typ, "!")
+	typ = strings.TrimSuffix(typ, "!")
+	if value == nil {
+		if nonNull {
+			return nil, fmt.Errorf("valeur non nulle attendue (%s!)", typ)
+		}
+		return nil, nil
+	}
+	if strings.HasPrefix(typ, "[") {
+		inner := typ[1 : len(typ)-1]
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		out := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			v, err := coerceValue(inner, item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	switch typ {
+	case "Int":
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n == float64(int(n)) {
+				return int(n), nil
+			}
+		}
+		return nil, fmt.Errorf("entier attendu, reçu %v", value)
+	case "Float":
+		switch n := value.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+		return nil, fmt.Errorf("nombre attendu, reçu %v", value)
+	case "String":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("chaîne attendue, reçu %v", value)
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int:
+			return strconv.Itoa(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return nil, fmt.Errorf("identifiant attendu, reçu %v", value)
+	case "Boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("booléen attendu, reçu %v", value)
+	}
+	return nil, fmt.Errorf("type d'argument inconnu %s", typ)
+}
+
+func (ex *gqlExecutor) resolveArgs(field *gqlField, sel gqlSelection) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(field.Args))
+	for name := range sel.Args {
+		if _, known := field.Args[name]; !known {
+			return nil, fmt.Errorf("argument inconnu %q sur %s", name, sel.Name)
+		}
+	}
+	for name, typ := range field.Args {
+		raw := ex.substitute(sel.Args[name])
+		v, err := coerceValue(typ, raw)
+		if err == nil {
+			v, err = clampPagination(name, v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("argument %s de %s: %w", name, sel.Name, err)
+		}
+		if v != nil {
+			args[name] = v
+		}
+	}
+	return args, nil
+}
+
+// clampPagination refuse first et offset négatifs et plafonne les grandes
+// valeurs, pour le découpage des listes comme pour le calcul de complexité
+func clampPagination(name string, value interface{}) (interface{}, error) {
+	n, ok := value.(int)
+	if !ok {
+		return value, nil
+	}
+	limit := 0
+	switch name {
+	case "first":
+		limit = graphQLMaxFirst
+	case "offset":
+		limit = graphQLMaxOffset
+	default:
+		return value, nil
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("valeur négative interdite")
+	}
+	return min(n, limit), nil
+}
+
+func (ex *gqlExecutor) substitute(value interface{}) interface{} {
+	switch v := value.(type) {
+	case gqlVariableRef:
+		return ex.variables[v.Name]
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = ex.substitute(item)
+		}
+		return out
+	}
+	return value
+}
+
+// expand remplace les fragments par leurs sélections pour un type donné
+func (ex *gqlExecutor) expand(typeName string, sels []gqlSelection, visiting map[string]bool) ([]gqlSelection, error) {
+	var out []gqlSelection
+	for _, sel := range sels {
+		switch {
+		case sel.FragmentName != "":
+			frag, ok := ex.doc.Fragments[sel.FragmentName]
+			if !ok {
+				return nil, fmt.Errorf("fragment %q introuvable", sel.FragmentName)
+			}
+			if visiting[sel.FragmentName] {
+				return nil, fmt.Errorf("fragment %q récursif", sel.FragmentName)
+			}
+			if frag.TypeCondition != typeName && typeName != "" {
+				continue
+			}
+			visiting[sel.FragmentName] = true
+			inner, err := ex.expand(typeName, frag.Selections, visiting)
+			delete(visiting, sel.FragmentName)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, inner...)
+		case sel.TypeCondition != "":
+			if sel.TypeCondition != "*" && sel.TypeCondition != typeName && typeName != "" {
+				continue
+			}
+			inner, err := ex.expand(typeName, sel.Selections, visiting)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, inner...)
+		default:
+			out = append(out, sel)
+		}
+	}
+	return out, nil
+}
+
+// depth renvoie la profondeur de la sélection ; le parcours s'arrête dès que
+// GraphQLMaxDepth est dépassée
+func (ex *gqlExecutor) depth(sels []gqlSelection, current int) (int, error) {
+	fields, err := ex.expand("", sels, map[string]bool{})
+	if err != nil {
+		return 0, err
+	}
+	deepest := current
+	for _, sel := range fields {
+		d := current + 1
+		if d <= GraphQLMaxDepth && len(sel.Selections) > 0 {
+			if d, err = ex.depth(sel.Selections, d); err != nil {
+				return 0, err
+			}
+		}
+		if d > GraphQLMaxDepth {
+			return d, nil
+		}
+		deepest = max(deepest, d)
+	}
+	return deepest, nil
+}
+
+// complexity additionne le coût des champs, multiplié par la taille attendue
+// des listes. Les calculs saturent et le parcours s'arrête au-delà de la limite.
+func (ex *gqlExecutor) complexity(obj *gqlObject, sels []gqlSelection) (int, error) {
+	fields, err := ex.expand(obj.Name, sels, map[string]bool{})
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, sel := range fields {
+		if sel.Name == "__typename" {
+			continue
+		}
+		field, ok := obj.Fields[sel.Name]
+		if !ok {
+			return 0, fmt.Errorf("champ %q inconnu sur %s", sel.Name, obj.Name)
+		}
+		cost := field.Cost
+		if cost == 0 {
+			cost = 1
+		}
+		base := gqlBaseType(field.Type)
+		child, isObject := ex.schema.Types[base]
+		if isObject && len(sel.Selections) == 0 {
+			return 0, fmt.Errorf("le champ %s de type %s nécessite une sélection", sel.Name, base)
+		}
+		if !isObject && len(sel.Selections) > 0 {
+			return 0, fmt.Errorf("le champ %s de type %s n'accepte pas de sélection", sel.Name, base)
+		}
+		args, err := ex.resolveArgs(field, sel)
+		if err != nil {
+			return 0, err
+		}
+		if isObject {
+			inner, err := ex.complexity(child, sel.Selections)
+			if err != nil {
+				return 0, err
+			}
+			if strings.HasPrefix(field.Type, "[") {
+				factor := graphQLListFactor
+				if first, ok := args["first"].(int); ok && first > 0 {
+					factor = first
+				}
+				inner = saturatingMul(inner, factor)
+			}
+			cost = saturatingAdd(cost, inner)
+		}
+		total = saturatingAdd(total, cost)
+		if total > GraphQLMaxComplexity {
+			return total, nil
+		}
+	}
+	return total, nil
+}
+
+// saturatingAdd et saturatingMul opèrent sur des coûts positifs sans
+// dépasser math.MaxInt
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
+
+func gqlBaseType(typ string) string {
+	return strings.Trim(typ, "[]!")
+}
+
+func (ex *gqlExecutor) executeObject(obj *gqlObject, source interface{}, sels []gqlSelection, path []interface{}) *gqlOrderedMap {
+	fields, _ := ex.expand(obj.Name, sels, map[string]bool{})
+	result := newGQLOrderedMap()
+	for _, sel := range fields {
+		fieldPath := append(append([]interface{}{}, path...), sel.Alias)
+		if sel.Name == "__typename" {
+			result.Set(sel.Alias, obj.Name)
+			continue
+		}
+		field := obj.Fields[sel.Name]
+		args, err := ex.resolveArgs(field, sel)
+		if err != nil {
+			ex.addError(err, fieldPath)
+			result.Set(sel.Alias, nil)
+			continue
+		}
+		value, err := field.Resolve(gqlParams{Source: source, Args: args, Ctx: ex.ctx})
+		if err != nil {
+			ex.addError(err, fieldPath)
+			result.Set(sel.Alias, nil)
+			continue
+		}
+		result.Set(sel.Alias, ex.completeValue(field.Type, value, sel.Selections, fieldPath))
+	}
+	return result
+}
+
+func (ex *gqlExecutor) completeValue(typ string, value interface{}, sels []gqlSelection, path []interface{}) interface{} {
+	typ = strings.TrimSuffix(typ, "!")
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	if strings.HasPrefix(typ, "[") {
+		inner := typ[1 : len(typ)-1]
+		if rv.Kind() != reflect.Slice {
+			ex.addError(fmt.Errorf("liste attendue"), path)
+			return nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = ex.completeValue(inner, rv.Index(i).Interface(), sels, append(append([]interface{}{}, path...), i))
+		}
+		return out
+	}
+	if gqlScalars[typ] {
+		return value
+	}
+	return ex.executeObject(ex.schema.Types[typ], value, sels, path)
+}
+
+func (ex *gqlExecutor) addError(err error, path []interface{}) {
+	ex.errors = append(ex.errors, GraphQLError{Message: err.Error(), Path: path})
+}
+
+// gqlOrderedMap conserve l'ordre des champs demandé dans la requête
+type gqlOrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newGQLOrderedMap() *gqlOrderedMap {
+	return &gqlOrderedMap{values: make(map[string]interface{})}
+}
+
+func (m *gqlOrderedMap) Set(key string, value interface{}) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *gqlOrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,537 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types sources des objets GraphQL qui n'ont pas d'équivalent direct dans models.go
+type gqlMember struct {
+	Name   string
+	Artist Artist
+}
+
+type gqlLocation struct {
+	Raw      string
+	Concerts []gqlConcert
+}
+
+type gqlConcert struct {
+	Artist   Artist
+	Location string
+	Date     string
+}
+
+// ─── Chargement groupé ───────────────────────────────────────
+
+// gqlLoader regroupe les accès base de données d'une requête GraphQL :
+// favoris et nombres de commentaires sont lus en une requête chacun,
+// et les commentaires de tous les artistes d'une liste en une seule requête.
+type gqlLoader struct {
+	mu            sync.Mutex
+	viewerID      int
+	favorites     map[int]bool
+	commentCounts map[int]int
+	comments      map[int][]Comment
+	pending       map[int]bool
+}
+
+func newGQLLoader(viewerID int) *gqlLoader {
+	return &gqlLoader{viewerID: viewerID, comments: make(map[int][]Comment), pending: make(map[int]bool)}
+}
+
+// Prime annonce des artistes dont les commentaires seront probablement demandés
+func (l *gqlLoader) Prime(artists []Artist) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, art := range artists {
+		if _, loaded := l.comments[art.ID]; !loaded {
+			l.pending[art.ID] = true
+		}
+	}
+}
+
+func (l *gqlLoader) IsFavorite(artistID int) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.viewerID == 0 {
+		return false, nil
+	}
+	if l.favorites == nil {
+		ids, err := GetUserFavorites(DB, l.viewerID)
+		if err != nil {
+			return false, err
+		}
+		l.favorites = make(map[int]bool, len(ids))
+		for _, id := range ids {
+			l.favorites[id] = true
+		}
+	}
+	return l.favorites[artistID], nil
+}
+
+func (l *gqlLoader) CommentCount(artistID int) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.commentCounts == nil {
+		counts, err := CountCommentsByArtist(DB)
+		if err != nil {
+			return 0, err
+		}
+		l.commentCounts = counts
+	}
+	return l.commentCounts[artistID], nil
+}
+
+func (l *gqlLoader) Comments(artistID int) ([]Comment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if comments, loaded := l.comments[artistID]; loaded {
+		return comments, nil
+	}
+	l.pending[artistID] = true
+	ids := make([]int, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	byArtist, err := GetCommentsByArtists(DB, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		l.comments[id] = byArtist[id]
+		delete(l.pending, id)
+	}
+	return l.comments[artistID], nil
+}
+
+// ─── Schéma ──────────────────────────────────────────────────
+
+var (
+	graphQLSchemaOnce sync.Once
+	graphQLSchema     *gqlSchema
+)
+
+func GraphQLSchema() *gqlSchema {
+	graphQLSchemaOnce.Do(func() {
+		graphQLSchema = buildGraphQLSchema()
+	})
+	return graphQLSchema
+}
+
+func buildGraphQLSchema() *gqlSchema {
+	artist := &gqlObject{Name: "Artist"}
+	member := &gqlObject{Name: "Member"}
+	location := &gqlObject{Name: "Location"}
+	concert := &gqlObject{Name: "Concert"}
+	coordinates := &gqlObject{Name: "Coordinates"}
+	comment := &gqlObject{Name: "Comment"}
+	user := &gqlObject{Name: "UserProfile"}
+	search := &gqlObject{Name: "SearchResult"}
+
+	artist.Fields = map[string]*gqlField{
+		"id":           {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Artist).ID, nil }},
+		"name":         {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Artist).Name, nil }},
+		"image":        {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Artist).Image, nil }},
+		"creationDate": {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Artist).CreationDate, nil }},
+		"firstAlbum":   {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Artist).FirstAlbum, nil }},
+		"concertCount": {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) { return len(p.Source.(Artist).ConcertDates), nil }},
+		"members": {Type: "[Member!]!", Resolve: func(p gqlParams) (interface{}, error) {
+			art := p.Source.(Artist)
+			members := make([]gqlMember, 0, len(art.Members))
+			for _, name := range art.Members {
+				members = append(members, gqlMember{Name: name, Artist: art})
+			}
+			return members, nil
+		}},
+		"locations": {Type: "[Location!]!", Resolve: func(p gqlParams) (interface{}, error) {
+			return artistLocations(p.Source.(Artist)), nil
+		}},
+		"concerts": {Type: "[Concert!]!", Resolve: func(p gqlParams) (interface{}, error) {
+			return artistConcerts(p.Source.(Artist)), nil
+		}},
+		"commentCount": {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) {
+			return p.Ctx.Loader.CommentCount(p.Source.(Artist).ID)
+		}},
+		"comments": {Type: "[Comment!]!", Args: map[string]string{"first": "Int"}, Resolve: func(p gqlParams) (interface{}, error) {
+			comments, err := p.Ctx.Loader.Comments(p.Source.(Artist).ID)
+			if err != nil {
+				return nil, err
+			}
+			if first, ok := p.Args["first"].(int); ok && first >= 0 && first < len(comments) {
+				comments = comments[:first]
+			}
+			return comments, nil
+		}},
+		"isFavorite": {Type: "Boolean!", Resolve: func(p gqlParams) (interface{}, error) {
+			return p.Ctx.Loader.IsFavorite(p.Source.(Artist).ID)
+		}},
+	}
+
+	member.Fields = map[string]*gqlField{
+		"name":   {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(gqlMember).Name, nil }},
+		"artist": {Type: "Artist!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(gqlMember).Artist, nil }},
+	}
+
+	location.Fields = map[string]*gqlField{
+		"name":  {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(gqlLocation).Raw, nil }},
+		"label": {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return FormatLocation(p.Source.(gqlLocation).Raw), nil }},
+		"concerts": {Type: "[Concert!]!", Resolve: func(p gqlParams) (interface{}, error) {
+			return p.Source.(gqlLocation).Concerts, nil
+		}},
+		// Cache de géocodage uniquement : /graphql est public et ne doit pas
+		// contourner la limite "geocode" de /api/geocode en appelant Nominatim.
+		// null tant que le lieu n'a pas été géocodé.
+		"coordinates": {Type: "Coordinates", Resolve: func(p gqlParams) (interface{}, error) {
+			if coords, ok := CachedCoordinates(p.Source.(gqlLocation).Raw); ok {
+				return coords, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	concert.Fields = map[string]*gqlField{
+		"date":   {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(gqlConcert).Date, nil }},
+		"artist": {Type: "Artist!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(gqlConcert).Artist, nil }},
+		"location": {Type: "Location!", Resolve: func(p gqlParams) (interface{}, error) {
+			c := p.Source.(gqlConcert)
+			return gqlLocation{Raw: c.Location, Concerts: []gqlConcert{c}}, nil
+		}},
+	}
+
+	coordinates.Fields = map[string]*gqlField{
+		"latitude":  {Type: "Float!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Coordinates).Latitude, nil }},
+		"longitude": {Type: "Float!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Coordinates).Longitude, nil }},
+	}
+
+	comment.Fields = map[string]*gqlField{
+		"id":        {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Comment).ID, nil }},
+		"content":   {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Comment).Content, nil }},
+		"createdAt": {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(Comment).CreatedAt, nil }},
+		"artist": {Type: "Artist", Resolve: func(p gqlParams) (interface{}, error) {
+			if art, ok := p.Ctx.Server.FindArtist(p.Source.(Comment).ArtistID); ok {
+				return art, nil
+			}
+			return nil, nil
+		}},
+		// L'auteur est reconstruit depuis la jointure faite avec les commentaires
+		"author": {Type: "UserProfile!", Resolve: func(p gqlParams) (interface{}, error) {
+			c := p.Source.(Comment)
+			return UserProfile{ID: c.UserID, Username: c.Username, PhotoProfil: c.Photo}, nil
+		}},
+	}
+
+	user.Fields = map[string]*gqlField{
+		"id":          {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(UserProfile).ID, nil }},
+		"username":    {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(UserProfile).Username, nil }},
+		"pseudo":      {Type: "String", Resolve: func(p gqlParams) (interface{}, error) { return nullableString(p.Source.(UserProfile).Pseudo), nil }},
+		"bio":         {Type: "String", Resolve: func(p gqlParams) (interface{}, error) { return nullableString(p.Source.(UserProfile).Bio), nil }},
+		"photoProfil": {Type: "String", Resolve: func(p gqlParams) (interface{}, error) { return nullableString(p.Source.(UserProfile).PhotoProfil), nil }},
+		"role":        {Type: "String", Resolve: func(p gqlParams) (interface{}, error) { return viewerOnly(p, p.Source.(UserProfile).Role), nil }},
+		"email":       {Type: "String", Resolve: func(p gqlParams) (interface{}, error) { return viewerOnly(p, p.Source.(UserProfile).Email), nil }},
+		"favorites": {Type: "[Artist!]", Resolve: func(p gqlParams) (interface{}, error) {
+			profile := p.Source.(UserProfile)
+			if profile.ID != p.Ctx.ViewerID {
+				return nil, nil
+			}
+			ids, err := GetUserFavorites(DB, profile.ID)
+			if err != nil {
+				return nil, err
+			}
+			artists := make([]Artist, 0, len(ids))
+			for _, id := range ids {
+				if art, ok := p.Ctx.Server.FindArtist(id); ok {
+					artists = append(artists, art)
+				}
+			}
+			p.Ctx.Loader.Prime(artists)
+			return artists, nil
+		}},
+	}
+
+	search.Fields = map[string]*gqlField{
+		"type":  {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(APISearchResult).Type, nil }},
+		"value": {Type: "String!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(APISearchResult).Value, nil }},
+		"artist": {Type: "Artist", Resolve: func(p gqlParams) (interface{}, error) {
+			if art, ok := p.Ctx.Server.FindArtist(p.Source.(APISearchResult).ArtistID); ok {
+				return art, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	query := &gqlObject{Name: "Query", Fields: map[string]*gqlField{
+		"artists": {
+			Type: "[Artist!]!",
+			Args: map[string]string{
+				"q": "String", "minCreation": "Int", "maxCreation": "Int",
+				"minFirstAlbum": "Int", "maxFirstAlbum": "Int", "members": "[Int!]", "location": "String",
+				"first": "Int", "offset": "Int",
+			},
+			Resolve: func(p gqlParams) (interface{}, error) {
+				filters := ArtistFilters{
+					Query:         argString(p.Args, "q"),
+					MinCreation:   argInt(p.Args, "minCreation", 0),
+					MaxCreation:   argInt(p.Args, "maxCreation", 0),
+					MinFirstAlbum: argInt(p.Args, "minFirstAlbum", 0),
+					MaxFirstAlbum: argInt(p.Args, "maxFirstAlbum", 0),
+					Location:      argString(p.Args, "location"),
+				}
+				if members, ok := p.Args["members"].([]interface{}); ok {
+					for _, m := range members {
+						filters.MembersCount = append(filters.MembersCount, m.(int))
+					}
+				}
+				artists := ApplyArtistFilters(p.Ctx.Server.ListArtists(), filters)
+				artists = sliceWindow(artists, argInt(p.Args, "offset", 0), argInt(p.Args, "first", DefaultPerPage))
+				p.Ctx.Loader.Prime(artists)
+				return artists, nil
+			},
+		},
+		"artist": {
+			Type: "Artist",
+			Args: map[string]string{"id": "Int!"},
+			Resolve: func(p gqlParams) (interface{}, error) {
+				if art, ok := p.Ctx.Server.FindArtist(p.Args["id"].(int)); ok {
+					return art, nil
+				}
+				return nil, nil
+			},
+		},
+		"locations": {
+			Type: "[Location!]!",
+			Args: map[string]string{"q": "String", "first": "Int", "offset": "Int"},
+			Resolve: func(p gqlParams) (interface{}, error) {
+				needle := strings.ToLower(argString(p.Args, "q"))
+				byRaw := make(map[string]*gqlLocation)
+				for _, art := range p.Ctx.Server.ListArtists() {
+					for _, loc := range artistLocations(art) {
+						if needle != "" && !strings.Contains(strings.ToLower(FormatLocation(loc.Raw)), needle) {
+							continue
+						}
+						entry, exists := byRaw[loc.Raw]
+						if !exists {
+							entry = &gqlLocation{Raw: loc.Raw}
+							byRaw[loc.Raw] = entry
+						}
+						entry.Concerts = append(entry.Concerts, loc.Concerts...)
+					}
+				}
+				locations := make([]gqlLocation, 0, len(byRaw))
+				for _, loc := range byRaw {
+					locations = append(locations, *loc)
+				}
+				sort.Slice(locations, func(i, j int) bool { return locations[i].Raw < locations[j].Raw })
+				return sliceWindow(locations, argInt(p.Args, "offset", 0), argInt(p.Args, "first", DefaultPerPage)), nil
+			},
+		},
+		"concerts": {
+			Type: "[Concert!]!",
+			Args: map[string]string{"artistId": "Int", "location": "String", "first": "Int", "offset": "Int"},
+			Resolve: func(p gqlParams) (interface{}, error) {
+				artistID := argInt(p.Args, "artistId", 0)
+				needle := strings.ToLower(argString(p.Args, "location"))
+				var concerts []gqlConcert
+				for _, art := range p.Ctx.Server.ListArtists() {
+					if artistID != 0 && art.ID != artistID {
+						continue
+					}
+					for _, c := range artistConcerts(art) {
+						if needle != "" && !strings.Contains(strings.ToLower(FormatLocation(c.Location)), needle) {
+							continue
+						}
+						concerts = append(concerts, c)
+					}
+				}
+				sort.SliceStable(concerts, func(i, j int) bool {
+					di, erri := ParseConcertDate(concerts[i].Date)
+					dj, errj := ParseConcertDate(concerts[j].Date)
+					return erri == nil && errj == nil && di.Before(dj)
+				})
+				return sliceWindow(concerts, argInt(p.Args, "offset", 0), argInt(p.Args, "first", DefaultPerPage)), nil
+			},
+		},
+		"search": {
+			Type: "[SearchResult!]!",
+			Args: map[string]string{"q": "String!", "first": "Int"},
+			Resolve: func(p gqlParams) (interface{}, error) {
+				results := SearchArtists(p.Ctx.Server.ListArtists(), p.Args["q"].(string))
+				return sliceWindow(results, 0, argInt(p.Args, "first", DefaultPerPage)), nil
+			},
+		},
+		"viewer": {
+			Type: "UserProfile",
+			Resolve: func(p gqlParams) (interface{}, error) {
+				if p.Ctx.ViewerID == 0 {
+					return nil, nil
+				}
+				u, err := GetUserByID(DB, p.Ctx.ViewerID)
+				if err != nil {
+					return nil, err
+				}
+				return UserProfile{
+					ID:          u.ID,
+					Username:    u.Username,
+					Email:       u.Email,
+					Pseudo:      getStringValue(u.Pseudo),
+					Bio:         getStringValue(u.Bio),
+					PhotoProfil: getStringValue(u.PhotoProfil),
+					Role:        u.Role,
+				}, nil
+			},
+		},
+	}}
+
+	return &gqlSchema{
+		Query: query,
+		Types: map[string]*gqlObject{
+			"Query": query, "Artist": artist, "Member": member, "Location": location, "Concert": concert,
+			"Coordinates": coordinates, "Comment": comment, "UserProfile": user, "SearchResult": search,
+		},
+	}
+}
+
+func artistLocations(art Artist) []gqlLocation {
+	locations := make([]gqlLocation, 0, len(art.DatesLocations))
+	for _, ld := range BuildLocationDates(art.DatesLocations) {
+		loc := gqlLocation{Raw: ld.Raw}
+		for _, date := range ld.Dates {
+			loc.Concerts = append(loc.Concerts, gqlConcert{Artist: art, Location: ld.Raw, Date: date})
+		}
+		locations = append(locations, loc)
+	}
+	return locations
+}
+
+func artistConcerts(art Artist) []gqlConcert {
+	var concerts []gqlConcert
+	for _, loc := range artistLocations(art) {
+		concerts = append(concerts, loc.Concerts...)
+	}
+	return concerts
+}
+
+func viewerOnly(p gqlParams, value string) interface{} {
+	if p.Source.(UserProfile).ID != p.Ctx.ViewerID || p.Ctx.ViewerID == 0 {
+		return nil
+	}
+	return value
+}
+
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func argString(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func argInt(args map[string]interface{}, name string, fallback int) int {
+	if n, ok := args[name].(int); ok {
+		return n
+	}
+	return fallback
+}
+
+func sliceWindow[T any](items []T, offset, first int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := len(items)
+	if first >= 0 && first < end-offset {
+		end = offset + first
+	}
+	return items[offset:end]
+}
+
+// ─── Handler ─────────────────────────────────────────────────
+
+// HandleGraphQL exécute une requête GraphQL (GET ?query= ou POST JSON).
+// L'utilisateur est identifié par session ou par jeton Bearer (portée read).
+func (s *Server) HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if raw := r.URL.Query().Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				writeGraphQL(w, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "variables invalides"}}})
+				return
+			}
+		}
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGraphQL(w, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "corps JSON invalide"}}})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeGraphQL(w, http.StatusMethodNotAllowed, GraphQLResponse{Errors: []GraphQLError{{Message: "Méthode non supportée"}}})
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQL(w, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "paramètre query manquant"}}})
+		return
+	}
+
+	viewerID, err := graphQLViewer(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeGraphQL(w, http.StatusUnauthorized, GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}})
+		return
+	}
+
+	ctx := &gqlContext{Server: s, ViewerID: viewerID, Loader: newGQLLoader(viewerID)}
+	resp := ExecuteGraphQL(GraphQLSchema(), req, ctx)
+	status := http.StatusOK
+	if resp.Data == nil && len(resp.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	writeGraphQL(w, status, resp)
+}
+
+func graphQLViewer(r *http.Request) (int, error) {
+	if raw, ok := BearerToken(r); ok {
+		token, err := GetAPITokenByValue(DB, raw)
+		if err != nil {
+			return 0, err
+		}
+		if !token.HasScope(ScopeRead) {
+			return 0, fmt.Errorf("portée requise: %s", ScopeRead)
+		}
+		if err := TouchAPIToken(DB, token.ID); err != nil {
+			log.Printf("Erreur mise à jour jeton %d: %v", token.ID, err)
+		}
+		if sanction, suspended, err := GetActiveSanction(DB, token.UserID, time.Now()); err == nil && suspended {
+			return 0, errors.New(sanction.Message())
+		}
+		return token.UserID, nil
+	}
+	userID, _ := CurrentUserID(r)
+	return userID, nil
+}
+
+func writeGraphQL(w http.ResponseWriter, status int, resp GraphQLResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("encodage réponse GraphQL: %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testGraphQLSchema est un schéma minimal, sans base de données : un nœud
+// récursif permet de construire des requêtes aussi profondes que voulu
+func testGraphQLSchema() *gqlSchema {
+	node := &gqlObject{Name: "Node"}
+	items := []int{1, 2, 3, 4, 5}
+	list := &gqlField{
+		Type: "[Node!]!",
+		Args: map[string]string{"first": "Int", "offset": "Int"},
+		Resolve: func(p gqlParams) (interface{}, error) {
+			return sliceWindow(items, argInt(p.Args, "offset", 0), argInt(p.Args, "first", -1)), nil
+		},
+	}
+	node.Fields = map[string]*gqlField{
+		"id":    {Type: "Int!", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(int), nil }},
+		"label": {Type: "String!", Args: map[string]string{"prefix": "String"}, Resolve: func(p gqlParams) (interface{}, error) { return argString(p.Args, "prefix") + fmt.Sprint(p.Source), nil }},
+		"nodes": list,
+		"next":  {Type: "Node", Resolve: func(p gqlParams) (interface{}, error) { return p.Source.(int) + 1, nil }},
+	}
+	query := &gqlObject{Name: "Query", Fields: map[string]*gqlField{
+		"nodes": list,
+		"node": {Type: "Node", Args: map[string]string{"id": "Int!"}, Resolve: func(p gqlParams) (interface{}, error) {
+			return p.Args["id"], nil
+		}},
+	}}
+	return &gqlSchema{Query: query, Types: map[string]*gqlObject{"Query": query, "Node": node}}
+}
+
+func runGraphQL(t *testing.T, query string, variables map[string]interface{}) (string, []GraphQLError) {
+	t.Helper()
+	resp := ExecuteGraphQL(testGraphQLSchema(), GraphQLRequest{Query: query, Variables: variables}, &gqlContext{})
+	if resp.Data == nil {
+		return "", resp.Errors
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("encodage: %v", err)
+	}
+	return string(data), resp.Errors
+}
+
+func expectGraphQLError(t *testing.T, query string, variables map[string]interface{}, contains string) {
+	t.Helper()
+	data, errs := runGraphQL(t, query, variables)
+	if len(errs) == 0 {
+		t.Fatalf("erreur attendue, reçu %s", data)
+	}
+	if !strings.Contains(errs[0].Message, contains) {
+		t.Fatalf("erreur %q, attendu %q", errs[0].Message, contains)
+	}
+}
+
+func TestParseGraphQL(t *testing.T) {
+	doc, err := ParseGraphQL(`
+		# commentaire
+		query Liste($n: Int = 2, $ids: [Int!]!) {
+			premiers: nodes(first: $n) { id ...Infos }
+			... on Query { node(id: 3) { id } }
+		}
+		fragment Infos on Node { label(prefix: "n°é") }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Operations) != 1 {
+		t.Fatalf("%d opérations", len(doc.Operations))
+	}
+	op := doc.Operations[0]
+	if op.Kind != "query" || op.Name != "Liste" {
+		t.Errorf("opération %s %s", op.Kind, op.Name)
+	}
+	if def := op.Variables["n"]; def.Type != "Int" || def.Default != 2 {
+		t.Errorf("variable n: %+v", def)
+	}
+	if def := op.Variables["ids"]; def.Type != "[Int!]!" {
+		t.Errorf("variable ids: %+v", def)
+	}
+	first := op.Selections[0]
+	if first.Alias != "premiers" || first.Name != "nodes" {
+		t.Errorf("alias: %+v", first)
+	}
+	if ref, ok := first.Args["first"].(gqlVariableRef); !ok || ref.Name != "n" {
+		t.Errorf("argument first: %#v", first.Args["first"])
+	}
+	if first.Selections[1].FragmentName != "Infos" {
+		t.Errorf("fragment: %+v", first.Selections[1])
+	}
+	if op.Selections[1].TypeCondition != "Query" {
+		t.Errorf("fragment en ligne: %+v", op.Selections[1])
+	}
+	frag := doc.Fragments["Infos"]
+	if frag.TypeCondition != "Node" || frag.Selections[0].Args["prefix"] != "n°é" {
+		t.Errorf("fragment Infos: %+v", frag)
+	}
+}
+
+func TestParseGraphQLErrors(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{ nodes { id }`,
+		`{ }`,
+		`{ nodes(first: ) { id } }`,
+		`{ nodes @include(if: true) { id } }`,
+		`{ label(prefix: "non terminée) }`,
+		`fragment F on Node { id }`,
+		`{ a . b }`,
+	} {
+		if _, err := ParseGraphQL(query); err == nil {
+			t.Errorf("%q: erreur de syntaxe attendue", query)
+		}
+	}
+}
+
+func TestExecuteGraphQL(t *testing.T) {
+	data, errs := runGraphQL(t, `
+		query($n: Int, $id: Int!) {
+			nodes(first: $n, offset: 1) { id ...Label }
+			seul: node(id: $id) { __typename next { id } }
+		}
+		fragment Label on Node { label(prefix: "#") }
+	`, map[string]interface{}{"n": float64(2), "id": float64(4)})
+	if len(errs) > 0 {
+		t.Fatalf("erreurs: %+v", errs)
+	}
+	want := `{"nodes":[{"id":2,"label":"#2"},{"id":3,"label":"#3"}],"seul":{"__typename":"Node","next":{"id":5}}}`
+	if data != want {
+		t.Errorf("données:\n%s\nattendu:\n%s", data, want)
+	}
+}
+
+func TestGraphQLVariables(t *testing.T) {
+	data, errs := runGraphQL(t, `query($n: Int = 1) { nodes(first: $n) { id } }`, nil)
+	if len(errs) > 0 || data != `{"nodes":[{"id":1}]}` {
+		t.Errorf("valeur par défaut: %s %+v", data, errs)
+	}
+	expectGraphQLError(t, `query($id: Int!) { node(id: $id) { id } }`, nil, "valeur non nulle attendue")
+	expectGraphQLError(t, `query($id: Int!) { node(id: $id) { id } }`, map[string]interface{}{"id": "abc"}, "entier attendu")
+	expectGraphQLError(t, `query($id: Int!) { node(id: $id) { id } }`, map[string]interface{}{"id": 1.5}, "entier attendu")
+}
+
+func TestGraphQLFragmentCycles(t *testing.T) {
+	tests := map[string]string{
+		"fragment auto-référencé":     `query { ...A } fragment A on Query { nodes { ...A } }`,
+		"spread direct":               `query { nodes { ...A } } fragment A on Node { ...A }`,
+		"cycle mutuel":                `query { nodes { ...A } } fragment A on Node { nodes { ...B } } fragment B on Node { next { ...A } }`,
+		"cycle non utilisé":           `query { nodes { id } } fragment A on Node { ...B } fragment B on Node { ...A }`,
+		"cycle via fragment en ligne": `query { nodes { ...A } } fragment A on Node { ... on Node { nodes { ...A } } }`,
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			expectGraphQLError(t, query, nil, "récursif")
+		})
+	}
+	expectGraphQLError(t, `{ nodes { ...Absent } }`, nil, "introuvable")
+}
+
+func TestGraphQLFragmentExpansionLimit(t *testing.T) {
+	// Chaque fragment double le précédent : 2^30 champs une fois développés
+	var sb strings.Builder
+	sb.WriteString(`{ nodes { ...F0 } }`)
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&sb, " fragment F%d on Node { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	sb.WriteString(" fragment F30 on Node { id }")
+	expectGraphQLError(t, sb.String(), nil, "champs demandés")
+}
+
+func TestGraphQLDepthLimit(t *testing.T) {
+	nested := func(depth int) string {
+		return "{ node(id: 1) " + strings.Repeat("{ next ", depth-1) + "{ id }" + strings.Repeat(" }", depth-1) + " }"
+	}
+	if _, errs := runGraphQL(t, nested(GraphQLMaxDepth-1), nil); len(errs) > 0 {
+		t.Fatalf("profondeur autorisée refusée: %+v", errs)
+	}
+	expectGraphQLError(t, nested(GraphQLMaxDepth), nil, "profondeur")
+	expectGraphQLError(t, nested(500), nil, "profondeur")
+}
+
+func TestGraphQLComplexityLimit(t *testing.T) {
+	// Sans plafond, first * first * ... dépassait la capacité d'un int
+	huge := fmt.Sprint(math.MaxInt / 2)
+	expectGraphQLError(t, `{ nodes(first: `+huge+`) { nodes(first: `+huge+`) { nodes(first: `+huge+`) { id } } } }`, nil, "complexité")
+	expectGraphQLError(t, `{ nodes(first: 100) { nodes(first: 100) { id } } }`, nil, "complexité")
+	if _, errs := runGraphQL(t, `{ nodes(first: 10) { nodes(first: 10) { id } } }`, nil); len(errs) > 0 {
+		t.Fatalf("requête raisonnable refusée: %+v", errs)
+	}
+}
+
+func TestGraphQLPaginationArguments(t *testing.T) {
+	expectGraphQLError(t, `{ nodes(first: -1) { id } }`, nil, "négative")
+	expectGraphQLError(t, `query($o: Int) { nodes(offset: $o) { id } }`, map[string]interface{}{"o": float64(-3)}, "négative")
+
+	data, errs := runGraphQL(t, fmt.Sprintf(`{ nodes(first: 2, offset: %d) { id } }`, math.MaxInt), nil)
+	if len(errs) > 0 || data != `{"nodes":[]}` {
+		t.Errorf("grand offset: %s %+v", data, errs)
+	}
+	if got, _ := clampPagination("first", 1000000); got != graphQLMaxFirst {
+		t.Errorf("first plafonné à %v, attendu %d", got, graphQLMaxFirst)
+	}
+}
+
+func TestSliceWindow(t *testing.T) {
+	items := []int{1, 2, 3}
+	tests := []struct {
+		offset, first int
+		want          int
+	}{
+		{0, -1, 3},
+		{1, 1, 1},
+		{2, 10, 1},
+		{5, 1, 0},
+		{1, math.MaxInt, 2},
+		{-4, 2, 2},
+	}
+	for _, tt := range tests {
+		if got := sliceWindow(items, tt.offset, tt.first); len(got) != tt.want {
+			t.Errorf("sliceWindow(%d, %d) = %v", tt.offset, tt.first, got)
+		}
+	}
+}
+
+// TestGraphQLHandlerRecursiveFragment reproduit la requête qui faisait
+// déborder la pile du serveur
+func TestGraphQLHandlerRecursiveFragment(t *testing.T) {
+	body := `{"query":"query { ...A } fragment A on Query { artists { ...A } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	testServer().Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "récursif") {
+		t.Fatalf("statut %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGraphQLArtistsQuery(t *testing.T) {
+	srv := testServer()
+	resp := ExecuteGraphQL(GraphQLSchema(), GraphQLRequest{Query: `{ artists(first: 1) { id name } artist(id: 2) { name } }`}, &gqlContext{Server: srv, Loader: newGQLLoader(0)})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("erreurs: %+v", resp.Errors)
+	}
+	data, _ := json.Marshal(resp.Data)
+	if want := `{"artists":[{"id":1,"name":"Queen"}],"artist":{"name":"Pink Floyd"}}`; string(data) != want {
+		t.Errorf("données %s, attendu %s", data, want)
+	}
+}
+
+// TestGraphQLCoordinatesCacheOnly vérifie qu'une requête anonyme ne déclenche
+// aucun géocodage : seuls les lieux déjà en cache ont des coordonnées
+func TestGraphQLCoordinatesCacheOnly(t *testing.T) {
+	cacheMutex.Lock()
+	geocodeCache["london-uk"] = Coordinates{Latitude: 51.5, Longitude: -0.12}
+	cacheMutex.Unlock()
+	defer func() {
+		cacheMutex.Lock()
+		delete(geocodeCache, "london-uk")
+		cacheMutex.Unlock()
+	}()
+	// Toute requête sortante passerait par le transport par défaut
+	var outbound int
+	previous := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		outbound++
+		return nil, fmt.Errorf("requête sortante vers %s", r.URL.Host)
+	})
+	defer func() { http.DefaultTransport = previous }()
+
+	body := `{"query":"{ artist(id: 1) { locations { name coordinates { latitude } } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	testServer().Routes().ServeHTTP(rec, req)
+
+	want := `{"data":{"artist":{"locations":[{"name":"london-uk","coordinates":{"latitude":51.5}},{"name":"paris-france","coordinates":null}]}}}`
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != want {
+		t.Fatalf("statut %d: %s", rec.Code, rec.Body.String())
+	}
+	if outbound != 0 {
+		t.Errorf("%d géocodage(s) déclenché(s)", outbound)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
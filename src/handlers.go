@@ -3,6 +3,7 @@ package src
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -22,29 +23,50 @@ func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
 			user, err := GetUserByID(DB, userID)
 			if err == nil {
 				userProfile = &UserProfile{
-					ID:          user.ID,
-					Username:    user.Username,
-					Email:       user.Email,
-					Pseudo:      getStringValue(user.Pseudo),
-					Bio:         getStringValue(user.Bio),
-					PhotoProfil: getStringValue(user.PhotoProfil),
-					Role:        user.Role,
+					ID:            user.ID,
+					Username:      user.Username,
+					Email:         user.Email,
+					Pseudo:        getStringValue(user.Pseudo),
+					Bio:           getStringValue(user.Bio),
+					PhotoProfil:   getStringValue(user.PhotoProfil),
+					Role:          user.Role,
+					EmailVerified: user.EmailVerifiedAt.Valid,
 				}
 			}
 		}
 	}
 
-	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	artists := s.ListArtists()
-	filtered := FilterArtists(artists, query)
-	data := IndexPageData{
-		Query:   query,
-		Count:   len(filtered),
-		Total:   len(artists),
-		Artists: filtered,
-		User:    userProfile,
+	filters, err := ParseArtistFilters(r.URL.Query())
+	var filterError string
+	if err != nil {
+		// Filtres invalides : on garde la recherche textuelle seule
+		filterError = err.Error()
+		filters = ArtistFilters{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+	}
+	filtered := ApplyArtistFilters(artists, filters)
+	if filters.Favorites {
+		if userProfile == nil {
+			filterError = "Connectez-vous pour n'afficher que vos favoris"
+		} else if favorites, err := filterFavorites(DB, filtered, filters, userProfile.ID); err != nil {
+			log.Printf("Erreur filtre favoris: %v", err)
+			filterError = "Vos favoris sont momentanément indisponibles"
+		} else {
+			filtered = favorites
+		}
 	}
-	s.Render(w, "index.html", data)
+	data := IndexPageData{
+		Query:         filters.Query,
+		Count:         len(filtered),
+		Total:         len(artists),
+		Artists:       filtered,
+		User:          userProfile,
+		Filters:       filters,
+		FilterValues:  filters.Values(),
+		FilterError:   filterError,
+		ExportColumns: ExportColumns(),
+	}
+	s.Render(w, r, "index.html", data)
 }
 
 func (s *Server) HandleProfile(w http.ResponseWriter, r *http.Request) {
@@ -70,19 +92,67 @@ func (s *Server) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := IndexPageData{
+	tokens, err := GetUserAPITokens(DB, userID)
+	if err != nil {
+		log.Printf("Erreur récupération jetons: %v", err)
+	}
+
+	data := ProfilePageData{
 		User: &UserProfile{
-			ID:          user.ID,
-			Username:    user.Username,
-			Email:       user.Email,
-			Pseudo:      getStringValue(user.Pseudo),
-			Bio:         getStringValue(user.Bio),
-			PhotoProfil: getStringValue(user.PhotoProfil),
-			Role:        user.Role,
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			Pseudo:        getStringValue(user.Pseudo),
+			Bio:           getStringValue(user.Bio),
+			PhotoProfil:   getStringValue(user.PhotoProfil),
+			Role:          user.Role,
+			EmailVerified: user.EmailVerifiedAt.Valid,
+
+			TwoFactorEnabled: user.TOTPEnabledAt.Valid,
 		},
+		Tokens:            tokens,
+		TokenScopes:       TokenScopes,
+		TwoFactorRequired: TwoFactorRequired(user),
+	}
+
+	if data.PendingEmail, err = PendingEmailChange(DB, userID); err != nil {
+		log.Printf("Erreur lecture changement email: %v", err)
+	}
+	if data.Sessions, err = GetUserSessions(DB, userID, session.ID); err != nil {
+		log.Printf("Erreur chargement sessions: %v", err)
+	}
+	if data.Identities, err = GetUserIdentities(DB, userID); err != nil {
+		log.Printf("Erreur chargement identités: %v", err)
+	}
+	data.LinkableProviders = unlinkedProviders(data.Identities)
+	data.HasPassword = user.PasswordHash != noPasswordHash
+	if user.DeletionRequestedAt.Valid {
+		data.DeletionScheduledAt = user.DeletionRequestedAt.Time.Add(AccountDeletionGrace).Format("02/01/2006")
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		if data.RecoveryCodesLeft, err = CountRecoveryCodes(DB, userID); err != nil {
+			log.Printf("Erreur comptage codes de secours: %v", err)
+		}
+	} else if secret, ok := session.Values[totpPendingKey].(string); ok {
+		data.TOTPSetup = &TOTPSetup{Secret: secret, URI: TOTPProvisioningURI(secret, user.Email)}
+	}
+	if flashes := session.Flashes(recoveryCodesFlash); len(flashes) > 0 {
+		data.RecoveryCodes, _ = flashes[0].([]string)
+		_ = SaveSession(w, r, session)
 	}
 
-	s.Render(w, "profile.html", data)
+	// Le jeton en clair n'est affiché qu'une fois, juste après sa création
+	if flashes := session.Flashes(tokenFlashKey); len(flashes) > 0 {
+		data.NewToken, _ = flashes[0].(string)
+		_ = SaveSession(w, r, session)
+	}
+	if flashes := session.Flashes(profileNoticeKey); len(flashes) > 0 {
+		data.Notice, _ = flashes[0].(string)
+		_ = SaveSession(w, r, session)
+	}
+
+	s.Render(w, r, "profile.html", data)
 }
 
 func getStringValue(ns sql.NullString) string {
@@ -118,21 +188,26 @@ func (s *Server) HandleArtist(w http.ResponseWriter, r *http.Request) {
 	// Récupérer l'utilisateur connecté
 	var userProfile *UserProfile
 	var isFav bool
+	var lists []ListMembership
 	if IsAuthenticated(r) {
 		session, _ := GetSession(r)
 		if userID, ok := session.Values["user_id"].(int); ok {
 			user, err := GetUserByID(DB, userID)
 			if err == nil {
 				userProfile = &UserProfile{
-					ID:          user.ID,
-					Username:    user.Username,
-					Email:       user.Email,
-					Pseudo:      getStringValue(user.Pseudo),
-					Bio:         getStringValue(user.Bio),
-					PhotoProfil: getStringValue(user.PhotoProfil),
-					Role:        user.Role,
+					ID:            user.ID,
+					Username:      user.Username,
+					Email:         user.Email,
+					Pseudo:        getStringValue(user.Pseudo),
+					Bio:           getStringValue(user.Bio),
+					PhotoProfil:   getStringValue(user.PhotoProfil),
+					Role:          user.Role,
+					EmailVerified: user.EmailVerifiedAt.Valid,
 				}
 				isFav = IsFavorite(DB, userID, id)
+				if lists, err = GetArtistLists(DB, userID, id); err != nil {
+					log.Printf("Erreur récupération listes: %v", err)
+				}
 			}
 		}
 	}
@@ -151,9 +226,12 @@ func (s *Server) HandleArtist(w http.ResponseWriter, r *http.Request) {
 		PayPalClientID:  PayPalClientID,
 		User:            userProfile,
 		IsFavorite:      isFav,
+		Lists:           lists,
 		Comments:        comments,
+
+		NeedsVerification: userProfile != nil && RequireVerifiedEmail && !userProfile.EmailVerified,
 	}
-	s.Render(w, "artist.html", data)
+	s.Render(w, r, "artist.html", data)
 }
 
 func (s *Server) HandleRefresh(w http.ResponseWriter, r *http.Request) {
@@ -165,6 +243,7 @@ func (s *Server) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Impossible d'actualiser les données", http.StatusBadGateway)
 		return
 	}
+	Audit(r, AuditEvent{Action: AuditDataRefresh, After: fmt.Sprintf("%d artistes", len(s.ListArtists()))})
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -197,14 +276,7 @@ func (s *Server) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		ArtistID int     `json:"artist_id"`
-		Location string  `json:"location"`
-		Date     string  `json:"date"`
-		Quantity int     `json:"quantity"`
-		Amount   float64 `json:"amount"`
-	}
-
+	var req CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Requête invalide", http.StatusBadRequest)
 		return
@@ -240,6 +312,22 @@ func (s *Server) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if userID, ok := SessionUserID(r); ok {
+		err := RecordOrder(DB, userID, Order{
+			PayPalOrderID: order.ID,
+			ArtistID:      art.ID,
+			ArtistName:    art.Name,
+			Location:      req.Location,
+			Date:          req.Date,
+			Quantity:      req.Quantity,
+			Amount:        req.Amount,
+			Status:        order.Status,
+		})
+		if err != nil {
+			log.Printf("Erreur enregistrement commande %s: %v", order.ID, err)
+		}
+	}
+
 	var approveURL string
 	for _, link := range order.Links {
 		if link.Rel == "approve" {
@@ -249,10 +337,10 @@ func (s *Server) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"order_id":    order.ID,
-		"status":      order.Status,
-		"approve_url": approveURL,
+	json.NewEncoder(w).Encode(CreateOrderResponse{
+		OrderID:    order.ID,
+		Status:     order.Status,
+		ApproveURL: approveURL,
 	})
 }
 
@@ -262,10 +350,7 @@ func (s *Server) HandleCaptureOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		OrderID string `json:"order_id"`
-	}
-
+	var req CaptureOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Requête invalide", http.StatusBadRequest)
 		return
@@ -282,6 +367,9 @@ func (s *Server) HandleCaptureOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Erreur lors de la capture du paiement", http.StatusInternalServerError)
 		return
 	}
+	if err := UpdateOrderStatus(DB, req.OrderID, capture.Status); err != nil {
+		log.Printf("Erreur mise à jour commande %s: %v", req.OrderID, err)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(capture)
@@ -303,6 +391,8 @@ func (s *Server) HandlePayPalSuccess(w http.ResponseWriter, r *http.Request) {
 	capture, err := CapturePayPalOrder(s.client, orderID)
 	if err != nil {
 		log.Printf("Erreur capture automatique PayPal: %v", err)
+	} else if err := UpdateOrderStatus(DB, orderID, capture.Status); err != nil {
+		log.Printf("Erreur mise à jour commande %s: %v", orderID, err)
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -341,65 +431,104 @@ func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if r.Method == http.MethodGet {
-		s.Render(w, "login.html", LoginPageData{})
+		var data LoginPageData
+		if r.URL.Query().Get("reset") == "1" {
+			data.Message = "Mot de passe modifié, vous pouvez vous connecter"
+		}
+		if r.URL.Query().Get("unlocked") == "1" {
+			data.Message = "Compte déverrouillé, vous pouvez vous connecter"
+		}
+		s.Render(w, r, "login.html", data)
 		return
 	}
 
 	if err := r.ParseForm(); err != nil {
-		s.Render(w, "login.html", LoginPageData{
+		s.Render(w, r, "login.html", LoginPageData{
 			Error: "Erreur lors du traitement du formulaire",
 		})
 		return
 	}
 
-	email := strings.TrimSpace(r.FormValue("email"))
+	email := strings.ToLower(strings.TrimSpace(r.FormValue("email")))
 	password := r.FormValue("password")
 
 	if email == "" || password == "" {
-		s.Render(w, "login.html", LoginPageData{
+		s.Render(w, r, "login.html", LoginPageData{
 			Error: "Veuillez remplir tous les champs",
 		})
 		return
 	}
 
+	var known *User
 	user, err := GetUserByEmail(DB, email)
-	if err != nil {
-		s.Render(w, "login.html", LoginPageData{
-			Error: "Email ou mot de passe incorrect",
+	if err == nil {
+		known = &user
+	}
+
+	// Délais progressifs et verrouillage avant toute vérification du mot de passe
+	ip, now := ClientIP(r), time.Now()
+	if err := CheckLoginAllowed(DB, known, email, ip, now); err != nil {
+		var throttled *LoginThrottledError
+		if errors.Is(err, ErrAccountLocked) || errors.As(err, &throttled) {
+			s.Render(w, r, "login.html", LoginPageData{Error: err.Error()})
+			return
+		}
+		log.Printf("Erreur vérification tentatives de connexion: %v", err)
+	}
+
+	if known == nil || checkPassword(user.PasswordHash, password) != nil {
+		locked, err := RecordLoginFailure(DB, known, email, ip, now)
+		if err != nil {
+			log.Printf("Erreur enregistrement échec de connexion: %v", err)
+		}
+		message := "Email ou mot de passe incorrect"
+		if locked {
+			message = ErrAccountLocked.Error()
+			Audit(r, AuditEvent{ActorName: email, Action: AuditLoginLocked, TargetType: "user", TargetID: strconv.Itoa(known.ID)})
+		}
+		s.Render(w, r, "login.html", LoginPageData{
+			Error: message,
 		})
 		return
 	}
-	if err := checkPassword(user.PasswordHash, password); err != nil {
-		s.Render(w, "login.html", LoginPageData{
-			Error: "Email ou mot de passe incorrect",
-		})
+	// Avec la 2FA, la connexion ne réussit qu'au second facteur : les codes
+	// erronés s'ajoutent aux échecs de mot de passe jusqu'au verrouillage
+	if !user.TOTPEnabledAt.Valid {
+		if err := RecordLoginSuccess(DB, email, ip, now); err != nil {
+			log.Printf("Erreur enregistrement connexion: %v", err)
+		}
+	}
+	if sanction, ok := user.ActiveSanction(now); ok {
+		s.renderSanction(w, r, sanction)
 		return
 	}
 
-	session, err := GetSession(r)
-	if err != nil {
-		s.Render(w, "login.html", LoginPageData{
-			Error: "Erreur de session, veuillez réessayer",
-		})
+	// Avec la 2FA, la session ne reçoit user_id qu'après le second facteur
+	if user.TOTPEnabledAt.Valid {
+		if err := startLogin2FA(w, r, user); err != nil {
+			s.Render(w, r, "login.html", LoginPageData{
+				Error: "Erreur de session, veuillez réessayer",
+			})
+			return
+		}
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
 		return
 	}
-	session.Values["user_id"] = user.ID
-	session.Values["email"] = user.Email
-	session.Values["username"] = user.Username
-	session.Values["role"] = user.Role
-	if err := SaveSession(w, r, session); err != nil {
-		s.Render(w, "login.html", LoginPageData{
+
+	if err := StartUserSession(w, r, user); err != nil {
+		s.Render(w, r, "login.html", LoginPageData{
 			Error: "Impossible de sauvegarder la session",
 		})
 		return
 	}
+	Audit(r, AuditEvent{Action: AuditLogin, After: "password"})
 
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
 func (s *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
-		s.Render(w, "register.html", nil)
+		s.Render(w, r, "register.html", nil)
 		return
 	}
 
@@ -427,14 +556,13 @@ func (s *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
-	session, err := GetSession(r)
-	if err == nil {
-		session.Values["user_id"] = user.ID
-		session.Values["email"] = user.Email
-		session.Values["username"] = user.Username
-		session.Values["role"] = user.Role
-		_ = SaveSession(w, r, session)
-	}
+	go func() {
+		if err := SendEmailVerification(user, user.Email); err != nil {
+			log.Printf("Erreur envoi vérification à l'utilisateur %d: %v", user.ID, err)
+		}
+	}()
+	_ = StartUserSession(w, r, user)
+	Audit(r, AuditEvent{ActorID: user.ID, ActorName: user.Username, Action: AuditRegister, TargetType: "user", TargetID: strconv.Itoa(user.ID)})
 
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
@@ -445,6 +573,14 @@ func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Se déconnecter pendant un emprunt d'identité rend la main à l'administrateur
+	if _, ok := CurrentImpersonation(r); ok {
+		s.HandleStopImpersonation(w, r)
+		return
+	}
+	if IsAuthenticated(r) {
+		Audit(r, AuditEvent{Action: AuditLogout})
+	}
 	session, err := GetSession(r)
 	if err == nil {
 		session.Values = make(map[interface{}]interface{})
@@ -556,30 +692,39 @@ func (s *Server) HandleAdminUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := GetAllUsers(DB)
+	filter := ParseUserFilter(r.URL.Query())
+	total, err := CountUsers(DB, filter)
+	if err != nil {
+		log.Printf("Erreur comptage utilisateurs: %v", err)
+		http.Error(w, "Erreur lors de la récupération des utilisateurs", http.StatusInternalServerError)
+		return
+	}
+	users, err := SearchUsers(DB, filter, adminUsersPageSize)
 	if err != nil {
 		log.Printf("Erreur récupération utilisateurs: %v", err)
 		http.Error(w, "Erreur lors de la récupération des utilisateurs", http.StatusInternalServerError)
 		return
 	}
+	locked, err := SearchUsers(DB, UserFilter{Status: UserStatusLocked, Sort: "recent", Page: 1}, 0)
+	if err != nil {
+		log.Printf("Erreur récupération comptes verrouillés: %v", err)
+	}
 
-	// Convertir les User en UserDisplay pour faciliter l'affichage dans les templates
-	usersDisplay := make([]UserDisplay, len(users))
-	for i, u := range users {
-		usersDisplay[i] = UserDisplay{
-			ID:          u.ID,
-			Username:    u.Username,
-			Email:       u.Email,
-			Pseudo:      getStringValue(u.Pseudo),
-			Bio:         getStringValue(u.Bio),
-			PhotoProfil: getStringValue(u.PhotoProfil),
-			Role:        u.Role,
-			CreatedAt:   u.CreatedAt.Format("02/01/2006"),
-		}
+	roles, err := GetRoles(DB)
+	if err != nil {
+		log.Printf("Erreur récupération rôles: %v", err)
 	}
 
 	data := AdminUsersPageData{
-		Users: usersDisplay,
+		Users:     users,
+		Locked:    locked,
+		Roles:     roles,
+		Statuses:  UserStatuses,
+		Filter:    filter,
+		Total:     total,
+		Page:      filter.Page,
+		Query:     r.URL.RawQuery,
+		ExportURL: "/admin/users/export?" + filter.Query(),
 		User: &UserProfile{
 			ID:          admin.ID,
 			Username:    admin.Username,
@@ -591,7 +736,18 @@ func (s *Server) HandleAdminUsers(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	s.Render(w, "admin-users.html", data)
+	if filter.Page > 1 {
+		data.PrevURL = filter.pageURL(filter.Page - 1)
+	}
+	if filter.Page*adminUsersPageSize < total {
+		data.NextURL = filter.pageURL(filter.Page + 1)
+	}
+	if flashes := session.Flashes(profileNoticeKey); len(flashes) > 0 {
+		data.Notice, _ = flashes[0].(string)
+		_ = SaveSession(w, r, session)
+	}
+
+	s.Render(w, r, "admin-users.html", data)
 }
 
 // HandleAdminUpdateUserRole met à jour le rôle d'un utilisateur (admin seulement)
@@ -615,23 +771,42 @@ func (s *Server) HandleAdminUpdateUserRole(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if role != "user" && role != "admin" {
+	if exists, err := RoleExists(DB, role); err != nil || !exists {
 		http.Error(w, "Rôle invalide", http.StatusBadRequest)
 		return
 	}
 
-	// Empêcher un admin de se retirer ses propres droits
+	// Empêcher un admin de modifier (et perdre) ses propres droits
 	session, _ := GetSession(r)
-	if currentUserID, ok := session.Values["user_id"].(int); ok && currentUserID == userID && role == "user" {
-		http.Error(w, "Vous ne pouvez pas retirer vos propres droits administrateur", http.StatusForbidden)
+	if currentUserID, ok := session.Values["user_id"].(int); ok && currentUserID == userID {
+		http.Error(w, "Vous ne pouvez pas modifier votre propre rôle", http.StatusForbidden)
 		return
 	}
 
+	target, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return
+	}
+	if err := CanChangeRole(CurrentUserRole(r), target.Role, role); err != nil {
+		if !roleChangeDenied(err) {
+			log.Printf("Erreur vérification changement de rôle: %v", err)
+			http.Error(w, "Erreur lors de la mise à jour du rôle", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err := UpdateUserRole(DB, userID, role); err != nil {
 		log.Printf("Erreur mise à jour rôle: %v", err)
 		http.Error(w, "Erreur lors de la mise à jour du rôle", http.StatusInternalServerError)
 		return
 	}
+	Audit(r, AuditEvent{Action: AuditRoleChange, TargetType: "user", TargetID: userIDStr, Before: target.Role, After: role})
+	// Les sessions ouvertes portent l'ancien rôle : l'utilisateur se reconnecte
+	if err := RevokeUserSessions(DB, userID); err != nil {
+		log.Printf("Erreur révocation sessions utilisateur %d: %v", userID, err)
+	}
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
@@ -662,11 +837,26 @@ func (s *Server) HandleAdminDeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	target, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return
+	}
+	if err := CanChangeRole(CurrentUserRole(r), target.Role, ""); err != nil {
+		if !roleChangeDenied(err) {
+			log.Printf("Erreur vérification suppression utilisateur: %v", err)
+			http.Error(w, "Erreur lors de la suppression de l'utilisateur", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err := DeleteUser(DB, userID); err != nil {
 		log.Printf("Erreur suppression utilisateur: %v", err)
 		http.Error(w, "Erreur lors de la suppression de l'utilisateur", http.StatusInternalServerError)
 		return
 	}
+	Audit(r, AuditEvent{Action: AuditUserDelete, TargetType: "user", TargetID: userIDStr, Before: target.Username + " <" + target.Email + ">"})
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
@@ -677,12 +867,7 @@ func (s *Server) HandleToggleFavorite(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
 		return
 	}
-	session, err := GetSession(r)
-	if err != nil {
-		http.Error(w, "Session indisponible", http.StatusUnauthorized)
-		return
-	}
-	userID, ok := session.Values["user_id"].(int)
+	userID, ok := CurrentUserID(r)
 	if !ok {
 		http.Error(w, "Non authentifié", http.StatusUnauthorized)
 		return
@@ -704,9 +889,7 @@ func (s *Server) HandleToggleFavorite(w http.ResponseWriter, r *http.Request) {
 
 	// Répondre en JSON pour les appels AJAX
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"is_favorite": isFav,
-	})
+	json.NewEncoder(w).Encode(FavoriteToggleResponse{IsFavorite: isFav})
 }
 
 // HandleAddComment ajoute un commentaire sur un artiste
@@ -715,12 +898,7 @@ func (s *Server) HandleAddComment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
 		return
 	}
-	session, err := GetSession(r)
-	if err != nil {
-		http.Error(w, "Session indisponible", http.StatusUnauthorized)
-		return
-	}
-	userID, ok := session.Values["user_id"].(int)
+	userID, ok := CurrentUserID(r)
 	if !ok {
 		http.Error(w, "Non authentifié", http.StatusUnauthorized)
 		return
@@ -754,12 +932,7 @@ func (s *Server) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
 		return
 	}
-	session, err := GetSession(r)
-	if err != nil {
-		http.Error(w, "Session indisponible", http.StatusUnauthorized)
-		return
-	}
-	userID, ok := session.Values["user_id"].(int)
+	userID, ok := CurrentUserID(r)
 	if !ok {
 		http.Error(w, "Non authentifié", http.StatusUnauthorized)
 		return
@@ -779,7 +952,21 @@ func (s *Server) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
 	artistIDStr := r.FormValue("artist_id")
 	artistID, _ := strconv.Atoi(artistIDStr)
 
-	if err := DeleteComment(DB, commentID, userID); err != nil {
+	// Les modérateurs peuvent supprimer n'importe quel commentaire
+	if HasPermission(r, PermDeleteComments) {
+		var deleted Comment
+		if deleted, err = ModerateComment(DB, commentID); err == nil && deleted.UserID != userID {
+			Audit(r, AuditEvent{
+				Action:     AuditCommentModerate,
+				TargetType: "comment",
+				TargetID:   commentIDStr,
+				Before:     fmt.Sprintf("utilisateur %d, artiste %d : %s", deleted.UserID, deleted.ArtistID, deleted.Content),
+			})
+		}
+	} else {
+		err = DeleteComment(DB, commentID, userID)
+	}
+	if err != nil {
 		log.Printf("Erreur suppression commentaire: %v", err)
 		http.Error(w, "Erreur lors de la suppression", http.StatusInternalServerError)
 		return
@@ -790,20 +977,20 @@ func (s *Server) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
 
 // HandleLegalConditions affiche la page des conditions générales de vente
 func (s *Server) HandleLegalConditions(w http.ResponseWriter, r *http.Request) {
-	s.Render(w, "legal-conditions.html", nil)
+	s.Render(w, r, "legal-conditions.html", nil)
 }
 
 // HandleLegalPrivacy affiche la page de politique de confidentialité
 func (s *Server) HandleLegalPrivacy(w http.ResponseWriter, r *http.Request) {
-	s.Render(w, "legal-privacy.html", nil)
+	s.Render(w, r, "legal-privacy.html", nil)
 }
 
 // HandleLegalCookies affiche la page de politique des cookies
 func (s *Server) HandleLegalCookies(w http.ResponseWriter, r *http.Request) {
-	s.Render(w, "legal-cookies.html", nil)
+	s.Render(w, r, "legal-cookies.html", nil)
 }
 
 // HandleLegalMentions affiche la page des mentions légales
 func (s *Server) HandleLegalMentions(w http.ResponseWriter, r *http.Request) {
-	s.Render(w, "legal-mentions.html", nil)
+	s.Render(w, r, "legal-mentions.html", nil)
 }
@@ -0,0 +1,223 @@
+package src
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// Emprunt d'identité : un administrateur voit le site comme un utilisateur
+// pour l'aider. La session devient celle de l'utilisateur et garde l'identité
+// de l'administrateur jusqu'à l'arrêt ou l'expiration de l'emprunt.
+const (
+	impersonatorIDKey      = "impersonator_id"
+	impersonatorNameKey    = "impersonator_name"
+	impersonationExpiryKey = "impersonation_expires_at" // timestamp Unix
+)
+
+var ErrImpersonateStaff = errors.New("impossible d'emprunter l'identité d'un membre de l'équipe")
+
+// Actions réservées au titulaire du compte, refusées pendant un emprunt
+var impersonationBlockedPaths = []string{
+	"/api/paypal/",
+	"/paypal/",
+	"/profile/password",
+	"/profile/email",
+	"/profile/2fa/",
+	"/profile/delete",
+	"/profile/export",
+	"/profile/tokens/",
+	"/profile/identities/",
+	"/profile/sessions/",
+	"/auth/oidc/",
+}
+
+// Impersonation décrit un emprunt d'identité en cours, affiché par le bandeau
+type Impersonation struct {
+	AdminID   int
+	AdminName string
+	UserID    int
+	Username  string
+	ExpiresAt time.Time
+}
+
+func (i Impersonation) Expired() bool {
+	return !time.Now().Before(i.ExpiresAt)
+}
+
+func sessionImpersonation(session *sessions.Session) (Impersonation, bool) {
+	adminID, ok := session.Values[impersonatorIDKey].(int)
+	if !ok {
+		return Impersonation{}, false
+	}
+	imp := Impersonation{AdminID: adminID}
+	imp.AdminName, _ = session.Values[impersonatorNameKey].(string)
+	imp.UserID, _ = session.Values["user_id"].(int)
+	imp.Username, _ = session.Values["username"].(string)
+	expiresAt, _ := session.Values[impersonationExpiryKey].(int64)
+	imp.ExpiresAt = time.Unix(expiresAt, 0)
+	return imp, true
+}
+
+// CurrentImpersonation renvoie l'emprunt d'identité de la session, s'il y en a un
+func CurrentImpersonation(r *http.Request) (Impersonation, bool) {
+	if _, ok := CurrentAPIToken(r); ok {
+		return Impersonation{}, false
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		return Impersonation{}, false
+	}
+	return sessionImpersonation(session)
+}
+
+func impersonationBlocked(path string) bool {
+	for _, prefix := range impersonationBlockedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImpersonationGuard met fin aux emprunts expirés et refuse les paiements et
+// les modifications d'identifiants tant qu'un emprunt est en cours
+func (s *Server) ImpersonationGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, StaticPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		imp, ok := CurrentImpersonation(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if imp.Expired() {
+			if err := stopImpersonation(w, r, imp, "expiration"); err != nil {
+				log.Printf("Erreur fin d'emprunt d'identité: %v", err)
+			}
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+				return
+			}
+			s.impersonationError(w, r, "L'emprunt d'identité a expiré, vous avez retrouvé votre compte.")
+			return
+		}
+		if impersonationBlocked(r.URL.Path) {
+			s.impersonationError(w, r, "Les paiements et les modifications du compte sont désactivés pendant un emprunt d'identité.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) impersonationError(w http.ResponseWriter, r *http.Request, message string) {
+	// fetch() et clients JSON : erreur JSON ; formulaires : page d'erreur
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		WriteAPIError(w, http.StatusForbidden, "impersonation", message)
+		return
+	}
+	s.RenderError(w, r, http.StatusForbidden, "Action impossible", message)
+}
+
+// startImpersonation ouvre une session au nom de target en y gardant
+// l'identité de l'administrateur
+func startImpersonation(w http.ResponseWriter, r *http.Request, admin, target User) error {
+	session, err := GetSession(r)
+	if err != nil {
+		return err
+	}
+	session.Values[impersonatorIDKey] = admin.ID
+	session.Values[impersonatorNameKey] = admin.Username
+	session.Values[impersonationExpiryKey] = time.Now().Add(ImpersonationTTL).Unix()
+	return StartUserSession(w, r, target)
+}
+
+// stopImpersonation rend la session à l'administrateur ; s'il n'existe plus,
+// la session est simplement fermée
+func stopImpersonation(w http.ResponseWriter, r *http.Request, imp Impersonation, reason string) error {
+	session, err := GetSession(r)
+	if err != nil {
+		return err
+	}
+	Audit(r, AuditEvent{ActorID: imp.AdminID, ActorName: imp.AdminName, Action: AuditImpersonateOff, TargetType: "user", TargetID: strconv.Itoa(imp.UserID), After: reason})
+	delete(session.Values, impersonatorIDKey)
+	delete(session.Values, impersonatorNameKey)
+	delete(session.Values, impersonationExpiryKey)
+
+	admin, err := GetUserByID(DB, imp.AdminID)
+	if err != nil {
+		session.Values = make(map[interface{}]interface{})
+		session.Options.MaxAge = -1
+		_ = SaveSession(w, r, session)
+		return err
+	}
+	return StartUserSession(w, r, admin)
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// HandleAdminImpersonate démarre un emprunt d'identité depuis /admin/users
+func (s *Server) HandleAdminImpersonate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "ID utilisateur invalide", http.StatusBadRequest)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	if userID == admin.ID {
+		http.Error(w, "Vous ne pouvez pas emprunter votre propre identité", http.StatusBadRequest)
+		return
+	}
+	target, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return
+	}
+	// Emprunter un compte de l'équipe donnerait accès à d'autres permissions
+	if IsStaffRole(target.Role) {
+		http.Error(w, ErrImpersonateStaff.Error(), http.StatusForbidden)
+		return
+	}
+
+	if err := startImpersonation(w, r, admin, target); err != nil {
+		log.Printf("Erreur emprunt d'identité de l'utilisateur %d: %v", userID, err)
+		http.Error(w, "Impossible de démarrer l'emprunt d'identité", http.StatusInternalServerError)
+		return
+	}
+	Audit(r, AuditEvent{ActorID: admin.ID, ActorName: admin.Username, Action: AuditImpersonateOn, TargetType: "user", TargetID: strconv.Itoa(userID), After: target.Username})
+	log.Printf("Administrateur %d : emprunt de l'identité de l'utilisateur %d", admin.ID, userID)
+	http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+// HandleStopImpersonation termine l'emprunt et restaure la session de l'administrateur
+func (s *Server) HandleStopImpersonation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	imp, ok := CurrentImpersonation(r)
+	if !ok {
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+		return
+	}
+	if err := stopImpersonation(w, r, imp, ""); err != nil {
+		log.Printf("Erreur fin d'emprunt d'identité: %v", err)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
@@ -0,0 +1,604 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Listes nommées d'artistes (« Festivals d'été », « Vus en concert »), en
+// plus des favoris. Une liste publique est visible par tous via son URL de
+// partage ; une liste privée uniquement par son propriétaire.
+
+var (
+	ErrListNotFound     = errors.New("liste introuvable")
+	ErrListNameRequired = errors.New("le nom de la liste est requis")
+	ErrTooManyLists     = errors.New("nombre maximal de listes atteint")
+)
+
+type ArtistList struct {
+	ID        int
+	UserID    int
+	Name      string
+	Token     string // dernier segment de l'URL de partage
+	Public    bool
+	Position  int
+	ItemCount int
+	CreatedAt time.Time
+	UpdatedAt sql.NullTime
+}
+
+// SharePath renvoie le chemin de la page publique de la liste
+func (l ArtistList) SharePath() string {
+	return "/lists/share/" + l.Token
+}
+
+func (l ArtistList) ShareURL() string {
+	return BaseURL + l.SharePath()
+}
+
+type ListItem struct {
+	ArtistID  int
+	Position  int
+	CreatedAt time.Time
+}
+
+// ExportedList est le format JSON d'une liste, pour l'export d'une liste et
+// l'export RGPD
+type ExportedList struct {
+	Name      string             `json:"name"`
+	Public    bool               `json:"public"`
+	URL       string             `json:"url"`
+	CreatedAt time.Time          `json:"created_at"`
+	Artists   []ExportedListItem `json:"artists"`
+}
+
+type ExportedListItem struct {
+	ArtistID   int       `json:"artist_id"`
+	ArtistName string    `json:"artist_name,omitempty"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+// normalizeListName nettoie et valide le nom saisi
+func normalizeListName(raw string) (string, error) {
+	name := strings.TrimSpace(raw)
+	if name == "" {
+		return "", ErrListNameRequired
+	}
+	if utf8.RuneCountInString(name) > ListNameMaxLength {
+		return "", fmt.Errorf("le nom de la liste ne peut pas dépasser %d caractères", ListNameMaxLength)
+	}
+	return name, nil
+}
+
+// parseOrder lit un ordre « 3,1,2 » envoyé après un glisser-déposer
+func parseOrder(raw string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, errors.New("ordre invalide")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+const listColumns = `l.id, l.user_id, l.name, l.share_token, l.is_public, l.position, l.created_at, l.updated_at,
+(SELECT COUNT(*) FROM list_items i WHERE i.list_id = l.id)`
+
+func scanList(row interface{ Scan(...interface{}) error }) (ArtistList, error) {
+	var l ArtistList
+	err := row.Scan(&l.ID, &l.UserID, &l.Name, &l.Token, &l.Public, &l.Position, &l.CreatedAt, &l.UpdatedAt, &l.ItemCount)
+	return l, err
+}
+
+// GetUserLists renvoie les listes de l'utilisateur dans l'ordre choisi
+func GetUserLists(db *sql.DB, userID int) ([]ArtistList, error) {
+	rows, err := db.Query("SELECT "+listColumns+" FROM lists l WHERE l.user_id = ? ORDER BY l.position, l.id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture listes: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []ArtistList
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan liste: %w", err)
+		}
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
+
+// GetList renvoie une liste appartenant à l'utilisateur
+func GetList(db *sql.DB, userID, listID int) (ArtistList, error) {
+	l, err := scanList(db.QueryRow("SELECT "+listColumns+" FROM lists l WHERE l.id = ? AND l.user_id = ?", listID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrListNotFound
+	}
+	if err != nil {
+		return l, fmt.Errorf("lecture liste: %w", err)
+	}
+	return l, nil
+}
+
+func GetListByToken(db *sql.DB, token string) (ArtistList, error) {
+	l, err := scanList(db.QueryRow("SELECT "+listColumns+" FROM lists l WHERE l.share_token = ?", token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrListNotFound
+	}
+	if err != nil {
+		return l, fmt.Errorf("lecture liste: %w", err)
+	}
+	return l, nil
+}
+
+func GetListItems(db *sql.DB, listID int) ([]ListItem, error) {
+	rows, err := db.Query("SELECT artist_id, position, created_at FROM list_items WHERE list_id = ? ORDER BY position, created_at", listID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture artistes de la liste: %w", err)
+	}
+	defer rows.Close()
+
+	var items []ListItem
+	for rows.Next() {
+		var it ListItem
+		if err := rows.Scan(&it.ArtistID, &it.Position, &it.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan artiste de la liste: %w", err)
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// GetArtistLists renvoie les listes de l'utilisateur en indiquant celles qui
+// contiennent déjà l'artiste
+func GetArtistLists(db *sql.DB, userID, artistID int) ([]ListMembership, error) {
+	const query = `SELECT l.id, l.name, i.artist_id IS NOT NULL FROM lists l
+LEFT JOIN list_items i ON i.list_id = l.id AND i.artist_id = ?
+WHERE l.user_id = ? ORDER BY l.position, l.id`
+	rows, err := db.Query(query, artistID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture listes de l'artiste: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []ListMembership
+	for rows.Next() {
+		var m ListMembership
+		if err := rows.Scan(&m.ID, &m.Name, &m.Contains); err != nil {
+			return nil, fmt.Errorf("scan liste: %w", err)
+		}
+		lists = append(lists, m)
+	}
+	return lists, rows.Err()
+}
+
+// CreateList crée une liste privée placée après les autres
+func CreateList(db *sql.DB, userID int, name string) (ArtistList, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM lists WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return ArtistList{}, fmt.Errorf("comptage listes: %w", err)
+	}
+	if count >= ListMaxPerUser {
+		return ArtistList{}, ErrTooManyLists
+	}
+	token, err := generateSecret()
+	if err != nil {
+		return ArtistList{}, err
+	}
+
+	const insert = `INSERT INTO lists (user_id, name, share_token, position)
+SELECT ?, ?, ?, COALESCE(MAX(position), 0) + 1 FROM lists WHERE user_id = ?`
+	res, err := db.Exec(insert, userID, name, token, userID)
+	if err != nil {
+		return ArtistList{}, fmt.Errorf("création liste: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return ArtistList{}, fmt.Errorf("création liste: %w", err)
+	}
+	return ArtistList{ID: int(id), UserID: userID, Name: name, Token: token}, nil
+}
+
+// UpdateList renomme la liste et change sa visibilité
+func UpdateList(db *sql.DB, listID int, name string, public bool) error {
+	if _, err := db.Exec("UPDATE lists SET name = ?, is_public = ? WHERE id = ?", name, public, listID); err != nil {
+		return fmt.Errorf("modification liste: %w", err)
+	}
+	return nil
+}
+
+// DeleteList supprime la liste ; ses artistes partent en cascade
+func DeleteList(db *sql.DB, listID int) error {
+	if _, err := db.Exec("DELETE FROM lists WHERE id = ?", listID); err != nil {
+		return fmt.Errorf("suppression liste: %w", err)
+	}
+	return nil
+}
+
+// ReorderLists enregistre l'ordre des listes ; les IDs qui n'appartiennent pas
+// à l'utilisateur sont ignorés
+func ReorderLists(db *sql.DB, userID int, listIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("ordre des listes: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, listID := range listIDs {
+		if _, err := tx.Exec("UPDATE lists SET position = ? WHERE id = ? AND user_id = ?", i+1, listID, userID); err != nil {
+			return fmt.Errorf("ordre des listes: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ToggleListItem ajoute l'artiste en fin de liste ou l'en retire ; renvoie
+// true s'il est désormais dans la liste
+func ToggleListItem(db *sql.DB, listID, artistID int) (bool, error) {
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM list_items WHERE list_id = ? AND artist_id = ?", listID, artistID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("vérification liste: %w", err)
+	}
+	if exists > 0 {
+		if _, err := db.Exec("DELETE FROM list_items WHERE list_id = ? AND artist_id = ?", listID, artistID); err != nil {
+			return false, fmt.Errorf("retrait de la liste: %w", err)
+		}
+		return false, nil
+	}
+	const insert = `INSERT INTO list_items (list_id, artist_id, position)
+SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM list_items WHERE list_id = ?`
+	if _, err := db.Exec(insert, listID, artistID, listID); err != nil {
+		return false, fmt.Errorf("ajout à la liste: %w", err)
+	}
+	return true, nil
+}
+
+func ReorderListItems(db *sql.DB, listID int, artistIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("ordre de la liste: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, artistID := range artistIDs {
+		if _, err := tx.Exec("UPDATE list_items SET position = ? WHERE list_id = ? AND artist_id = ?", i+1, listID, artistID); err != nil {
+			return fmt.Errorf("ordre de la liste: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ExportList construit la version JSON de la liste ; artistName sert à nommer
+// les artistes
+func ExportList(db *sql.DB, l ArtistList, artistName func(int) string) (ExportedList, error) {
+	items, err := GetListItems(db, l.ID)
+	if err != nil {
+		return ExportedList{}, err
+	}
+	export := ExportedList{
+		Name:      l.Name,
+		Public:    l.Public,
+		URL:       l.ShareURL(),
+		CreatedAt: l.CreatedAt,
+		Artists:   []ExportedListItem{},
+	}
+	for _, it := range items {
+		export.Artists = append(export.Artists, ExportedListItem{ArtistID: it.ArtistID, ArtistName: artistName(it.ArtistID), AddedAt: it.CreatedAt})
+	}
+	return export, nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func listNotice(w http.ResponseWriter, r *http.Request, target, notice string) {
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// ownedList charge la liste list_id du formulaire si elle appartient à
+// l'utilisateur ; sinon répond en erreur
+func ownedList(w http.ResponseWriter, r *http.Request, userID int) (ArtistList, bool) {
+	listID, err := strconv.Atoi(r.FormValue("list_id"))
+	if err != nil || listID <= 0 {
+		http.Error(w, "ID liste invalide", http.StatusBadRequest)
+		return ArtistList{}, false
+	}
+	l, err := GetList(DB, userID, listID)
+	if errors.Is(err, ErrListNotFound) {
+		http.Error(w, "Liste introuvable", http.StatusNotFound)
+		return l, false
+	}
+	if err != nil {
+		log.Printf("Erreur lecture liste %d: %v", listID, err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return l, false
+	}
+	return l, true
+}
+
+func (s *Server) HandleLists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	lists, err := GetUserLists(DB, user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération listes: %v", err)
+		http.Error(w, "Erreur lors de la récupération des listes", http.StatusInternalServerError)
+		return
+	}
+	s.Render(w, r, "lists.html", ListsPageData{Lists: lists, User: adminProfile(user), Notice: takeNotice(w, r)})
+}
+
+func (s *Server) HandleCreateList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	name, err := normalizeListName(r.FormValue("name"))
+	if err != nil {
+		listNotice(w, r, "/lists", err.Error())
+		return
+	}
+	l, err := CreateList(DB, userID, name)
+	if errors.Is(err, ErrTooManyLists) {
+		listNotice(w, r, "/lists", fmt.Sprintf("Vous ne pouvez pas créer plus de %d listes", ListMaxPerUser))
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur création liste: %v", err)
+		listNotice(w, r, "/lists", "Erreur lors de la création de la liste")
+		return
+	}
+	listNotice(w, r, "/lists#list-"+strconv.Itoa(l.ID), fmt.Sprintf("Liste « %s » créée", l.Name))
+}
+
+// HandleUpdateList renomme une liste et la rend publique ou privée
+func (s *Server) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	l, ok := ownedList(w, r, userID)
+	if !ok {
+		return
+	}
+	target := "/lists#list-" + strconv.Itoa(l.ID)
+	name, err := normalizeListName(r.FormValue("name"))
+	if err != nil {
+		listNotice(w, r, target, err.Error())
+		return
+	}
+	public := r.FormValue("public") == "1"
+	if err := UpdateList(DB, l.ID, name, public); err != nil {
+		log.Printf("Erreur modification liste %d: %v", l.ID, err)
+		listNotice(w, r, target, "Erreur lors de l'enregistrement de la liste")
+		return
+	}
+	listNotice(w, r, target, fmt.Sprintf("Liste « %s » enregistrée", name))
+}
+
+func (s *Server) HandleDeleteList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	l, ok := ownedList(w, r, userID)
+	if !ok {
+		return
+	}
+	if err := DeleteList(DB, l.ID); err != nil {
+		log.Printf("Erreur suppression liste %d: %v", l.ID, err)
+		listNotice(w, r, "/lists", "Erreur lors de la suppression de la liste")
+		return
+	}
+	listNotice(w, r, "/lists", fmt.Sprintf("Liste « %s » supprimée", l.Name))
+}
+
+// HandleReorderLists reçoit l'ordre complet des listes après un glisser-déposer
+func (s *Server) HandleReorderLists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	ids, err := parseOrder(r.FormValue("order"))
+	if err != nil {
+		http.Error(w, "Ordre invalide", http.StatusBadRequest)
+		return
+	}
+	if err := ReorderLists(DB, userID, ids); err != nil {
+		log.Printf("Erreur ordre listes: %v", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleToggleListItem ajoute ou retire un artiste d'une liste
+func (s *Server) HandleToggleListItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	artistID, err := strconv.Atoi(r.FormValue("artist_id"))
+	if err != nil || artistID <= 0 {
+		http.Error(w, "ID artiste invalide", http.StatusBadRequest)
+		return
+	}
+	if _, found := s.FindArtist(artistID); !found {
+		http.Error(w, "Artiste introuvable", http.StatusNotFound)
+		return
+	}
+	l, ok := ownedList(w, r, userID)
+	if !ok {
+		return
+	}
+
+	inList, err := ToggleListItem(DB, l.ID, artistID)
+	if err != nil {
+		log.Printf("Erreur toggle liste: %v", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListToggleResponse{InList: inList})
+}
+
+func (s *Server) HandleReorderListItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	l, ok := ownedList(w, r, userID)
+	if !ok {
+		return
+	}
+	ids, err := parseOrder(r.FormValue("order"))
+	if err != nil {
+		http.Error(w, "Ordre invalide", http.StatusBadRequest)
+		return
+	}
+	if err := ReorderListItems(DB, l.ID, ids); err != nil {
+		log.Printf("Erreur ordre liste %d: %v", l.ID, err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sharedList charge la liste de l'URL de partage ; une liste privée n'est
+// visible que par son propriétaire
+func (s *Server) sharedList(w http.ResponseWriter, r *http.Request) (ArtistList, bool) {
+	l, err := GetListByToken(DB, r.PathValue("token"))
+	if err != nil && !errors.Is(err, ErrListNotFound) {
+		log.Printf("Erreur lecture liste partagée: %v", err)
+		s.RenderError(w, r, http.StatusInternalServerError, "Erreur serveur", "La liste n'a pas pu être chargée.")
+		return l, false
+	}
+	userID, _ := CurrentUserID(r)
+	if err != nil || (!l.Public && l.UserID != userID) {
+		s.RenderError(w, r, http.StatusNotFound, "Liste introuvable", "Cette liste n'existe pas ou n'est pas publique.")
+		return l, false
+	}
+	return l, true
+}
+
+// HandleSharedList affiche une liste depuis son URL de partage, sans connexion
+// requise pour une liste publique
+func (s *Server) HandleSharedList(w http.ResponseWriter, r *http.Request) {
+	l, ok := s.sharedList(w, r)
+	if !ok {
+		return
+	}
+	items, err := GetListItems(DB, l.ID)
+	if err != nil {
+		log.Printf("Erreur lecture liste %d: %v", l.ID, err)
+		s.RenderError(w, r, http.StatusInternalServerError, "Erreur serveur", "La liste n'a pas pu être chargée.")
+		return
+	}
+
+	data := SharedListPageData{List: l}
+	for _, it := range items {
+		if art, found := s.FindArtist(it.ArtistID); found {
+			data.Artists = append(data.Artists, art)
+		}
+	}
+	if owner, err := GetUserByID(DB, l.UserID); err == nil {
+		data.Owner = owner.Username
+		if pseudo := getStringValue(owner.Pseudo); pseudo != "" {
+			data.Owner = pseudo
+		}
+	}
+	if userID, ok := CurrentUserID(r); ok {
+		if user, err := GetUserByID(DB, userID); err == nil {
+			data.User = adminProfile(user)
+			data.IsOwner = user.ID == l.UserID
+			data.Notice = takeNotice(w, r)
+		}
+	}
+	s.Render(w, r, "list.html", data)
+}
+
+// HandleExportList télécharge la liste au format JSON
+func (s *Server) HandleExportList(w http.ResponseWriter, r *http.Request) {
+	l, ok := s.sharedList(w, r)
+	if !ok {
+		return
+	}
+	export, err := ExportList(DB, l, func(id int) string {
+		art, _ := s.FindArtist(id)
+		return art.Name
+	})
+	if err != nil {
+		log.Printf("Erreur export liste %d: %v", l.ID, err)
+		http.Error(w, "Erreur lors de l'export de la liste", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("liste-%d-%s.json", l.ID, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		log.Printf("Erreur export liste %d: %v", l.ID, err)
+	}
+}
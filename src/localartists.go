@@ -0,0 +1,410 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Artistes créés par les administrateurs, absents de l'API Groupie. Ils sont
+// stockés dans local_artists et servis comme les autres par ListArtists.
+
+var ErrLocalArtistUnknown = errors.New("artiste local introuvable")
+
+// artistImageTypes sont les formats acceptés pour l'image d'un artiste local
+var artistImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// IsLocalArtist indique un ID de la plage réservée aux artistes locaux
+func IsLocalArtist(id int) bool {
+	return id >= LocalArtistIDStart
+}
+
+// localArtist complète les localisations et dates à partir des relations,
+// comme FetchArtistsData le fait pour l'API
+func localArtist(a Artist) Artist {
+	a.Locations = make([]string, 0, len(a.DatesLocations))
+	for loc := range a.DatesLocations {
+		a.Locations = append(a.Locations, loc)
+	}
+	sort.Strings(a.Locations)
+
+	a.ConcertDates = nil
+	seen := make(map[string]bool)
+	for _, loc := range a.Locations {
+		for _, date := range a.DatesLocations[loc] {
+			if !seen[date] {
+				seen[date] = true
+				a.ConcertDates = append(a.ConcertDates, date)
+			}
+		}
+	}
+	return a
+}
+
+// ParseRelations lit les concerts saisis à raison d'une ligne par lieu :
+// « paris-france : 01-01-2020, 02-01-2020 »
+func ParseRelations(text string) (map[string][]string, error) {
+	relations := make(map[string][]string)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		loc, dates, ok := strings.Cut(line, ":")
+		loc = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(loc)), " ", "_")
+		if !ok || loc == "" {
+			return nil, fmt.Errorf("ligne %d : format attendu « lieu : JJ-MM-AAAA, … »", i+1)
+		}
+		for _, date := range strings.Split(dates, ",") {
+			date = strings.TrimSpace(date)
+			if date == "" {
+				continue
+			}
+			if _, err := time.Parse("02-01-2006", date); err != nil {
+				return nil, fmt.Errorf("ligne %d : date « %s » invalide (JJ-MM-AAAA)", i+1, date)
+			}
+			relations[loc] = append(relations[loc], date)
+		}
+		if len(relations[loc]) == 0 {
+			return nil, fmt.Errorf("ligne %d : aucune date pour « %s »", i+1, loc)
+		}
+	}
+	return relations, nil
+}
+
+// FormatRelations est l'inverse de ParseRelations, pour pré-remplir le formulaire
+func FormatRelations(relations map[string][]string) string {
+	var lines []string
+	for _, ld := range BuildLocationDates(relations) {
+		lines = append(lines, ld.Raw+" : "+strings.Join(ld.Dates, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+func GetLocalArtists(db *sql.DB) ([]Artist, error) {
+	const query = `SELECT id, name, image, members, creation_date, first_album, relations FROM local_artists ORDER BY id`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("lecture artistes locaux: %w", err)
+	}
+	defer rows.Close()
+
+	var artists []Artist
+	for rows.Next() {
+		var a Artist
+		var members, relations string
+		if err := rows.Scan(&a.ID, &a.Name, &a.Image, &members, &a.CreationDate, &a.FirstAlbum, &relations); err != nil {
+			return nil, fmt.Errorf("scan artiste local: %w", err)
+		}
+		if err := json.Unmarshal([]byte(members), &a.Members); err != nil {
+			return nil, fmt.Errorf("membres de l'artiste %d: %w", a.ID, err)
+		}
+		if err := json.Unmarshal([]byte(relations), &a.DatesLocations); err != nil {
+			return nil, fmt.Errorf("concerts de l'artiste %d: %w", a.ID, err)
+		}
+		artists = append(artists, localArtist(a))
+	}
+	return artists, rows.Err()
+}
+
+func localArtistJSON(a Artist) (members, relations []byte, err error) {
+	if a.Members == nil {
+		a.Members = []string{}
+	}
+	if a.DatesLocations == nil {
+		a.DatesLocations = map[string][]string{}
+	}
+	if members, err = json.Marshal(a.Members); err != nil {
+		return nil, nil, err
+	}
+	relations, err = json.Marshal(a.DatesLocations)
+	return members, relations, err
+}
+
+// CreateLocalArtist enregistre l'artiste et renvoie son ID, pris dans la plage réservée
+func CreateLocalArtist(db *sql.DB, a Artist, userID int) (int, error) {
+	members, relations, err := localArtistJSON(a)
+	if err != nil {
+		return 0, fmt.Errorf("création artiste local: %w", err)
+	}
+	const query = `INSERT INTO local_artists (name, image, members, creation_date, first_album, relations, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	res, err := db.Exec(query, a.Name, a.Image, members, a.CreationDate, a.FirstAlbum, relations, nullableUserID(userID))
+	if err != nil {
+		return 0, fmt.Errorf("création artiste local: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("création artiste local: %w", err)
+	}
+	return int(id), nil
+}
+
+func UpdateLocalArtist(db *sql.DB, a Artist) error {
+	members, relations, err := localArtistJSON(a)
+	if err != nil {
+		return fmt.Errorf("mise à jour artiste local: %w", err)
+	}
+	const query = `UPDATE local_artists SET name = ?, image = ?, members = ?, creation_date = ?, first_album = ?, relations = ? WHERE id = ?`
+	res, err := db.Exec(query, a.Name, a.Image, members, a.CreationDate, a.FirstAlbum, relations, a.ID)
+	if err != nil {
+		return fmt.Errorf("mise à jour artiste local: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// aucune ligne modifiée : artiste inconnu ou valeurs identiques
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM local_artists WHERE id = ?)", a.ID).Scan(&exists); err == nil && !exists {
+			return ErrLocalArtistUnknown
+		}
+	}
+	return nil
+}
+
+// DeleteLocalArtist supprime l'artiste avec ses favoris et commentaires ;
+// les commandes gardent le nom de l'artiste
+func DeleteLocalArtist(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("suppression artiste local: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM local_artists WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("suppression artiste local: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrLocalArtistUnknown
+	}
+	for _, table := range []string{"favorites", "list_items", "comments", "artist_overrides"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE artist_id = ?", id); err != nil {
+			return fmt.Errorf("suppression %s de l'artiste local: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// saveArtistImage enregistre l'image envoyée dans static/uploads ; renvoie ""
+// si aucun fichier n'a été choisi
+func saveArtistImage(r *http.Request) (string, error) {
+	file, _, err := r.FormFile("image_file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.New("image illisible")
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	ext, ok := artistImageTypes[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", errors.New("l'image doit être au format JPEG, PNG, GIF ou WebP")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("lecture image: %w", err)
+	}
+
+	uploadDir := "static/uploads"
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return "", fmt.Errorf("création dossier uploads: %w", err)
+	}
+	filename := fmt.Sprintf("artist_%d%s", time.Now().UnixNano(), ext)
+	dst, err := os.Create(filepath.Join(uploadDir, filename))
+	if err != nil {
+		return "", fmt.Errorf("création fichier: %w", err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, file); err != nil {
+		return "", fmt.Errorf("copie fichier: %w", err)
+	}
+	return "/static/uploads/" + filename, nil
+}
+
+// removeArtistImage supprime une image envoyée pour un artiste local
+func removeArtistImage(image string) {
+	if !strings.HasPrefix(image, "/static/uploads/artist_") {
+		return
+	}
+	path := filepath.Join("static", "uploads", filepath.Base(image))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Erreur suppression image artiste %s: %v", path, err)
+	}
+}
+
+func (s *Server) findLocalArtist(id int) (Artist, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, art := range s.local {
+		if art.ID == id {
+			return art, true
+		}
+	}
+	return Artist{}, false
+}
+
+// HandleAdminLocalArtist affiche le formulaire d'un artiste local, vide sans id
+func (s *Server) HandleAdminLocalArtist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	data := AdminLocalArtistPageData{IsNew: true, User: adminProfile(admin), Notice: takeNotice(w, r)}
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		id, _ := strconv.Atoi(idStr)
+		art, found := s.findLocalArtist(id)
+		if !found {
+			s.RenderError(w, r, http.StatusNotFound, "Artiste introuvable", "Cet artiste local n'existe pas.")
+			return
+		}
+		data.Artist, data.IsNew = art, false
+		data.Members = strings.Join(art.Members, "\n")
+		data.Relations = FormatRelations(art.DatesLocations)
+	}
+	s.Render(w, r, "admin-local-artist.html", data)
+}
+
+func (s *Server) HandleAdminLocalArtistSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseMultipartForm(10 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		http.Error(w, "Erreur parsing formulaire", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	var current Artist
+	if id != 0 {
+		var found bool
+		if current, found = s.findLocalArtist(id); !found {
+			adminArtistNotice(w, r, 0, ErrLocalArtistUnknown.Error())
+			return
+		}
+	}
+
+	// en cas d'erreur, le formulaire est réaffiché avec la saisie
+	data := AdminLocalArtistPageData{
+		IsNew:     id == 0,
+		Members:   r.FormValue("members"),
+		Relations: r.FormValue("relations"),
+		User:      adminProfile(admin),
+	}
+	var image string
+	submitted := Artist{
+		ID:         id,
+		Name:       r.FormValue("name"),
+		Image:      r.FormValue("image"),
+		FirstAlbum: r.FormValue("first_album"),
+	}
+	submitted.CreationDate, _ = strconv.Atoi(r.FormValue("creation_date"))
+	fail := func(err error) {
+		removeArtistImage(image)
+		if submitted.Image == "" {
+			submitted.Image = current.Image
+		}
+		data.Artist = submitted
+		data.Notice = err.Error()
+		s.Render(w, r, "admin-local-artist.html", data)
+	}
+
+	image, err := saveArtistImage(r)
+	if err != nil {
+		fail(err)
+		return
+	}
+	form := r.Form
+	if image != "" {
+		form.Set("image", image)
+	} else if form.Get("image") == "" {
+		form.Set("image", current.Image)
+	}
+	art, err := parseArtistForm(r, current)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if art.DatesLocations, err = ParseRelations(r.FormValue("relations")); err != nil {
+		fail(err)
+		return
+	}
+
+	if id == 0 {
+		if id, err = CreateLocalArtist(DB, art, admin.ID); err == nil {
+			Audit(r, AuditEvent{Action: AuditArtistCreate, TargetType: "artist", TargetID: strconv.Itoa(id), After: art.Name})
+		}
+	} else {
+		art.ID = id
+		if err = UpdateLocalArtist(DB, art); err == nil {
+			Audit(r, AuditEvent{Action: AuditArtistUpdate, TargetType: "artist", TargetID: strconv.Itoa(id), Before: current.Name, After: art.Name})
+		}
+	}
+	if err != nil {
+		log.Printf("Erreur enregistrement artiste local: %v", err)
+		fail(errors.New("erreur lors de l'enregistrement de l'artiste"))
+		return
+	}
+	if current.Image != art.Image {
+		removeArtistImage(current.Image)
+	}
+	if err := s.RebuildArtists(); err != nil {
+		log.Printf("Erreur fusion des artistes: %v", err)
+	}
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash("Artiste « "+art.Name+" » enregistré", profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/admin/artists/local?id="+strconv.Itoa(id), http.StatusSeeOther)
+}
+
+func (s *Server) HandleAdminLocalArtistDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	art, found := s.findLocalArtist(id)
+	if !found {
+		adminArtistNotice(w, r, 0, ErrLocalArtistUnknown.Error())
+		return
+	}
+	if err := DeleteLocalArtist(DB, id); err != nil {
+		log.Printf("Erreur suppression artiste local %d: %v", id, err)
+		adminArtistNotice(w, r, 0, "Erreur lors de la suppression de l'artiste")
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditArtistDelete, TargetType: "artist", TargetID: strconv.Itoa(id), Before: art.Name})
+	removeArtistImage(art.Image)
+	if err := s.RebuildArtists(); err != nil {
+		log.Printf("Erreur fusion des artistes: %v", err)
+	}
+	adminArtistNotice(w, r, 0, "Artiste « "+art.Name+" » supprimé")
+}
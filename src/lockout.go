@@ -0,0 +1,250 @@
+package src
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAccountLocked      = errors.New("compte temporairement verrouillé après trop de tentatives. Consultez vos emails pour le déverrouiller ou réessayez plus tard")
+	ErrInvalidUnlockToken = errors.New("lien de déverrouillage invalide ou expiré")
+)
+
+// LoginThrottledError impose d'attendre avant une nouvelle tentative
+type LoginThrottledError struct {
+	Wait time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Wait >= time.Minute {
+		return fmt.Sprintf("Trop de tentatives, réessayez dans %d minutes", int(e.Wait.Round(time.Minute).Minutes()))
+	}
+	return fmt.Sprintf("Trop de tentatives, réessayez dans %d secondes", int(e.Wait.Round(time.Second).Seconds()))
+}
+
+// loginDelay renvoie l'attente imposée après failures échecs consécutifs :
+// nulle jusqu'à LoginDelayAfter, puis doublée à chaque échec
+func loginDelay(failures int) time.Duration {
+	if failures < LoginDelayAfter {
+		return 0
+	}
+	shift := failures - LoginDelayAfter
+	if shift > 10 {
+		return LoginMaxDelay
+	}
+	delay := time.Second << shift
+	if delay > LoginMaxDelay {
+		return LoginMaxDelay
+	}
+	return delay
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// loginFailures compte les échecs récents non effacés pour cet email
+func loginFailures(db *sql.DB, email string, now time.Time) (int, sql.NullTime, error) {
+	var count int
+	var last sql.NullTime
+	const query = `SELECT COUNT(*), MAX(created_at) FROM login_attempts WHERE email = ? AND success = FALSE AND cleared = FALSE AND created_at > ?`
+	if err := db.QueryRow(query, email, now.Add(-LoginFailureWindow)).Scan(&count, &last); err != nil {
+		return 0, sql.NullTime{}, fmt.Errorf("lecture tentatives: %w", err)
+	}
+	return count, last, nil
+}
+
+func ipFailures(db *sql.DB, ip string, now time.Time) (failures, accounts int, err error) {
+	const query = `SELECT COUNT(*), COUNT(DISTINCT email) FROM login_attempts WHERE ip = ? AND success = FALSE AND created_at > ?`
+	if err := db.QueryRow(query, ip, now.Add(-LoginIPWindow)).Scan(&failures, &accounts); err != nil {
+		return 0, 0, fmt.Errorf("lecture tentatives IP: %w", err)
+	}
+	return failures, accounts, nil
+}
+
+// CheckLoginAllowed indique si une tentative peut être évaluée. user est nil
+// si l'email est inconnu : les mêmes délais s'appliquent, pour ne pas
+// révéler quelles adresses sont inscrites.
+func CheckLoginAllowed(db *sql.DB, user *User, email, ip string, now time.Time) error {
+	failures, _, err := ipFailures(db, ip, now)
+	if err != nil {
+		return err
+	}
+	if failures >= LoginIPThreshold {
+		return &LoginThrottledError{Wait: LoginIPWindow}
+	}
+
+	if user != nil && user.LockedUntil.Valid && user.LockedUntil.Time.After(now) {
+		return ErrAccountLocked
+	}
+	count, last, err := loginFailures(db, email, now)
+	if err != nil {
+		return err
+	}
+	if user == nil && count >= LoginLockThreshold {
+		return ErrAccountLocked
+	}
+	if wait := last.Time.Add(loginDelay(count)).Sub(now); last.Valid && wait > 0 {
+		return &LoginThrottledError{Wait: wait}
+	}
+	return nil
+}
+
+// RecordLoginFailure enregistre l'échec et verrouille le compte au seuil ;
+// renvoie true si le compte vient d'être verrouillé
+func RecordLoginFailure(db *sql.DB, user *User, email, ip string, now time.Time) (bool, error) {
+	if _, err := db.Exec("INSERT INTO login_attempts (email, ip, success, created_at) VALUES (?, ?, FALSE, ?)", email, ip, now); err != nil {
+		return false, fmt.Errorf("enregistrement tentative: %w", err)
+	}
+
+	if failures, accounts, err := ipFailures(db, ip, now); err == nil {
+		if failures == LoginIPThreshold {
+			log.Printf("Activité suspecte: %d échecs de connexion depuis %s en %s, adresse bloquée", failures, ip, LoginIPWindow)
+		}
+		if accounts == LoginIPAccounts {
+			log.Printf("Activité suspecte: %d comptes différents visés depuis %s en %s", accounts, ip, LoginIPWindow)
+		}
+	}
+
+	if user == nil {
+		return false, nil
+	}
+	count, _, err := loginFailures(db, email, now)
+	if err != nil {
+		return false, err
+	}
+	if count < LoginLockThreshold {
+		return false, nil
+	}
+	if _, err := db.Exec("UPDATE users SET locked_until = ? WHERE id = ?", now.Add(LoginLockDuration), user.ID); err != nil {
+		return false, fmt.Errorf("verrouillage compte: %w", err)
+	}
+	// Le compteur repart de zéro à la fin du verrouillage
+	if _, err := db.Exec("UPDATE login_attempts SET cleared = TRUE WHERE email = ? AND success = FALSE AND cleared = FALSE", email); err != nil {
+		return false, fmt.Errorf("remise à zéro tentatives: %w", err)
+	}
+	log.Printf("Compte %d verrouillé après %d échecs de connexion (dernière IP %s)", user.ID, count, ip)
+	go sendAccountUnlock(*user)
+	return true, nil
+}
+
+// RecordLoginSuccess enregistre la connexion et remet le compteur à zéro
+func RecordLoginSuccess(db *sql.DB, email, ip string, now time.Time) error {
+	if _, err := db.Exec("INSERT INTO login_attempts (email, ip, success, created_at) VALUES (?, ?, TRUE, ?)", email, ip, now); err != nil {
+		return fmt.Errorf("enregistrement tentative: %w", err)
+	}
+	if _, err := db.Exec("UPDATE login_attempts SET cleared = TRUE WHERE email = ? AND success = FALSE AND cleared = FALSE", email); err != nil {
+		return fmt.Errorf("remise à zéro tentatives: %w", err)
+	}
+	return nil
+}
+
+// UnlockAccount lève le verrouillage et oublie les échecs précédents
+func UnlockAccount(db *sql.DB, userID int) error {
+	if _, err := db.Exec("UPDATE users SET locked_until = NULL WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("déverrouillage compte: %w", err)
+	}
+	const query = `UPDATE login_attempts SET cleared = TRUE WHERE email = (SELECT email FROM users WHERE id = ?) AND success = FALSE AND cleared = FALSE`
+	if _, err := db.Exec(query, userID); err != nil {
+		return fmt.Errorf("remise à zéro tentatives: %w", err)
+	}
+	return nil
+}
+
+func CreateAccountUnlock(db *sql.DB, userID int) (string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	const query = `INSERT INTO account_unlocks (user_id, token_hash, expires_at) VALUES (?, ?, ?)`
+	if _, err := db.Exec(query, userID, hashToken(token), time.Now().Add(AccountUnlockTTL)); err != nil {
+		return "", fmt.Errorf("création déverrouillage: %w", err)
+	}
+	return token, nil
+}
+
+// ConsumeAccountUnlock consomme le jeton et déverrouille le compte
+func ConsumeAccountUnlock(db *sql.DB, token string) (int, error) {
+	now := time.Now()
+	var unlockID, userID int
+	const query = `SELECT id, user_id FROM account_unlocks WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`
+	if err := db.QueryRow(query, hashToken(token), now).Scan(&unlockID, &userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidUnlockToken
+		}
+		return 0, fmt.Errorf("lecture déverrouillage: %w", err)
+	}
+	res, err := db.Exec("UPDATE account_unlocks SET used_at = ? WHERE id = ? AND used_at IS NULL", now, unlockID)
+	if err != nil {
+		return 0, fmt.Errorf("consommation déverrouillage: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, ErrInvalidUnlockToken
+	}
+	return userID, UnlockAccount(db, userID)
+}
+
+func sendAccountUnlock(user User) {
+	token, err := CreateAccountUnlock(DB, user.ID)
+	if err != nil {
+		log.Printf("Erreur déverrouillage pour l'utilisateur %d: %v", user.ID, err)
+		return
+	}
+	link := BaseURL + "/account/unlock?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(`Bonjour %s,
+
+Votre compte Groupie Tracker a été verrouillé pendant %d minutes après plusieurs tentatives de connexion échouées.
+
+Si c'était vous, vous pouvez le déverrouiller immédiatement avec ce lien :
+
+%s
+
+Si ce n'était pas vous, quelqu'un essaie peut-être de deviner votre mot de passe : nous vous conseillons de le changer et d'activer la double authentification.
+`, user.Username, int(LoginLockDuration.Minutes()), link)
+
+	if err := CurrentMailer().Send(Mail{To: user.Email, Subject: "Votre compte a été verrouillé", Body: body}); err != nil {
+		log.Printf("Erreur envoi email de déverrouillage à l'utilisateur %d: %v", user.ID, err)
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleAccountUnlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := ConsumeAccountUnlock(DB, r.URL.Query().Get("token")); err != nil {
+		if !errors.Is(err, ErrInvalidUnlockToken) {
+			log.Printf("Erreur déverrouillage compte: %v", err)
+		}
+		s.Render(w, r, "login.html", LoginPageData{Error: ErrInvalidUnlockToken.Error()})
+		return
+	}
+	http.Redirect(w, r, "/login?unlocked=1", http.StatusSeeOther)
+}
+
+func (s *Server) HandleAdminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, err := strconv.Atoi(strings.TrimSpace(r.FormValue("user_id")))
+	if err != nil || userID <= 0 {
+		http.Error(w, "ID utilisateur invalide", http.StatusBadRequest)
+		return
+	}
+	if err := UnlockAccount(DB, userID); err != nil {
+		log.Printf("Erreur déverrouillage utilisateur %d: %v", userID, err)
+		http.Error(w, "Erreur lors du déverrouillage", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Compte %d déverrouillé par un administrateur", userID)
+	Audit(r, AuditEvent{Action: AuditUserUnlock, TargetType: "user", TargetID: strconv.Itoa(userID)})
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
@@ -0,0 +1,148 @@
+package src
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string // texte brut
+}
+
+// Mailer envoie les emails transactionnels (réinitialisation, vérification...)
+type Mailer interface {
+	Send(m Mail) error
+}
+
+var (
+	mailer   Mailer
+	mailerMu sync.RWMutex
+)
+
+// CurrentMailer renvoie le mailer configuré : SMTP si SMTP_HOST est défini,
+// sinon écriture dans MAIL_DIR (ou dans les logs) pour le développement local
+func CurrentMailer() Mailer {
+	mailerMu.RLock()
+	m := mailer
+	mailerMu.RUnlock()
+	if m != nil {
+		return m
+	}
+
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	if mailer == nil {
+		mailer = newMailerFromEnv()
+	}
+	return mailer
+}
+
+func SetMailer(m Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	mailer = m
+}
+
+func newMailerFromEnv() Mailer {
+	if SMTPHost != "" {
+		return &SMTPMailer{
+			Host:     SMTPHost,
+			Port:     SMTPPort,
+			Username: SMTPUsername,
+			Password: SMTPPassword,
+			From:     MailFrom,
+		}
+	}
+	return &FileMailer{Dir: MailDir, From: MailFrom}
+}
+
+// formatMail construit le message RFC 5322 encodé en UTF-8
+func formatMail(from string, m Mail) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func validateMail(m Mail) error {
+	if m.To == "" || strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return fmt.Errorf("email invalide")
+	}
+	return nil
+}
+
+// ─── SMTP ────────────────────────────────────────────────────
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPMailer) Send(m Mail) error {
+	if err := validateMail(m); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	// L'enveloppe SMTP n'accepte que l'adresse nue, sans nom affiché
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("expéditeur invalide: %w", err)
+	}
+	addr := net.JoinHostPort(s.Host, s.Port)
+	if err := smtp.SendMail(addr, auth, from.Address, []string{m.To}, formatMail(s.From, m)); err != nil {
+		return fmt.Errorf("envoi email: %w", err)
+	}
+	return nil
+}
+
+// ─── Développement ───────────────────────────────────────────
+
+// FileMailer écrit chaque email dans un fichier .eml de Dir,
+// ou dans les logs si Dir est vide
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (f *FileMailer) Send(m Mail) error {
+	if err := validateMail(m); err != nil {
+		return err
+	}
+	if f.Dir == "" {
+		log.Printf("Email pour %s — %s\n%s", m.To, m.Subject, m.Body)
+		return nil
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return fmt.Errorf("création dossier emails: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), strings.ReplaceAll(m.To, "@", "_at_"))
+	path := filepath.Join(f.Dir, filepath.Base(name))
+	if err := os.WriteFile(path, formatMail(f.From, m), 0o644); err != nil {
+		return fmt.Errorf("écriture email: %w", err)
+	}
+	log.Printf("Email pour %s écrit dans %s", m.To, path)
+	return nil
+}
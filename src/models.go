@@ -1,5 +1,7 @@
 package src
 
+import "net/url"
+
 type Artist struct {
 	ID              int                 `json:"id"`
 	Image           string              `json:"image"`
@@ -37,11 +39,15 @@ type RelationsPayload struct {
 }
 
 type IndexPageData struct {
-	Query   string
-	Count   int
-	Total   int
-	Artists []Artist
-	User    *UserProfile // Informations de l'utilisateur connecté
+	Query         string
+	Count         int
+	Total         int
+	Artists       []Artist
+	User          *UserProfile // Informations de l'utilisateur connecté
+	Filters       ArtistFilters
+	FilterValues  url.Values // Filtres courants, repris par le formulaire d'export
+	FilterError   string
+	ExportColumns []ExportColumn
 }
 
 type UserProfile struct {
@@ -52,11 +58,150 @@ type UserProfile struct {
 	Bio         string
 	PhotoProfil string
 	Role        string
+
+	EmailVerified    bool
+	TwoFactorEnabled bool
+}
+
+type ProfilePageData struct {
+	User        *UserProfile
+	Tokens      []APIToken
+	TokenScopes []TokenScope
+	NewToken    string
+	Notice      string
+
+	PendingEmail string // changement d'adresse en attente de confirmation
+
+	TOTPSetup         *TOTPSetup // enrôlement en cours
+	RecoveryCodes     []string   // affichés une seule fois
+	RecoveryCodesLeft int
+	TwoFactorRequired bool
+
+	Identities        []UserIdentity
+	LinkableProviders []OIDCProviderConfig // fournisseurs pas encore liés
+	HasPassword       bool
+
+	Sessions []DeviceSession
+
+	DeletionScheduledAt string // date de suppression définitive, si demandée
 }
 
 type AdminUsersPageData struct {
-	Users []UserDisplay
-	User  *UserProfile // Utilisateur connecté (admin)
+	Users     []UserDisplay
+	Locked    []UserDisplay // comptes verrouillés après trop d'échecs
+	Roles     []Role        // rôles attribuables
+	Statuses  []UserStatusOption
+	Filter    UserFilter
+	Total     int
+	Page      int
+	PrevURL   string // vide sur la première page
+	NextURL   string // vide sur la dernière page
+	ExportURL string // export CSV avec les filtres courants
+	Query     string // filtres courants, renvoyés par les actions groupées
+	Notice    string
+	User      *UserProfile // Utilisateur connecté (admin)
+}
+
+type AdminDashboardPageData struct {
+	Summary DashboardSummary
+	User    *UserProfile
+}
+
+type AdminArtistRow struct {
+	Artist    Artist
+	Local     bool // créé sur le site, modifiable directement
+	Overrides int  // corrections actives
+	Stale     int  // dont caduques
+}
+
+type AdminArtistsPageData struct {
+	Artists []AdminArtistRow
+	Stale   int
+	User    *UserProfile
+	Notice  string
+}
+
+type AdminConcertRow struct {
+	ArtistConcert
+	Pretty string
+	Added  bool // ajouté par une correction
+}
+
+type AdminArtistEditPageData struct {
+	Artist   Artist // avec corrections
+	Upstream Artist // tel que publié par l'API
+	Members  string // un membre par ligne
+	Concerts []AdminConcertRow
+	History  []ArtistOverride
+	User     *UserProfile
+	Notice   string
+}
+
+type AdminLocalArtistPageData struct {
+	Artist    Artist
+	IsNew     bool
+	Members   string // un membre par ligne
+	Relations string // une ligne « lieu : dates » par lieu
+	User      *UserProfile
+	Notice    string
+}
+
+type FavoriteEntry struct {
+	Artist   Artist
+	Note     string
+	Upcoming []UpcomingConcert
+}
+
+type FavoritesPageData struct {
+	Favorites []FavoriteEntry // par ordre de priorité
+	User      *UserProfile
+	Notice    string
+}
+
+type ListsPageData struct {
+	Lists  []ArtistList // dans l'ordre choisi par l'utilisateur
+	User   *UserProfile
+	Notice string
+}
+
+type SharedListPageData struct {
+	List    ArtistList
+	Artists []Artist
+	Owner   string // pseudo ou nom d'utilisateur du propriétaire
+	IsOwner bool
+	User    *UserProfile // nil pour un visiteur non connecté
+	Notice  string
+}
+
+// ListMembership indique, sur la fiche artiste, si une liste contient l'artiste
+type ListMembership struct {
+	ID       int
+	Name     string
+	Contains bool
+}
+
+type AdminOrdersPageData struct {
+	Orders []Order
+	User   *UserProfile
+}
+
+type AdminAuditPageData struct {
+	Events    []AuditEvent
+	Filter    AuditFilter
+	Actions   []string
+	Total     int
+	Page      int
+	PrevURL   string // vide sur la première page
+	NextURL   string // vide sur la dernière page
+	ExportURL string // export CSV avec les filtres courants
+	User      *UserProfile
+}
+
+type AdminRolesPageData struct {
+	Roles       []Role
+	Permissions []Permission
+	Notice      string
+	User        *UserProfile
 }
 
 type UserDisplay struct {
@@ -68,6 +213,32 @@ type UserDisplay struct {
 	PhotoProfil string
 	Role        string
 	CreatedAt   string
+	Locked      bool
+	LockedUntil string
+
+	Impersonable bool // les membres de l'équipe ne peuvent pas être empruntés
+
+	Banned         bool
+	SuspendedUntil string // vide si le compte n'est pas suspendu
+	SanctionReason string
+
+	EmailVerified   bool
+	DeletionPending bool
+}
+
+// StatusLabel résume l'état du compte pour l'export CSV
+func (u UserDisplay) StatusLabel() string {
+	switch {
+	case u.Banned:
+		return "banni"
+	case u.SuspendedUntil != "":
+		return "suspendu jusqu'au " + u.SuspendedUntil
+	case u.Locked:
+		return "verrouillé jusqu'au " + u.LockedUntil
+	case u.DeletionPending:
+		return "suppression demandée"
+	}
+	return "actif"
 }
 
 type LocationDates struct {
@@ -85,6 +256,9 @@ type ArtistPageData struct {
 	User            *UserProfile
 	IsFavorite      bool
 	Comments        []Comment
+	Lists           []ListMembership
+
+	NeedsVerification bool // commentaires et achats bloqués tant que l'email n'est pas vérifié
 }
 
 type Comment struct {
@@ -97,7 +271,60 @@ type Comment struct {
 	CreatedAt string
 }
 
+// CreateOrderRequest est le corps JSON attendu par /api/paypal/create-order
+type CreateOrderRequest struct {
+	ArtistID int     `json:"artist_id"`
+	Location string  `json:"location"`
+	Date     string  `json:"date"`
+	Quantity int     `json:"quantity"`
+	Amount   float64 `json:"amount"`
+}
+
+type CreateOrderResponse struct {
+	OrderID    string `json:"order_id"`
+	Status     string `json:"status"`
+	ApproveURL string `json:"approve_url"`
+}
+
+// CaptureOrderRequest est le corps JSON attendu par /api/paypal/capture-order
+type CaptureOrderRequest struct {
+	OrderID string `json:"order_id"`
+}
+
+type FavoriteToggleResponse struct {
+	IsFavorite bool `json:"is_favorite"`
+}
+
+type ListToggleResponse struct {
+	InList bool `json:"in_list"`
+}
+
+type ErrorPageData struct {
+	Status  int
+	Title   string
+	Message string
+}
+
+// SanctionPageData explique une suspension ou un bannissement
+type SanctionPageData struct {
+	Banned  bool
+	Message string
+	Reason  string
+}
+
 type LoginPageData struct {
 	Error   string
 	Message string
 }
+
+// ArtistFilters regroupe les critères de filtrage de la liste des artistes
+type ArtistFilters struct {
+	Query         string
+	MinCreation   int
+	MaxCreation   int
+	MinFirstAlbum int
+	MaxFirstAlbum int
+	MembersCount  []int
+	Location      string
+	Favorites     bool // favoris de l'utilisateur connecté uniquement
+}
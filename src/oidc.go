@@ -0,0 +1,769 @@
+package src
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcFlowTimeout = 10 * time.Minute
+	oidcClockSkew   = 2 * time.Minute
+	oidcJWKSMaxAge  = time.Hour
+
+	// Mot de passe inutilisable des comptes créés par un fournisseur externe
+	noPasswordHash = "!"
+)
+
+// OIDCProviderConfig décrit un fournisseur OpenID Connect configuré par
+// variables d'environnement (voir loadOIDCProviders)
+type OIDCProviderConfig struct {
+	Name         string // identifiant utilisé dans les URLs
+	Label        string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+func (c OIDCProviderConfig) RedirectURL() string {
+	return BaseURL + "/auth/oidc/" + c.Name + "/callback"
+}
+
+type UserIdentity struct {
+	ID          int
+	UserID      int
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt sql.NullTime
+}
+
+// IDTokenClaims contient les claims utilisés du jeton d'identité
+type IDTokenClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	AuthorizedFor string       `json:"azp"`
+	Expiry        int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"email_verified"`
+	Name          string       `json:"name"`
+}
+
+// oidcAudience accepte "aud" sous forme de chaîne ou de liste
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a oidcAudience) Contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// ─── Configuration ───────────────────────────────────────────
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// loadOIDCProviders lit OIDC_PROVIDERS (liste séparée par des virgules) puis,
+// pour chaque nom, OIDC_<NOM>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _LABEL, _SCOPES
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerNamePattern.MatchString(name) {
+			log.Printf("Fournisseur OIDC ignoré, nom invalide: %q", name)
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg := OIDCProviderConfig{
+			Name:         name,
+			Label:        getEnvOrDefault(prefix+"LABEL", name),
+			Issuer:       strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(getEnvOrDefault(prefix+"SCOPES", "openid email profile")),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			log.Printf("Fournisseur OIDC %s ignoré: %sISSUER et %sCLIENT_ID requis", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, cfg)
+	}
+	return providers
+}
+
+func FindOIDCProvider(name string) (*oidcProvider, bool) {
+	oidcRegistryOnce.Do(func() {
+		oidcRegistry = make(map[string]*oidcProvider)
+		for _, cfg := range OIDCProviders {
+			oidcRegistry[cfg.Name] = &oidcProvider{Config: cfg}
+		}
+	})
+	p, ok := oidcRegistry[name]
+	return p, ok
+}
+
+var (
+	oidcRegistry     map[string]*oidcProvider
+	oidcRegistryOnce sync.Once
+	oidcHTTPClient   = &http.Client{Timeout: ClientTimeout}
+)
+
+// ProviderLabel renvoie le nom affiché du fournisseur de l'identité
+func (i UserIdentity) ProviderLabel() string {
+	for _, cfg := range OIDCProviders {
+		if cfg.Name == i.Provider {
+			return cfg.Label
+		}
+	}
+	return i.Provider
+}
+
+func unlinkedProviders(identities []UserIdentity) []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, cfg := range OIDCProviders {
+		linked := false
+		for _, id := range identities {
+			if id.Provider == cfg.Name {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			providers = append(providers, cfg)
+		}
+	}
+	return providers
+}
+
+// ─── Client ──────────────────────────────────────────────────
+
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+type oidcProvider struct {
+	Config OIDCProviderConfig
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+func oidcGetJSON(rawURL string, target interface{}) error {
+	resp, err := oidcHTTPClient.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: statut %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// Discovery lit (une fois) le document .well-known/openid-configuration
+func (p *oidcProvider) Discovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	var doc oidcDiscovery
+	if err := oidcGetJSON(p.Config.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("découverte OIDC %s: %w", p.Config.Name, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.Config.Issuer {
+		return nil, fmt.Errorf("découverte OIDC %s: émetteur inattendu %q", p.Config.Name, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("découverte OIDC %s: document incomplet", p.Config.Name)
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// AuthCodeURL construit l'URL d'autorisation (code + PKCE S256)
+func (p *oidcProvider) AuthCodeURL(state, nonce, verifier string) (string, error) {
+	doc, err := p.Discovery()
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.Config.ClientID)
+	params.Set("redirect_uri", p.Config.RedirectURL())
+	params.Set("scope", strings.Join(p.Config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange échange le code d'autorisation contre un jeton d'identité vérifié
+func (p *oidcProvider) Exchange(code, verifier, nonce string) (IDTokenClaims, error) {
+	doc, err := p.Discovery()
+	if err != nil {
+		return IDTokenClaims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.Config.RedirectURL())
+	form.Set("code_verifier", verifier)
+
+	// client_secret_basic par défaut (RFC 6749), client_secret_post si seul proposé
+	useBasic := len(doc.TokenAuthMethods) == 0
+	for _, method := range doc.TokenAuthMethods {
+		if method == "client_secret_basic" {
+			useBasic = true
+		}
+	}
+	if !useBasic {
+		form.Set("client_id", p.Config.ClientID)
+		form.Set("client_secret", p.Config.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return IDTokenClaims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return IDTokenClaims{}, fmt.Errorf("échange code OIDC: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return IDTokenClaims{}, fmt.Errorf("réponse jeton OIDC: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		return IDTokenClaims{}, fmt.Errorf("échange code OIDC: statut %d %s", resp.StatusCode, tokens.Error)
+	}
+	return p.VerifyIDToken(tokens.IDToken, nonce, time.Now())
+}
+
+// VerifyIDToken vérifie la signature RS256 et les claims du jeton d'identité
+func (p *oidcProvider) VerifyIDToken(raw, nonce string, now time.Time) (IDTokenClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return IDTokenClaims{}, errors.New("jeton d'identité mal formé")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return IDTokenClaims{}, fmt.Errorf("en-tête jeton d'identité: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return IDTokenClaims{}, fmt.Errorf("algorithme de signature non supporté: %s", header.Alg)
+	}
+	key, err := p.publicKey(header.Kid)
+	if err != nil {
+		return IDTokenClaims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return IDTokenClaims{}, errors.New("signature du jeton d'identité mal formée")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return IDTokenClaims{}, errors.New("signature du jeton d'identité invalide")
+	}
+
+	var claims IDTokenClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return IDTokenClaims{}, fmt.Errorf("claims jeton d'identité: %w", err)
+	}
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.Config.Issuer:
+		return IDTokenClaims{}, errors.New("émetteur du jeton d'identité inattendu")
+	case !claims.Audience.Contains(p.Config.ClientID):
+		return IDTokenClaims{}, errors.New("jeton d'identité destiné à un autre client")
+	case len(claims.Audience) > 1 && claims.AuthorizedFor != p.Config.ClientID:
+		return IDTokenClaims{}, errors.New("azp du jeton d'identité invalide")
+	case now.After(time.Unix(claims.Expiry, 0).Add(oidcClockSkew)):
+		return IDTokenClaims{}, errors.New("jeton d'identité expiré")
+	case claims.IssuedAt != 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(oidcClockSkew)):
+		return IDTokenClaims{}, errors.New("jeton d'identité émis dans le futur")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 || nonce == "":
+		return IDTokenClaims{}, errors.New("nonce du jeton d'identité invalide")
+	case claims.Subject == "":
+		return IDTokenClaims{}, errors.New("sujet du jeton d'identité manquant")
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// publicKey renvoie la clé kid, en rechargeant le JWKS si elle est inconnue
+// (rotation des clés chez le fournisseur)
+func (p *oidcProvider) publicKey(kid string) (*rsa.PublicKey, error) {
+	doc, err := p.Discovery()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok && time.Since(p.keysFetched) < oidcJWKSMaxAge {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < 10*time.Second {
+		return nil, fmt.Errorf("clé de signature inconnue: %s", kid)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := oidcGetJSON(doc.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("lecture JWKS %s: %w", p.Config.Name, err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("clé de signature inconnue: %s", kid)
+}
+
+func (p *oidcProvider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	// Sans kid, on accepte la clé unique du fournisseur
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+func GetIdentity(db *sql.DB, provider, subject string) (UserIdentity, error) {
+	var id UserIdentity
+	const query = `SELECT id, user_id, provider, subject, email, created_at, last_login_at FROM user_identities WHERE provider = ? AND subject = ? LIMIT 1`
+	err := db.QueryRow(query, provider, subject).Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt, &id.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return UserIdentity{}, sql.ErrNoRows
+		}
+		return UserIdentity{}, fmt.Errorf("lecture identité: %w", err)
+	}
+	return id, nil
+}
+
+func GetUserIdentities(db *sql.DB, userID int) ([]UserIdentity, error) {
+	rows, err := db.Query(`SELECT id, user_id, provider, subject, email, created_at, last_login_at FROM user_identities WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("liste identités: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []UserIdentity
+	for rows.Next() {
+		var id UserIdentity
+		if err := rows.Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt, &id.LastLoginAt); err != nil {
+			return nil, fmt.Errorf("scan identité: %w", err)
+		}
+		identities = append(identities, id)
+	}
+	return identities, rows.Err()
+}
+
+func LinkIdentity(db *sql.DB, userID int, provider string, claims IDTokenClaims) error {
+	const query = `INSERT INTO user_identities (user_id, provider, subject, email, last_login_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := db.Exec(query, userID, provider, claims.Subject, claims.Email, time.Now()); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
+			return errors.New("ce compte externe est déjà lié à un utilisateur")
+		}
+		return fmt.Errorf("liaison identité: %w", err)
+	}
+	return nil
+}
+
+// UnlinkIdentity retire une identité, sauf s'il s'agit du dernier moyen de connexion
+func UnlinkIdentity(db *sql.DB, userID, identityID int) error {
+	user, err := GetUserByID(db, userID)
+	if err != nil {
+		return err
+	}
+	identities, err := GetUserIdentities(db, userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == noPasswordHash && len(identities) <= 1 {
+		return errors.New("définissez un mot de passe avant de retirer votre dernier compte externe")
+	}
+	res, err := db.Exec("DELETE FROM user_identities WHERE id = ? AND user_id = ?", identityID, userID)
+	if err != nil {
+		return fmt.Errorf("suppression identité: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("identité introuvable")
+	}
+	return nil
+}
+
+func TouchIdentity(db *sql.DB, identityID int) error {
+	_, err := db.Exec("UPDATE user_identities SET last_login_at = ? WHERE id = ?", time.Now(), identityID)
+	return err
+}
+
+// CreateExternalUser crée le compte d'un utilisateur connecté pour la première
+// fois via un fournisseur, sans mot de passe utilisable
+func CreateExternalUser(db *sql.DB, provider string, claims IDTokenClaims) (int, error) {
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if email == "" {
+		return 0, errors.New("le fournisseur n'a pas transmis d'adresse email")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("transaction création compte: %w", err)
+	}
+	defer tx.Rollback()
+
+	username, err := uniqueUsername(tx, usernameFromEmail(email))
+	if err != nil {
+		return 0, err
+	}
+	var verifiedAt interface{}
+	if claims.EmailVerified {
+		verifiedAt = time.Now()
+	}
+	res, err := tx.Exec(`INSERT INTO users (username, email, password_hash, email_verified_at) VALUES (?, ?, ?, ?)`, username, email, noPasswordHash, verifiedAt)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
+			return 0, fmt.Errorf("un compte existe déjà avec cet email")
+		}
+		return 0, fmt.Errorf("création utilisateur: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("création utilisateur: %w", err)
+	}
+	const query = `INSERT INTO user_identities (user_id, provider, subject, email, last_login_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, id, provider, claims.Subject, email, time.Now()); err != nil {
+		return 0, fmt.Errorf("liaison identité: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("validation création compte: %w", err)
+	}
+	return int(id), nil
+}
+
+func uniqueUsername(tx *sql.Tx, base string) (string, error) {
+	candidate := base
+	for i := 2; i < 100; i++ {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", candidate).Scan(&count); err != nil {
+			return "", fmt.Errorf("vérification nom d'utilisateur: %w", err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", errors.New("impossible de générer un nom d'utilisateur")
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// HandleOIDCLogin redirige vers le fournisseur pour une connexion ou une liaison
+func (s *Server) HandleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := FindOIDCProvider(r.PathValue("provider"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusInternalServerError)
+		return
+	}
+
+	// GET : connexion ; POST depuis le profil : liaison au compte connecté
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	link := r.Method == http.MethodPost
+	if link && !IsAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	state, err1 := generateSecret()
+	nonce, err2 := generateSecret()
+	verifier, err3 := generateSecret()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		log.Printf("Erreur génération paramètres OIDC: %v", err)
+		http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+		return
+	}
+	authURL, err := provider.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		log.Printf("Erreur OIDC %s: %v", provider.Config.Name, err)
+		s.Render(w, r, "login.html", LoginPageData{Error: "Fournisseur d'identité indisponible, réessayez plus tard"})
+		return
+	}
+
+	session.Values["oidc_provider"] = provider.Config.Name
+	session.Values["oidc_state"] = state
+	session.Values["oidc_nonce"] = nonce
+	session.Values["oidc_verifier"] = verifier
+	session.Values["oidc_link"] = link
+	session.Values["oidc_at"] = time.Now().Unix()
+	if err := SaveSession(w, r, session); err != nil {
+		http.Error(w, "Session indisponible", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (s *Server) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := FindOIDCProvider(r.PathValue("provider"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusInternalServerError)
+		return
+	}
+
+	// Les paramètres du flux sont à usage unique
+	expectedProvider, _ := session.Values["oidc_provider"].(string)
+	state, _ := session.Values["oidc_state"].(string)
+	nonce, _ := session.Values["oidc_nonce"].(string)
+	verifier, _ := session.Values["oidc_verifier"].(string)
+	link, _ := session.Values["oidc_link"].(bool)
+	startedAt, _ := session.Values["oidc_at"].(int64)
+	for _, key := range []string{"oidc_provider", "oidc_state", "oidc_nonce", "oidc_verifier", "oidc_link", "oidc_at"} {
+		delete(session.Values, key)
+	}
+	_ = SaveSession(w, r, session)
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		log.Printf("Connexion OIDC %s refusée: %s %s", provider.Config.Name, errCode, query.Get("error_description"))
+		s.Render(w, r, "login.html", LoginPageData{Error: "Connexion annulée auprès du fournisseur d'identité"})
+		return
+	}
+	if state == "" || expectedProvider != provider.Config.Name ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 ||
+		time.Since(time.Unix(startedAt, 0)) > oidcFlowTimeout {
+		s.Render(w, r, "login.html", LoginPageData{Error: "Session de connexion expirée, veuillez recommencer"})
+		return
+	}
+
+	claims, err := provider.Exchange(query.Get("code"), verifier, nonce)
+	if err != nil {
+		log.Printf("Erreur OIDC %s: %v", provider.Config.Name, err)
+		s.Render(w, r, "login.html", LoginPageData{Error: "Impossible de vérifier votre identité auprès du fournisseur"})
+		return
+	}
+
+	if link {
+		s.finishOIDCLink(w, r, provider.Config, claims)
+		return
+	}
+	s.finishOIDCLogin(w, r, provider.Config, claims)
+}
+
+func (s *Server) finishOIDCLink(w http.ResponseWriter, r *http.Request, cfg OIDCProviderConfig, claims IDTokenClaims) {
+	userID, ok := SessionUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	notice := "Compte " + cfg.Label + " lié"
+	if err := LinkIdentity(DB, userID, cfg.Name, claims); err != nil {
+		log.Printf("Erreur liaison OIDC %s pour l'utilisateur %d: %v", cfg.Name, userID, err)
+		notice = err.Error()
+	} else {
+		Audit(r, AuditEvent{Action: AuditIdentityLink, TargetType: "identity", TargetID: cfg.Name, After: claims.Email})
+	}
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/profile#identities", http.StatusSeeOther)
+}
+
+func (s *Server) finishOIDCLogin(w http.ResponseWriter, r *http.Request, cfg OIDCProviderConfig, claims IDTokenClaims) {
+	var userID int
+	identity, err := GetIdentity(DB, cfg.Name, claims.Subject)
+	switch {
+	case err == nil:
+		userID = identity.UserID
+		if err := TouchIdentity(DB, identity.ID); err != nil {
+			log.Printf("Erreur mise à jour identité %d: %v", identity.ID, err)
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		// Pas de liaison automatique sur l'email : elle permettrait à un
+		// fournisseur de prendre le contrôle d'un compte existant
+		if _, err := GetUserByEmail(DB, claims.Email); err == nil {
+			s.Render(w, r, "login.html", LoginPageData{Error: "Un compte existe déjà avec l'email " + claims.Email + ". Connectez-vous avec votre mot de passe puis liez " + cfg.Label + " depuis votre profil."})
+			return
+		}
+		if userID, err = CreateExternalUser(DB, cfg.Name, claims); err != nil {
+			log.Printf("Erreur création compte OIDC %s: %v", cfg.Name, err)
+			s.Render(w, r, "login.html", LoginPageData{Error: err.Error()})
+			return
+		}
+	default:
+		log.Printf("Erreur OIDC %s: %v", cfg.Name, err)
+		s.Render(w, r, "login.html", LoginPageData{Error: "Une erreur est survenue, veuillez réessayer"})
+		return
+	}
+
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		s.Render(w, r, "login.html", LoginPageData{Error: "Une erreur est survenue, veuillez réessayer"})
+		return
+	}
+	if sanction, ok := user.ActiveSanction(time.Now()); ok {
+		s.renderSanction(w, r, sanction)
+		return
+	}
+	if user.TOTPEnabledAt.Valid {
+		if err := startLogin2FA(w, r, user); err != nil {
+			s.Render(w, r, "login.html", LoginPageData{Error: "Erreur de session, veuillez réessayer"})
+			return
+		}
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+	if err := StartUserSession(w, r, user); err != nil {
+		s.Render(w, r, "login.html", LoginPageData{Error: "Impossible de sauvegarder la session"})
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditLogin, After: cfg.Name})
+	http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+func (s *Server) HandleUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	var identityID int
+	if _, err := fmt.Sscan(r.FormValue("identity_id"), &identityID); err != nil {
+		http.Error(w, "Identifiant invalide", http.StatusBadRequest)
+		return
+	}
+
+	notice := "Compte externe retiré"
+	if err := UnlinkIdentity(DB, userID, identityID); err != nil {
+		notice = err.Error()
+	} else {
+		Audit(r, AuditEvent{Action: AuditIdentityUnlink, TargetType: "identity", TargetID: strconv.Itoa(identityID)})
+	}
+	session.AddFlash(notice, profileNoticeKey)
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#identities", http.StatusSeeOther)
+}
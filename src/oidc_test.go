@@ -0,0 +1,145 @@
+package src
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOIDCProvider démarre un fournisseur minimal (découverte et JWKS) et
+// renvoie la clé qui signe ses jetons
+func testOIDCProvider(t *testing.T) (*oidcProvider, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	return &oidcProvider{Config: OIDCProviderConfig{Name: "test", Issuer: srv.URL, ClientID: "groupie"}}, key
+}
+
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, header, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	p, key := testOIDCProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	header := map[string]interface{}{"alg": "RS256", "kid": "k1"}
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": p.Config.Issuer, "sub": "abc", "aud": "groupie",
+			"exp": now.Add(time.Hour).Unix(), "iat": now.Unix(),
+			"nonce": "n0nce", "email": "jean@example.com",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	valid := signTestJWT(t, key, header, claims(nil))
+	got, err := p.VerifyIDToken(valid, "n0nce", now)
+	if err != nil {
+		t.Fatalf("jeton valide refusé: %v", err)
+	}
+	if got.Subject != "abc" || got.Email != "jean@example.com" {
+		t.Errorf("claims: %+v", got)
+	}
+
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+p.Config.Issuer+`","sub":"admin","aud":"groupie","nonce":"n0nce"}`)) + "." + parts[2]
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		ok    bool
+	}{
+		{"audience en liste avec azp", signTestJWT(t, key, header, claims(func(c map[string]interface{}) {
+			c["aud"] = []string{"autre", "groupie"}
+			c["azp"] = "groupie"
+		})), "n0nce", true},
+		{"émetteur avec barre finale", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["iss"] = p.Config.Issuer + "/" })), "n0nce", true},
+		{"expiré dans la tolérance", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["exp"] = now.Add(-time.Minute).Unix() })), "n0nce", true},
+		{"mal formé", "a.b", "n0nce", false},
+		{"payload modifié", tampered, "n0nce", false},
+		{"autre clé", signTestJWT(t, otherKey, header, claims(nil)), "n0nce", false},
+		{"algorithme none", strings.Replace(valid, parts[0], base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"k1"}`)), 1), "n0nce", false},
+		{"algorithme HS256", signTestJWT(t, key, map[string]interface{}{"alg": "HS256", "kid": "k1"}, claims(nil)), "n0nce", false},
+		{"clé inconnue", signTestJWT(t, key, map[string]interface{}{"alg": "RS256", "kid": "k2"}, claims(nil)), "n0nce", false},
+		{"autre émetteur", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" })), "n0nce", false},
+		{"autre client", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["aud"] = "autre" })), "n0nce", false},
+		{"audience en liste sans azp", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["aud"] = []string{"autre", "groupie"} })), "n0nce", false},
+		{"expiré", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["exp"] = now.Add(-time.Hour).Unix() })), "n0nce", false},
+		{"émis dans le futur", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["iat"] = now.Add(time.Hour).Unix() })), "n0nce", false},
+		{"mauvais nonce", valid, "autre", false},
+		{"nonce vide", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { c["nonce"] = "" })), "", false},
+		{"sujet manquant", signTestJWT(t, key, header, claims(func(c map[string]interface{}) { delete(c, "sub") })), "n0nce", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.VerifyIDToken(tt.token, tt.nonce, now)
+			if (err == nil) != tt.ok {
+				t.Fatalf("erreur %v, attendu accepté=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestOIDCAudienceUnmarshal(t *testing.T) {
+	var c IDTokenClaims
+	if err := json.Unmarshal([]byte(`{"aud":"groupie"}`), &c); err != nil || !c.Audience.Contains("groupie") {
+		t.Errorf("audience simple: %v %v", c.Audience, err)
+	}
+	if err := json.Unmarshal([]byte(`{"aud":["a","groupie"]}`), &c); err != nil || len(c.Audience) != 2 || !c.Audience.Contains("groupie") {
+		t.Errorf("audience liste: %v %v", c.Audience, err)
+	}
+	if err := json.Unmarshal([]byte(`{"aud":42}`), &c); err == nil {
+		t.Error("audience numérique acceptée")
+	}
+}
@@ -0,0 +1,528 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const OpenAPIPath = "/api/openapi.json"
+
+// apiOperation décrit une route JSON pour la génération du document OpenAPI.
+// Request et les valeurs de Responses sont des valeurs zéro des structs
+// réellement encodées par les handlers : le schéma est dérivé par réflexion.
+type apiOperation struct {
+	Method    string
+	Path      string
+	Summary   string
+	Tag       string
+	Auth      bool
+	Scope     string
+	Params    []apiParam
+	Form      []apiParam
+	Request   interface{}
+	Responses map[int]interface{}
+}
+
+type apiParam struct {
+	Name     string
+	In       string
+	Type     string
+	Required bool
+	Summary  string
+}
+
+// apiPageOf et apiItemOf décrivent les enveloppes génériques APIPage / APIItem
+type apiPageOf struct{ Item interface{} }
+type apiItemOf struct{ Item interface{} }
+
+var paginationParams = []apiParam{
+	{Name: "page", In: "query", Type: "integer", Summary: "Numéro de page (1 par défaut)"},
+	{Name: "per_page", In: "query", Type: "integer", Summary: "Éléments par page (20 par défaut, 100 max)"},
+}
+
+var apiErrorResponses = map[int]interface{}{
+	http.StatusBadRequest:      APIError{},
+	http.StatusNotFound:        APIError{},
+	http.StatusTooManyRequests: APIError{},
+}
+
+// apiOperations liste les routes documentées. /api/comment/add et
+// /api/comment/delete n'y figurent pas : ce sont des formulaires HTML qui
+// répondent par une redirection, sans corps JSON.
+func apiOperations() []apiOperation {
+	return []apiOperation{
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/artists", Tag: "artists",
+			Summary: "Liste paginée des artistes, avec recherche et filtres",
+			Params: append([]apiParam{
+				{Name: "q", In: "query", Type: "string", Summary: "Recherche texte (nom, membre, lieu, dates)"},
+				{Name: "min_creation", In: "query", Type: "integer", Summary: "Année de création minimale"},
+				{Name: "max_creation", In: "query", Type: "integer", Summary: "Année de création maximale"},
+				{Name: "min_first_album", In: "query", Type: "integer", Summary: "Année du premier album minimale"},
+				{Name: "max_first_album", In: "query", Type: "integer", Summary: "Année du premier album maximale"},
+				{Name: "members", In: "query", Type: "string", Summary: "Nombres de membres acceptés, séparés par des virgules"},
+				{Name: "location", In: "query", Type: "string", Summary: "Lieu de concert"},
+				{Name: "favorites", In: "query", Type: "string", Summary: "Non supporté (400 invalid_parameter) : les favoris sont servis par " + APIVersionPrefix + "/me/favorites"},
+			}, paginationParams...),
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiPageOf{APIArtist{}}}),
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/artists/{id}", Tag: "artists",
+			Summary:   "Détail d'un artiste avec ses relations dates / lieux",
+			Params:    []apiParam{{Name: "id", In: "path", Type: "integer", Required: true}},
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiItemOf{APIArtistDetail{}}}),
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/locations", Tag: "concerts",
+			Summary:   "Lieux de concert connus",
+			Params:    append([]apiParam{{Name: "q", In: "query", Type: "string"}}, paginationParams...),
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiPageOf{APILocation{}}}),
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/concerts", Tag: "concerts",
+			Summary: "Concerts triés par date",
+			Params: append([]apiParam{
+				{Name: "artist_id", In: "query", Type: "integer"},
+				{Name: "location", In: "query", Type: "string"},
+				{Name: "from", In: "query", Type: "string", Summary: "Date minimale AAAA-MM-JJ"},
+				{Name: "to", In: "query", Type: "string", Summary: "Date maximale AAAA-MM-JJ"},
+			}, paginationParams...),
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiPageOf{APIConcert{}}}),
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/members", Tag: "artists",
+			Summary:   "Membres de tous les groupes",
+			Params:    append([]apiParam{{Name: "q", In: "query", Type: "string"}}, paginationParams...),
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiPageOf{APIMember{}}}),
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/search", Tag: "artists",
+			Summary:   "Suggestions de recherche typées",
+			Params:    append([]apiParam{{Name: "q", In: "query", Type: "string", Required: true}}, paginationParams...),
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiPageOf{APISearchResult{}}}),
+		},
+		{
+			Method: http.MethodGet, Path: "/api/geocode", Tag: "maps", Auth: true,
+			Summary:   "Géocode une adresse via Nominatim",
+			Params:    []apiParam{{Name: "address", In: "query", Type: "string", Required: true}},
+			Responses: map[int]interface{}{http.StatusOK: Coordinates{}},
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/me", Tag: "account", Auth: true, Scope: ScopeRead,
+			Summary:   "Profil de l'utilisateur authentifié",
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiItemOf{APIUser{}}, http.StatusUnauthorized: APIError{}}),
+		},
+		{
+			Method: http.MethodGet, Path: APIVersionPrefix + "/me/favorites", Tag: "favorites", Auth: true, Scope: ScopeRead,
+			Summary:   "Artistes favoris de l'utilisateur authentifié",
+			Responses: withErrors(map[int]interface{}{http.StatusOK: apiItemOf{[]APIArtist{}}, http.StatusUnauthorized: APIError{}}),
+		},
+		{
+			Method: http.MethodPost, Path: "/api/favorite/toggle", Tag: "favorites", Auth: true, Scope: ScopeFavorites,
+			Summary:   "Ajoute ou retire un artiste des favoris",
+			Form:      []apiParam{{Name: "artist_id", Type: "integer", Required: true}},
+			Responses: map[int]interface{}{http.StatusOK: FavoriteToggleResponse{}, http.StatusForbidden: APIError{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/list/toggle", Tag: "lists", Auth: true, Scope: ScopeFavorites,
+			Summary: "Ajoute ou retire un artiste d'une liste de l'utilisateur",
+			Form: []apiParam{
+				{Name: "list_id", Type: "integer", Required: true},
+				{Name: "artist_id", Type: "integer", Required: true},
+			},
+			Responses: map[int]interface{}{http.StatusOK: ListToggleResponse{}, http.StatusForbidden: APIError{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/stats/{chart}", Tag: "admin", Auth: true,
+			Summary: "Données d'un graphique du tableau de bord (permission dashboard.view)",
+			Params: []apiParam{{Name: "chart", In: "path", Type: "string", Required: true,
+				Summary: "registrations, activity, favorites, comments ou revenue"}},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  ChartData{},
+				http.StatusNotFound:            APIError{},
+				http.StatusInternalServerError: APIError{},
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/paypal/create-order", Tag: "tickets", Auth: true,
+			Summary:   "Crée une commande PayPal pour un billet",
+			Request:   CreateOrderRequest{},
+			Responses: map[int]interface{}{http.StatusOK: CreateOrderResponse{}, http.StatusForbidden: APIError{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/paypal/capture-order", Tag: "tickets", Auth: true,
+			Summary:   "Capture le paiement d'une commande PayPal",
+			Request:   CaptureOrderRequest{},
+			Responses: map[int]interface{}{http.StatusOK: PayPalCaptureResponse{}, http.StatusForbidden: APIError{}},
+		},
+	}
+}
+
+func withErrors(responses map[int]interface{}) map[int]interface{} {
+	for status, body := range apiErrorResponses {
+		responses[status] = body
+	}
+	return responses
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]interface{}
+)
+
+// OpenAPISpec renvoie le document OpenAPI 3, généré une seule fois
+func OpenAPISpec() map[string]interface{} {
+	openAPIOnce.Do(func() {
+		openAPIDoc = BuildOpenAPISpec(apiOperations())
+	})
+	return openAPIDoc
+}
+
+// BuildOpenAPISpec génère le document OpenAPI à partir des opérations déclarées
+func BuildOpenAPISpec(operations []apiOperation) map[string]interface{} {
+	gen := &schemaGenerator{components: make(map[string]interface{})}
+	paths := make(map[string]interface{})
+
+	for _, op := range operations {
+		item, _ := paths[op.Path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+
+		operation := map[string]interface{}{
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+			"tags":        []string{op.Tag},
+		}
+		if op.Auth {
+			security := []map[string][]string{{"sessionCookie": {}}}
+			if op.Scope != "" {
+				security = append(security, map[string][]string{"bearerToken": {op.Scope}})
+			}
+			operation["security"] = security
+		}
+
+		params := make([]map[string]interface{}, 0, len(op.Params))
+		for _, p := range op.Params {
+			param := map[string]interface{}{
+				"name":     p.Name,
+				"in":       p.In,
+				"required": p.Required,
+				"schema":   map[string]interface{}{"type": p.Type},
+			}
+			if p.Summary != "" {
+				param["description"] = p.Summary
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": gen.schemaFor(op.Request)},
+				},
+			}
+		} else if len(op.Form) > 0 {
+			properties := make(map[string]interface{})
+			required := []string{}
+			for _, f := range op.Form {
+				properties[f.Name] = map[string]interface{}{"type": f.Type}
+				if f.Required {
+					required = append(required, f.Name)
+				}
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/x-www-form-urlencoded": map[string]interface{}{
+						"schema": map[string]interface{}{"type": "object", "properties": properties, "required": required},
+					},
+				},
+			}
+		}
+
+		responses := make(map[string]interface{})
+		for status, body := range op.Responses {
+			responses[fmt.Sprint(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": gen.schemaFor(body)},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Groupie Tracker API",
+			"version":     "1.0.0",
+			"description": "Artistes, concerts et lieux agrégés depuis l'API Groupie Tracker.",
+		},
+		"servers": []map[string]string{{"url": "/"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": gen.components,
+			"securitySchemes": map[string]interface{}{
+				"sessionCookie": map[string]string{"type": "apiKey", "in": "cookie", "name": SessionName, "description": "Les requêtes POST authentifiées par cookie doivent porter l'en-tête " + csrfHeaderName},
+				"bearerToken":   map[string]string{"type": "http", "scheme": "bearer", "description": "Jeton d'accès personnel créé depuis la page profil"},
+			},
+		},
+	}
+}
+
+func operationID(op apiOperation) string {
+	cleaned := strings.NewReplacer("/api/", "", "/", "_", "-", "_", "{", "", "}", "").Replace(op.Path)
+	return strings.ToLower(op.Method) + "_" + strings.Trim(cleaned, "_")
+}
+
+// schemaGenerator convertit les types Go en schémas JSON Schema / OpenAPI
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schemaFor(v interface{}) map[string]interface{} {
+	switch wrapper := v.(type) {
+	case apiPageOf:
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"data": map[string]interface{}{"type": "array", "items": g.schemaFor(wrapper.Item)},
+				"meta": g.typeSchema(reflect.TypeOf(APIPageMeta{})),
+			},
+			"required": []string{"data", "meta"},
+		}
+	case apiItemOf:
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"data": g.schemaFor(wrapper.Item)},
+			"required":   []string{"data"},
+		}
+	}
+	return g.typeSchema(reflect.TypeOf(v))
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		schema := g.typeSchema(t.Elem())
+		return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, exists := g.components[name]; !exists {
+			g.components[name] = nil // réserve le nom pour les types récursifs
+			g.components[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	g.collectFields(t, properties, &required)
+	sort.Strings(required)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// collectFields suit les règles d'encoding/json : tags, champs ignorés,
+// omitempty et champs embarqués aplatis
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.collectFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.typeSchema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// ─── Contrôle de contrat ─────────────────────────────────────
+
+// ValidateAgainstSchema vérifie qu'une valeur JSON décodée respecte un schéma
+// du document (types, propriétés requises, propriétés inattendues)
+func ValidateAgainstSchema(spec map[string]interface{}, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		components := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		target, ok := components[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: schéma %s introuvable", path, name)
+		}
+		return ValidateAgainstSchema(spec, target, value, path)
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		if value == nil && schema["nullable"] == true {
+			return nil
+		}
+		for _, sub := range allOf {
+			if err := ValidateAgainstSchema(spec, sub.(map[string]interface{}), value, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: objet attendu, reçu %T", path, value)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			for key, item := range obj {
+				if err := ValidateAgainstSchema(spec, additional, item, path+"."+key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		required, _ := schema["required"].([]string)
+		for _, key := range required {
+			if _, exists := obj[key]; !exists {
+				return fmt.Errorf("%s: propriété requise %q absente", path, key)
+			}
+		}
+		for key, item := range obj {
+			propSchema, exists := properties[key].(map[string]interface{})
+			if !exists {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: propriété %q absente du schéma", path, key)
+				}
+				continue
+			}
+			if err := ValidateAgainstSchema(spec, propSchema, item, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: tableau attendu, reçu %T", path, value)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range list {
+			if err := ValidateAgainstSchema(spec, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: chaîne attendue, reçu %T", path, value)
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: entier attendu, reçu %v", path, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: nombre attendu, reçu %T", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: booléen attendu, reçu %T", path, value)
+		}
+	}
+	return nil
+}
+
+// CheckAPIContract valide une réponse JSON contre l'opération correspondante
+// du document spec (OpenAPISpec() en général) ; utilisé par openapi_test.go
+func CheckAPIContract(spec map[string]interface{}, method, path string, status int, body []byte) error {
+	item, ok := spec["paths"].(map[string]interface{})[path].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	operation, ok := item[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	response, ok := operation["responses"].(map[string]interface{})[fmt.Sprint(status)].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s %s: statut %d non documenté", method, path, status)
+	}
+	// 204 et réponses sans contenu documenté : le corps doit être vide
+	content, _ := response["content"].(map[string]interface{})
+	media, _ := content["application/json"].(map[string]interface{})
+	schema, ok := media["schema"].(map[string]interface{})
+	if !ok {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("%s %s: corps inattendu pour le statut %d", method, path, status)
+		}
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: réponse JSON invalide: %w", method, path, err)
+	}
+	if err := ValidateAgainstSchema(spec, schema, value, "$"); err != nil {
+		return fmt.Errorf("%s %s (%d): %w", method, path, status, err)
+	}
+	return nil
+}
+
+// HandleOpenAPI sert le document OpenAPI des routes JSON
+func (s *Server) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	WriteAPIJSON(w, r, http.StatusOK, OpenAPISpec())
+}
@@ -0,0 +1,168 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testServer renvoie un serveur sans base de données ni appel à l'API Groupie
+func testServer() *Server {
+	return &Server{artists: []Artist{
+		{
+			ID: 1, Name: "Queen", Image: "https://example.com/queen.jpeg",
+			Members:      []string{"Freddie Mercury", "Brian May"},
+			CreationDate: 1970, FirstAlbum: "14-12-1973",
+			Locations:      []string{"london-uk", "paris-france"},
+			ConcertDates:   []string{"*10-07-2019", "12-07-2019"},
+			DatesLocations: map[string][]string{"london-uk": {"10-07-2019"}, "paris-france": {"12-07-2019"}},
+		},
+		{
+			ID: 2, Name: "Pink Floyd", Image: "https://example.com/pinkfloyd.jpeg",
+			Members:      []string{"Roger Waters", "David Gilmour"},
+			CreationDate: 1965, FirstAlbum: "05-08-1967",
+			Locations:      []string{"paris-france"},
+			ConcertDates:   []string{"*01-01-2020"},
+			DatesLocations: map[string][]string{"paris-france": {"01-01-2020"}},
+		},
+	}}
+}
+
+// operationRequest construit un appel valide à l'opération : paramètres de
+// chemin et paramètres requis renseignés
+func operationRequest(op apiOperation) *http.Request {
+	path := op.Path
+	query := url.Values{}
+	for _, p := range op.Params {
+		value := "1"
+		switch p.Name {
+		case "q":
+			value = "queen"
+		case "chart":
+			value = "registrations"
+		case "address":
+			value = "Paris"
+		}
+		switch {
+		case p.In == "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", value)
+		case p.Required:
+			query.Set(p.Name, value)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var req *http.Request
+	switch {
+	case op.Request != nil:
+		req = httptest.NewRequest(op.Method, path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+	case len(op.Form) > 0:
+		form := url.Values{}
+		for _, f := range op.Form {
+			form.Set(f.Name, "1")
+		}
+		req = httptest.NewRequest(op.Method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	default:
+		req = httptest.NewRequest(op.Method, path, nil)
+	}
+	req.Header.Set("Accept", "application/json")
+	return req
+}
+
+func checkContract(t *testing.T, handler http.Handler, op apiOperation, req *http.Request) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		// Sans session, les routes réservées aux navigateurs renvoient vers la
+		// connexion : pas de corps JSON à vérifier
+		if op.Auth && rec.Code == http.StatusSeeOther && rec.Header().Get("Location") == "/login" {
+			return
+		}
+		t.Fatalf("%s %s: réponse %d non JSON (%q)", req.Method, req.URL, rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if err := CheckAPIContract(OpenAPISpec(), op.Method, op.Path, rec.Code, rec.Body.Bytes()); err != nil {
+		t.Fatalf("écart de contrat: %v\n%s", err, rec.Body.String())
+	}
+}
+
+// TestAPIContract appelle chaque route documentée et compare la réponse au
+// document OpenAPI. Sans base de données, les routes authentifiées ne sont
+// vérifiées que sur leur réponse à un appel anonyme.
+func TestAPIContract(t *testing.T) {
+	handler := testServer().Routes()
+	for _, op := range apiOperations() {
+		t.Run(op.Method+" "+op.Path, func(t *testing.T) {
+			checkContract(t, handler, op, operationRequest(op))
+		})
+	}
+}
+
+// TestAPIContractErrors vérifie les réponses d'erreur documentées
+func TestAPIContractErrors(t *testing.T) {
+	handler := testServer().Routes()
+	operations := map[string]apiOperation{}
+	for _, op := range apiOperations() {
+		operations[op.Path] = op
+	}
+	tests := []struct {
+		path   string
+		target string
+		status int
+	}{
+		{APIVersionPrefix + "/artists/{id}", APIVersionPrefix + "/artists/999", http.StatusNotFound},
+		{APIVersionPrefix + "/artists/{id}", APIVersionPrefix + "/artists/abc", http.StatusBadRequest},
+		{APIVersionPrefix + "/artists", APIVersionPrefix + "/artists?page=0", http.StatusBadRequest},
+		{APIVersionPrefix + "/artists", APIVersionPrefix + "/artists?page=461168601842738800", http.StatusOK},
+		{APIVersionPrefix + "/artists", APIVersionPrefix + "/artists?favorites=1", http.StatusBadRequest},
+		{APIVersionPrefix + "/concerts", APIVersionPrefix + "/concerts?from=hier", http.StatusBadRequest},
+		{APIVersionPrefix + "/me", APIVersionPrefix + "/me", http.StatusUnauthorized},
+		{"/api/favorite/toggle", "/api/favorite/toggle", http.StatusForbidden},
+		{"/api/list/toggle", "/api/list/toggle", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			op, ok := operations[tt.path]
+			if !ok {
+				t.Fatalf("opération %s non documentée", tt.path)
+			}
+			req := httptest.NewRequest(op.Method, tt.target, nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("statut %d, attendu %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if err := CheckAPIContract(OpenAPISpec(), op.Method, op.Path, rec.Code, rec.Body.Bytes()); err != nil {
+				t.Fatalf("écart de contrat: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckAPIContractNoContent(t *testing.T) {
+	// Copie locale : le document servi par OpenAPISpec() est partagé
+	spec := BuildOpenAPISpec(apiOperations())
+	spec["paths"].(map[string]interface{})["/test/no-content"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"responses": map[string]interface{}{"204": map[string]interface{}{"description": "Aucun contenu"}},
+		},
+	}
+
+	if err := CheckAPIContract(spec, http.MethodPost, "/test/no-content", http.StatusNoContent, nil); err != nil {
+		t.Fatalf("204 sans corps refusé: %v", err)
+	}
+	if err := CheckAPIContract(spec, http.MethodPost, "/test/no-content", http.StatusNoContent, []byte(`{"a":1}`)); err == nil {
+		t.Fatal("204 avec corps accepté")
+	}
+	if _, ok := OpenAPISpec()["paths"].(map[string]interface{})["/test/no-content"]; ok {
+		t.Error("le document partagé a été modifié")
+	}
+}
@@ -0,0 +1,132 @@
+package src
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Order est une commande de billets passée via PayPal
+type Order struct {
+	ID            int        `json:"id"`
+	PayPalOrderID string     `json:"paypal_order_id"`
+	ArtistID      int        `json:"artist_id"`
+	ArtistName    string     `json:"artist_name"`
+	Location      string     `json:"location"`
+	Date          string     `json:"date"`
+	Quantity      int        `json:"quantity"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CapturedAt    *time.Time `json:"captured_at,omitempty"`
+
+	Username string `json:"-"` // acheteur, pour /admin/orders
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+func RecordOrder(db *sql.DB, userID int, o Order) error {
+	const query = `INSERT INTO orders (user_id, paypal_order_id, artist_id, artist_name, location, concert_date, quantity, amount, status, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, userID, o.PayPalOrderID, o.ArtistID, o.ArtistName, o.Location, o.Date, o.Quantity, o.Amount, o.Status, time.Now())
+	if err != nil {
+		return fmt.Errorf("enregistrement commande: %w", err)
+	}
+	return nil
+}
+
+// UpdateOrderStatus reporte le statut renvoyé par PayPal après capture
+func UpdateOrderStatus(db *sql.DB, paypalOrderID, status string) error {
+	var capturedAt interface{}
+	if status == "COMPLETED" {
+		capturedAt = time.Now()
+	}
+	const query = `UPDATE orders SET status = ?, captured_at = COALESCE(captured_at, ?) WHERE paypal_order_id = ?`
+	if _, err := db.Exec(query, status, capturedAt, paypalOrderID); err != nil {
+		return fmt.Errorf("mise à jour commande: %w", err)
+	}
+	return nil
+}
+
+func GetUserOrders(db *sql.DB, userID int) ([]Order, error) {
+	const query = `SELECT id, paypal_order_id, artist_id, artist_name, location, concert_date, quantity, amount, status, created_at, captured_at
+FROM orders WHERE user_id = ? ORDER BY created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture commandes: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		var capturedAt sql.NullTime
+		if err := rows.Scan(&o.ID, &o.PayPalOrderID, &o.ArtistID, &o.ArtistName, &o.Location, &o.Date, &o.Quantity, &o.Amount, &o.Status, &o.CreatedAt, &capturedAt); err != nil {
+			return nil, fmt.Errorf("scan commande: %w", err)
+		}
+		if capturedAt.Valid {
+			o.CapturedAt = &capturedAt.Time
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// GetRecentOrders liste les dernières commandes, tous utilisateurs confondus
+func GetRecentOrders(db *sql.DB, limit int) ([]Order, error) {
+	const query = `SELECT o.id, o.paypal_order_id, o.artist_id, o.artist_name, o.location, o.concert_date, o.quantity, o.amount, o.status, o.created_at, o.captured_at,
+       COALESCE(u.username, '')
+FROM orders o LEFT JOIN users u ON u.id = o.user_id
+ORDER BY o.created_at DESC LIMIT ?`
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("lecture commandes: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		var capturedAt sql.NullTime
+		if err := rows.Scan(&o.ID, &o.PayPalOrderID, &o.ArtistID, &o.ArtistName, &o.Location, &o.Date, &o.Quantity, &o.Amount, &o.Status, &o.CreatedAt, &capturedAt, &o.Username); err != nil {
+			return nil, fmt.Errorf("scan commande: %w", err)
+		}
+		if capturedAt.Valid {
+			o.CapturedAt = &capturedAt.Time
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleAdminOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	orders, err := GetRecentOrders(DB, AdminOrdersLimit)
+	if err != nil {
+		log.Printf("Erreur récupération commandes: %v", err)
+		http.Error(w, "Erreur lors de la récupération des commandes", http.StatusInternalServerError)
+		return
+	}
+	s.Render(w, r, "admin-orders.html", AdminOrdersPageData{
+		Orders: orders,
+		User: &UserProfile{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			Pseudo:      getStringValue(user.Pseudo),
+			PhotoProfil: getStringValue(user.PhotoProfil),
+			Role:        user.Role,
+		},
+	})
+}
@@ -0,0 +1,820 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Champs corrigeables d'un artiste ; les concerts sont ajoutés ou retirés un par un
+const (
+	OverrideImage         = "image"
+	OverrideName          = "name"
+	OverrideMembers       = "members"
+	OverrideCreationDate  = "creationDate"
+	OverrideFirstAlbum    = "firstAlbum"
+	OverrideConcertAdd    = "concert.add"
+	OverrideConcertRemove = "concert.remove"
+)
+
+var (
+	ErrOverrideField   = errors.New("champ non modifiable")
+	ErrOverrideUnknown = errors.New("correction introuvable ou déjà annulée")
+	ErrOverrideExists  = errors.New("cette correction est déjà active")
+)
+
+// overrideScalarFields sont remplacés en bloc : une seule correction active par champ
+var overrideScalarFields = []string{OverrideImage, OverrideName, OverrideMembers, OverrideCreationDate, OverrideFirstAlbum}
+
+var overrideLabels = map[string]string{
+	OverrideImage:         "Image",
+	OverrideName:          "Nom",
+	OverrideMembers:       "Membres",
+	OverrideCreationDate:  "Année de création",
+	OverrideFirstAlbum:    "Premier album",
+	OverrideConcertAdd:    "Concert ajouté",
+	OverrideConcertRemove: "Concert retiré",
+}
+
+// ArtistConcert est une date de concert dans un lieu, au format de l'API
+type ArtistConcert struct {
+	Location string `json:"location"`
+	Date     string `json:"date"`
+}
+
+// ArtistOverride est une correction éditoriale. Value et Upstream sont en JSON ;
+// Upstream garde la valeur de l'API au moment de la correction pour détecter
+// les changements en amont.
+type ArtistOverride struct {
+	ID         int
+	ArtistID   int
+	Field      string
+	Value      string
+	Upstream   string
+	Stale      bool // l'API a changé depuis : la correction est à vérifier
+	Author     string
+	CreatedAt  time.Time
+	RevertedAt sql.NullTime
+	Reverter   string
+}
+
+func (o ArtistOverride) Active() bool {
+	return !o.RevertedAt.Valid
+}
+
+func (o ArtistOverride) FieldLabel() string {
+	if label, ok := overrideLabels[o.Field]; ok {
+		return label
+	}
+	return o.Field
+}
+
+// Display rend la valeur lisible pour l'historique
+func (o ArtistOverride) Display() string {
+	return displayOverrideValue(o.Field, o.Value)
+}
+
+func (o ArtistOverride) UpstreamDisplay() string {
+	if o.Upstream == "" {
+		return ""
+	}
+	return displayOverrideValue(o.Field, o.Upstream)
+}
+
+func displayOverrideValue(field, value string) string {
+	switch field {
+	case OverrideMembers:
+		var members []string
+		if json.Unmarshal([]byte(value), &members) == nil {
+			return strings.Join(members, ", ")
+		}
+	case OverrideConcertAdd, OverrideConcertRemove:
+		var c ArtistConcert
+		if json.Unmarshal([]byte(value), &c) == nil {
+			return FormatLocation(c.Location) + " · " + c.Date
+		}
+	default:
+		var v interface{}
+		if json.Unmarshal([]byte(value), &v) == nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return value
+}
+
+// ─── Fusion ──────────────────────────────────────────────────
+
+// overrideValue renvoie la valeur JSON d'un champ scalaire de l'artiste
+func overrideValue(a Artist, field string) (string, error) {
+	var v interface{}
+	switch field {
+	case OverrideImage:
+		v = a.Image
+	case OverrideName:
+		v = a.Name
+	case OverrideMembers:
+		members := a.Members
+		if members == nil {
+			members = []string{}
+		}
+		v = members
+	case OverrideCreationDate:
+		v = a.CreationDate
+	case OverrideFirstAlbum:
+		v = a.FirstAlbum
+	default:
+		return "", ErrOverrideField
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func applyOverride(a *Artist, o ArtistOverride) error {
+	value := []byte(o.Value)
+	switch o.Field {
+	case OverrideImage:
+		return json.Unmarshal(value, &a.Image)
+	case OverrideName:
+		return json.Unmarshal(value, &a.Name)
+	case OverrideMembers:
+		return json.Unmarshal(value, &a.Members)
+	case OverrideCreationDate:
+		return json.Unmarshal(value, &a.CreationDate)
+	case OverrideFirstAlbum:
+		return json.Unmarshal(value, &a.FirstAlbum)
+	case OverrideConcertAdd, OverrideConcertRemove:
+		var c ArtistConcert
+		if err := json.Unmarshal(value, &c); err != nil {
+			return err
+		}
+		if o.Field == OverrideConcertAdd {
+			addConcert(a, c)
+		} else {
+			removeConcert(a, c)
+		}
+		return nil
+	}
+	return ErrOverrideField
+}
+
+// overrideStale indique si la correction ne correspond plus aux données de l'API
+func overrideStale(upstream Artist, o ArtistOverride) bool {
+	switch o.Field {
+	case OverrideConcertAdd, OverrideConcertRemove:
+		var c ArtistConcert
+		if err := json.Unmarshal([]byte(o.Value), &c); err != nil {
+			return true
+		}
+		// concert désormais publié par l'API, ou déjà retiré par elle
+		return hasConcert(upstream, c) == (o.Field == OverrideConcertAdd)
+	}
+	current, err := overrideValue(upstream, o.Field)
+	return err != nil || current != o.Upstream
+}
+
+func hasConcert(a Artist, c ArtistConcert) bool {
+	for _, date := range CleanDates(a.DatesLocations[c.Location]) {
+		if date == c.Date {
+			return true
+		}
+	}
+	return false
+}
+
+func addConcert(a *Artist, c ArtistConcert) {
+	if hasConcert(*a, c) {
+		return
+	}
+	if a.DatesLocations == nil {
+		a.DatesLocations = make(map[string][]string)
+	}
+	if _, ok := a.DatesLocations[c.Location]; !ok {
+		a.Locations = append(a.Locations, c.Location)
+	}
+	a.DatesLocations[c.Location] = append(a.DatesLocations[c.Location], c.Date)
+	for _, date := range a.ConcertDates {
+		if date == c.Date {
+			return
+		}
+	}
+	a.ConcertDates = append(a.ConcertDates, c.Date)
+}
+
+func removeConcert(a *Artist, c ArtistConcert) {
+	dates := CleanDates(a.DatesLocations[c.Location])
+	kept := dates[:0]
+	for _, date := range dates {
+		if date != c.Date {
+			kept = append(kept, date)
+		}
+	}
+	if len(kept) > 0 {
+		a.DatesLocations[c.Location] = kept
+	} else if _, ok := a.DatesLocations[c.Location]; ok {
+		delete(a.DatesLocations, c.Location)
+		a.Locations = removeString(a.Locations, c.Location)
+	}
+	for _, others := range a.DatesLocations {
+		for _, date := range CleanDates(others) {
+			if date == c.Date {
+				return
+			}
+		}
+	}
+	a.ConcertDates = removeString(CleanDates(a.ConcertDates), c.Date)
+}
+
+func removeString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// cloneArtist copie les slices et la map pour corriger sans toucher aux données de l'API
+func cloneArtist(a Artist) Artist {
+	a.Members = append([]string(nil), a.Members...)
+	a.Locations = append([]string(nil), a.Locations...)
+	a.ConcertDates = append([]string(nil), a.ConcertDates...)
+	relations := make(map[string][]string, len(a.DatesLocations))
+	for loc, dates := range a.DatesLocations {
+		relations[loc] = append([]string(nil), dates...)
+	}
+	a.DatesLocations = relations
+	return a
+}
+
+// MergeArtistOverrides applique les corrections actives, dans l'ordre de création,
+// et renvoie pour chacune si elle est caduque
+func MergeArtistOverrides(upstream []Artist, overrides []ArtistOverride) ([]Artist, map[int]bool) {
+	byArtist := make(map[int][]ArtistOverride)
+	for _, o := range overrides {
+		byArtist[o.ArtistID] = append(byArtist[o.ArtistID], o)
+	}
+
+	stale := make(map[int]bool, len(overrides))
+	merged := make([]Artist, len(upstream))
+	for i, a := range upstream {
+		merged[i] = a
+		if len(byArtist[a.ID]) == 0 {
+			continue
+		}
+		merged[i] = cloneArtist(a)
+		for _, o := range byArtist[a.ID] {
+			stale[o.ID] = overrideStale(a, o)
+			if err := applyOverride(&merged[i], o); err != nil {
+				log.Printf("Correction %d de l'artiste %d ignorée: %v", o.ID, a.ID, err)
+				stale[o.ID] = true
+			}
+		}
+	}
+	// artiste retiré de l'API
+	for _, o := range overrides {
+		if _, ok := stale[o.ID]; !ok {
+			stale[o.ID] = true
+		}
+	}
+	return merged, stale
+}
+
+// RebuildArtists recalcule les artistes affichés : données de l'API, artistes
+// locaux puis corrections actives. Si la base est indisponible, les artistes
+// locaux précédents sont conservés et les corrections ignorées.
+func (s *Server) RebuildArtists() error {
+	s.rebuildMu.Lock()
+	defer s.rebuildMu.Unlock()
+
+	local, localErr := GetLocalArtists(DB)
+	overrides, err := GetActiveOverrides(DB)
+	if err != nil {
+		overrides = nil
+	}
+	s.mu.Lock()
+	if localErr == nil {
+		s.local = local
+	}
+	all := make([]Artist, 0, len(s.upstream)+len(s.local))
+	all = append(append(all, s.upstream...), s.local...)
+	merged, stale := MergeArtistOverrides(all, overrides)
+	s.artists = merged
+	s.mu.Unlock()
+
+	if err == nil {
+		err = UpdateOverrideStaleness(DB, overrides, stale)
+	}
+	return errors.Join(localErr, err)
+}
+
+// FindUpstreamArtist renvoie l'artiste tel que publié par l'API, sans correction
+func (s *Server) FindUpstreamArtist(id int) (Artist, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, art := range s.upstream {
+		if art.ID == id {
+			return art, true
+		}
+	}
+	return Artist{}, false
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+const overrideColumns = `o.id, o.artist_id, o.field, o.value, o.upstream, o.stale, COALESCE(a.username, ''), o.created_at, o.reverted_at, COALESCE(r.username, '')
+FROM artist_overrides o
+LEFT JOIN users a ON a.id = o.created_by
+LEFT JOIN users r ON r.id = o.reverted_by`
+
+func scanOverrides(rows *sql.Rows) ([]ArtistOverride, error) {
+	defer rows.Close()
+	var overrides []ArtistOverride
+	for rows.Next() {
+		var o ArtistOverride
+		if err := rows.Scan(&o.ID, &o.ArtistID, &o.Field, &o.Value, &o.Upstream, &o.Stale, &o.Author, &o.CreatedAt, &o.RevertedAt, &o.Reverter); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+func GetActiveOverrides(db *sql.DB) ([]ArtistOverride, error) {
+	rows, err := db.Query("SELECT " + overrideColumns + " WHERE o.reverted_at IS NULL ORDER BY o.id")
+	if err != nil {
+		return nil, fmt.Errorf("lecture corrections: %w", err)
+	}
+	overrides, err := scanOverrides(rows)
+	if err != nil {
+		return nil, fmt.Errorf("lecture corrections: %w", err)
+	}
+	return overrides, nil
+}
+
+// GetArtistOverrideHistory renvoie toutes les corrections d'un artiste, les plus récentes d'abord
+func GetArtistOverrideHistory(db *sql.DB, artistID int) ([]ArtistOverride, error) {
+	rows, err := db.Query("SELECT "+overrideColumns+" WHERE o.artist_id = ? ORDER BY o.id DESC", artistID)
+	if err != nil {
+		return nil, fmt.Errorf("historique corrections: %w", err)
+	}
+	overrides, err := scanOverrides(rows)
+	if err != nil {
+		return nil, fmt.Errorf("historique corrections: %w", err)
+	}
+	return overrides, nil
+}
+
+func GetArtistOverride(db *sql.DB, id int) (ArtistOverride, error) {
+	rows, err := db.Query("SELECT "+overrideColumns+" WHERE o.id = ?", id)
+	if err != nil {
+		return ArtistOverride{}, fmt.Errorf("lecture correction: %w", err)
+	}
+	overrides, err := scanOverrides(rows)
+	if err != nil {
+		return ArtistOverride{}, fmt.Errorf("lecture correction: %w", err)
+	}
+	if len(overrides) == 0 {
+		return ArtistOverride{}, ErrOverrideUnknown
+	}
+	return overrides[0], nil
+}
+
+// CreateArtistOverride enregistre une correction. Pour un champ scalaire, la
+// correction active précédente est annulée et reste dans l'historique.
+func CreateArtistOverride(db *sql.DB, o ArtistOverride, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("création correction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if o.Field == OverrideConcertAdd || o.Field == OverrideConcertRemove {
+		var exists bool
+		const query = `SELECT EXISTS(SELECT 1 FROM artist_overrides WHERE artist_id = ? AND field = ? AND value = ? AND reverted_at IS NULL)`
+		if err := tx.QueryRow(query, o.ArtistID, o.Field, o.Value).Scan(&exists); err != nil {
+			return fmt.Errorf("création correction: %w", err)
+		}
+		if exists {
+			return ErrOverrideExists
+		}
+	} else {
+		const revert = `UPDATE artist_overrides SET reverted_at = NOW(), reverted_by = ? WHERE artist_id = ? AND field = ? AND reverted_at IS NULL`
+		if _, err := tx.Exec(revert, nullableUserID(userID), o.ArtistID, o.Field); err != nil {
+			return fmt.Errorf("création correction: %w", err)
+		}
+	}
+
+	const insert = `INSERT INTO artist_overrides (artist_id, field, value, upstream, created_by) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(insert, o.ArtistID, o.Field, o.Value, o.Upstream, nullableUserID(userID)); err != nil {
+		return fmt.Errorf("création correction: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RevertArtistOverride annule une correction active
+func RevertArtistOverride(db *sql.DB, id, userID int) error {
+	const query = `UPDATE artist_overrides SET reverted_at = NOW(), reverted_by = ? WHERE id = ? AND reverted_at IS NULL`
+	res, err := db.Exec(query, nullableUserID(userID), id)
+	if err != nil {
+		return fmt.Errorf("annulation correction: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOverrideUnknown
+	}
+	return nil
+}
+
+// RevertFieldOverride rend à un champ scalaire la valeur de l'API
+func RevertFieldOverride(db *sql.DB, artistID int, field string, userID int) error {
+	const query = `UPDATE artist_overrides SET reverted_at = NOW(), reverted_by = ? WHERE artist_id = ? AND field = ? AND reverted_at IS NULL`
+	if _, err := db.Exec(query, nullableUserID(userID), artistID, field); err != nil {
+		return fmt.Errorf("annulation correction: %w", err)
+	}
+	return nil
+}
+
+// UpdateOverrideStaleness enregistre les corrections devenues caduques (ou à nouveau valides)
+func UpdateOverrideStaleness(db *sql.DB, overrides []ArtistOverride, stale map[int]bool) error {
+	for _, o := range overrides {
+		if o.Stale == stale[o.ID] {
+			continue
+		}
+		if _, err := db.Exec("UPDATE artist_overrides SET stale = ? WHERE id = ?", stale[o.ID], o.ID); err != nil {
+			return fmt.Errorf("mise à jour corrections caduques: %w", err)
+		}
+		if stale[o.ID] {
+			log.Printf("Correction %d de l'artiste %d caduque après mise à jour de l'API", o.ID, o.ArtistID)
+		}
+	}
+	return nil
+}
+
+func nullableUserID(userID int) interface{} {
+	if userID == 0 {
+		return nil
+	}
+	return userID
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func adminArtistNotice(w http.ResponseWriter, r *http.Request, artistID int, notice string) {
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	target := "/admin/artists"
+	if artistID != 0 {
+		target += "/edit?id=" + strconv.Itoa(artistID)
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func takeNotice(w http.ResponseWriter, r *http.Request) string {
+	session, err := GetSession(r)
+	if err != nil {
+		return ""
+	}
+	flashes := session.Flashes(profileNoticeKey)
+	if len(flashes) == 0 {
+		return ""
+	}
+	_ = SaveSession(w, r, session)
+	notice, _ := flashes[0].(string)
+	return notice
+}
+
+func adminProfile(u User) *UserProfile {
+	return &UserProfile{
+		ID:          u.ID,
+		Username:    u.Username,
+		Email:       u.Email,
+		Pseudo:      getStringValue(u.Pseudo),
+		PhotoProfil: getStringValue(u.PhotoProfil),
+		Role:        u.Role,
+	}
+}
+
+func (s *Server) HandleAdminArtists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	overrides, err := GetActiveOverrides(DB)
+	if err != nil {
+		log.Printf("Erreur récupération corrections: %v", err)
+		http.Error(w, "Erreur lors de la récupération des corrections", http.StatusInternalServerError)
+		return
+	}
+	counts := make(map[int]*AdminArtistRow)
+	for _, o := range overrides {
+		if counts[o.ArtistID] == nil {
+			counts[o.ArtistID] = &AdminArtistRow{}
+		}
+		counts[o.ArtistID].Overrides++
+		if o.Stale {
+			counts[o.ArtistID].Stale++
+		}
+	}
+
+	data := AdminArtistsPageData{User: adminProfile(admin), Notice: takeNotice(w, r)}
+	for _, art := range s.ListArtists() {
+		row := AdminArtistRow{Artist: art, Local: IsLocalArtist(art.ID)}
+		if c := counts[art.ID]; c != nil {
+			row.Overrides, row.Stale = c.Overrides, c.Stale
+			data.Stale += c.Stale
+		}
+		data.Artists = append(data.Artists, row)
+	}
+	// les corrections à vérifier d'abord
+	sort.SliceStable(data.Artists, func(i, j int) bool {
+		return data.Artists[i].Stale > data.Artists[j].Stale
+	})
+	s.Render(w, r, "admin-artists.html", data)
+}
+
+func (s *Server) HandleAdminArtistEdit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	if IsLocalArtist(id) {
+		// un artiste local se modifie directement
+		http.Redirect(w, r, "/admin/artists/local?id="+strconv.Itoa(id), http.StatusSeeOther)
+		return
+	}
+	art, found := s.FindArtist(id)
+	upstream, _ := s.FindUpstreamArtist(id)
+	if !found {
+		s.RenderError(w, r, http.StatusNotFound, "Artiste introuvable", "Cet artiste n'existe pas.")
+		return
+	}
+	history, err := GetArtistOverrideHistory(DB, id)
+	if err != nil {
+		log.Printf("Erreur historique corrections: %v", err)
+		http.Error(w, "Erreur lors de la récupération des corrections", http.StatusInternalServerError)
+		return
+	}
+
+	added := make(map[ArtistConcert]bool)
+	for _, o := range history {
+		if o.Active() && o.Field == OverrideConcertAdd {
+			var c ArtistConcert
+			if json.Unmarshal([]byte(o.Value), &c) == nil {
+				added[c] = true
+			}
+		}
+	}
+	var concerts []AdminConcertRow
+	for _, ld := range BuildLocationDates(art.DatesLocations) {
+		for _, date := range ld.Dates {
+			c := ArtistConcert{Location: ld.Raw, Date: date}
+			concerts = append(concerts, AdminConcertRow{ArtistConcert: c, Pretty: ld.Pretty, Added: added[c]})
+		}
+	}
+
+	s.Render(w, r, "admin-artist-edit.html", AdminArtistEditPageData{
+		Artist:   art,
+		Upstream: upstream,
+		Members:  strings.Join(art.Members, "\n"),
+		Concerts: concerts,
+		History:  history,
+		User:     adminProfile(admin),
+		Notice:   takeNotice(w, r),
+	})
+}
+
+// parseArtistForm lit le formulaire d'édition par-dessus l'artiste courant
+func parseArtistForm(r *http.Request, art Artist) (Artist, error) {
+	art.Name = strings.TrimSpace(r.FormValue("name"))
+	if art.Name == "" {
+		return art, errors.New("le nom est obligatoire")
+	}
+	art.Image = strings.TrimSpace(r.FormValue("image"))
+	if !strings.HasPrefix(art.Image, "/static/uploads/") {
+		if u, err := url.Parse(art.Image); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return art, errors.New("l'image doit être une adresse http(s)")
+		}
+	}
+	art.Members = nil
+	for _, line := range strings.Split(r.FormValue("members"), "\n") {
+		if member := strings.TrimSpace(line); member != "" {
+			art.Members = append(art.Members, member)
+		}
+	}
+	year, err := strconv.Atoi(strings.TrimSpace(r.FormValue("creation_date")))
+	if err != nil || year < 1900 || year > time.Now().Year() {
+		return art, errors.New("année de création invalide")
+	}
+	art.CreationDate = year
+	art.FirstAlbum = strings.TrimSpace(r.FormValue("first_album"))
+	if _, err := time.Parse("02-01-2006", art.FirstAlbum); err != nil {
+		return art, errors.New("date du premier album invalide (JJ-MM-AAAA)")
+	}
+	return art, nil
+}
+
+func (s *Server) HandleAdminArtistSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	current, found := s.FindArtist(id)
+	upstream, _ := s.FindUpstreamArtist(id)
+	if !found || IsLocalArtist(id) {
+		adminArtistNotice(w, r, 0, "Artiste introuvable")
+		return
+	}
+	edited, err := parseArtistForm(r, current)
+	if err != nil {
+		adminArtistNotice(w, r, id, err.Error())
+		return
+	}
+
+	changed := 0
+	for _, field := range overrideScalarFields {
+		before, _ := overrideValue(current, field)
+		after, _ := overrideValue(edited, field)
+		if before == after {
+			continue
+		}
+		base, _ := overrideValue(upstream, field)
+		if after == base {
+			err = RevertFieldOverride(DB, id, field, admin.ID)
+		} else {
+			err = CreateArtistOverride(DB, ArtistOverride{ArtistID: id, Field: field, Value: after, Upstream: base}, admin.ID)
+		}
+		if err != nil {
+			log.Printf("Erreur correction artiste %d: %v", id, err)
+			break
+		}
+		Audit(r, AuditEvent{Action: AuditArtistOverride, TargetType: "artist", TargetID: strconv.Itoa(id), Before: field + "=" + before, After: field + "=" + after})
+		changed++
+	}
+	if changed > 0 {
+		if applyErr := s.RebuildArtists(); applyErr != nil {
+			log.Printf("Erreur application corrections: %v", applyErr)
+		}
+	}
+	switch {
+	case err != nil:
+		adminArtistNotice(w, r, id, "Erreur lors de l'enregistrement des corrections")
+	case changed == 0:
+		adminArtistNotice(w, r, id, "Aucune modification")
+	default:
+		adminArtistNotice(w, r, id, fmt.Sprintf("%d champ(s) corrigé(s)", changed))
+	}
+}
+
+// HandleAdminArtistConcert ajoute ou retire un concert ; retirer un concert
+// ajouté localement annule simplement son ajout
+func (s *Server) HandleAdminArtistConcert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	if _, found := s.FindArtist(id); !found || IsLocalArtist(id) {
+		adminArtistNotice(w, r, 0, "Artiste introuvable")
+		return
+	}
+	c := ArtistConcert{
+		Location: strings.ReplaceAll(strings.ToLower(strings.TrimSpace(r.FormValue("location"))), " ", "_"),
+		Date:     strings.TrimSpace(r.FormValue("date")),
+	}
+	if day, err := time.Parse("2006-01-02", c.Date); err == nil {
+		c.Date = day.Format("02-01-2006") // champ date du navigateur
+	}
+	if _, err := time.Parse("02-01-2006", c.Date); err != nil || c.Location == "" {
+		adminArtistNotice(w, r, id, "Lieu ou date de concert invalide")
+		return
+	}
+	value, _ := json.Marshal(c)
+
+	field, notice := OverrideConcertAdd, "Concert ajouté"
+	if r.FormValue("action") == "remove" {
+		field, notice = OverrideConcertRemove, "Concert retiré"
+		history, err := GetArtistOverrideHistory(DB, id)
+		if err != nil {
+			log.Printf("Erreur historique corrections: %v", err)
+			adminArtistNotice(w, r, id, "Erreur lors de l'enregistrement de la correction")
+			return
+		}
+		for _, o := range history {
+			if o.Active() && o.Field == OverrideConcertAdd && o.Value == string(value) {
+				s.revertOverride(w, r, o, admin.ID)
+				return
+			}
+		}
+	}
+
+	err := CreateArtistOverride(DB, ArtistOverride{ArtistID: id, Field: field, Value: string(value)}, admin.ID)
+	if errors.Is(err, ErrOverrideExists) {
+		adminArtistNotice(w, r, id, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur correction artiste %d: %v", id, err)
+		adminArtistNotice(w, r, id, "Erreur lors de l'enregistrement de la correction")
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditArtistOverride, TargetType: "artist", TargetID: strconv.Itoa(id), After: field + "=" + string(value)})
+	if err := s.RebuildArtists(); err != nil {
+		log.Printf("Erreur application corrections: %v", err)
+	}
+	adminArtistNotice(w, r, id, notice)
+}
+
+func (s *Server) HandleAdminArtistRevert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("override"))
+	o, err := GetArtistOverride(DB, id)
+	if err != nil {
+		adminArtistNotice(w, r, 0, ErrOverrideUnknown.Error())
+		return
+	}
+	s.revertOverride(w, r, o, admin.ID)
+}
+
+func (s *Server) revertOverride(w http.ResponseWriter, r *http.Request, o ArtistOverride, adminID int) {
+	if err := RevertArtistOverride(DB, o.ID, adminID); err != nil {
+		adminArtistNotice(w, r, o.ArtistID, ErrOverrideUnknown.Error())
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditArtistRevert, TargetType: "artist", TargetID: strconv.Itoa(o.ArtistID), Before: o.Field + "=" + o.Value})
+	if err := s.RebuildArtists(); err != nil {
+		log.Printf("Erreur application corrections: %v", err)
+	}
+	adminArtistNotice(w, r, o.ArtistID, "Correction annulée : "+o.FieldLabel())
+}
+
+// HandleAdminArtistRestore réapplique une correction annulée de l'historique
+func (s *Server) HandleAdminArtistRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("override"))
+	o, err := GetArtistOverride(DB, id)
+	if err != nil || o.Active() {
+		adminArtistNotice(w, r, 0, "Correction introuvable ou déjà active")
+		return
+	}
+	restored := ArtistOverride{ArtistID: o.ArtistID, Field: o.Field, Value: o.Value}
+	if upstream, ok := s.FindUpstreamArtist(o.ArtistID); ok {
+		restored.Upstream, _ = overrideValue(upstream, o.Field)
+	}
+	err = CreateArtistOverride(DB, restored, admin.ID)
+	if errors.Is(err, ErrOverrideExists) {
+		adminArtistNotice(w, r, o.ArtistID, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur correction artiste %d: %v", o.ArtistID, err)
+		adminArtistNotice(w, r, o.ArtistID, "Erreur lors du rétablissement de la correction")
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditArtistOverride, TargetType: "artist", TargetID: strconv.Itoa(o.ArtistID), After: o.Field + "=" + o.Value})
+	if err := s.RebuildArtists(); err != nil {
+		log.Printf("Erreur application corrections: %v", err)
+	}
+	adminArtistNotice(w, r, o.ArtistID, "Correction rétablie : "+o.FieldLabel())
+}
@@ -0,0 +1,102 @@
+package src
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testOverride(id, artistID int, field string, value, upstream interface{}) ArtistOverride {
+	encode := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	o := ArtistOverride{ID: id, ArtistID: artistID, Field: field, Value: encode(value)}
+	if upstream != nil {
+		o.Upstream = encode(upstream)
+	}
+	return o
+}
+
+func TestMergeArtistOverrides(t *testing.T) {
+	upstream := testServer().artists
+	before := cloneArtist(upstream[0])
+
+	overrides := []ArtistOverride{
+		testOverride(1, 1, OverrideName, "Queen (UK)", "Queen"),
+		testOverride(2, 1, OverrideMembers, []string{"Freddie Mercury", "Brian May", "Roger Taylor"}, []string{"Freddie Mercury", "Brian May"}),
+		testOverride(3, 1, OverrideConcertAdd, ArtistConcert{Location: "berlin-germany", Date: "01-08-2019"}, nil),
+		testOverride(4, 1, OverrideConcertRemove, ArtistConcert{Location: "london-uk", Date: "10-07-2019"}, nil),
+		testOverride(5, 2, OverrideCreationDate, 1964, 1966),
+		testOverride(6, 99, OverrideName, "Disparu", "Ancien"),
+	}
+	merged, stale := MergeArtistOverrides(upstream, overrides)
+
+	queen := merged[0]
+	if queen.Name != "Queen (UK)" || len(queen.Members) != 3 {
+		t.Errorf("champs non corrigés: %q %v", queen.Name, queen.Members)
+	}
+	if !reflect.DeepEqual(queen.DatesLocations["berlin-germany"], []string{"01-08-2019"}) {
+		t.Errorf("concert ajouté absent: %v", queen.DatesLocations)
+	}
+	if _, ok := queen.DatesLocations["london-uk"]; ok {
+		t.Errorf("lieu sans concert conservé: %v", queen.DatesLocations)
+	}
+	if reflect.DeepEqual(queen.Locations, before.Locations) {
+		t.Errorf("lieux non mis à jour: %v", queen.Locations)
+	}
+	if merged[1].CreationDate != 1964 {
+		t.Errorf("année de création: %d", merged[1].CreationDate)
+	}
+	if !reflect.DeepEqual(upstream[0], before) {
+		t.Error("les données de l'API ont été modifiées")
+	}
+
+	want := map[int]bool{1: false, 2: false, 3: false, 4: false, 5: true, 6: true}
+	if !reflect.DeepEqual(stale, want) {
+		t.Errorf("caducité %v, attendu %v", stale, want)
+	}
+}
+
+func TestMergeArtistOverridesOrder(t *testing.T) {
+	merged, stale := MergeArtistOverrides(testServer().artists, []ArtistOverride{
+		testOverride(1, 2, OverrideFirstAlbum, "01-01-1967", "05-08-1967"),
+		testOverride(2, 2, OverrideFirstAlbum, "02-02-1967", "05-08-1967"),
+		{ID: 3, ArtistID: 2, Field: OverrideImage, Value: "{pas du json", Upstream: `"https://example.com/pinkfloyd.jpeg"`},
+	})
+	if merged[1].FirstAlbum != "02-02-1967" {
+		t.Errorf("la dernière correction doit l'emporter: %s", merged[1].FirstAlbum)
+	}
+	if merged[1].Image != "https://example.com/pinkfloyd.jpeg" {
+		t.Errorf("correction invalide appliquée: %s", merged[1].Image)
+	}
+	if stale[1] || stale[2] || !stale[3] {
+		t.Errorf("caducité: %v", stale)
+	}
+}
+
+func TestOverrideStale(t *testing.T) {
+	queen := testServer().artists[0]
+	tests := []struct {
+		name string
+		o    ArtistOverride
+		want bool
+	}{
+		{"valeur inchangée en amont", testOverride(1, 1, OverrideName, "Queen II", "Queen"), false},
+		{"valeur modifiée en amont", testOverride(1, 1, OverrideName, "Queen II", "The Queen"), true},
+		{"membres inchangés", testOverride(1, 1, OverrideMembers, []string{}, []string{"Freddie Mercury", "Brian May"}), false},
+		{"champ inconnu", testOverride(1, 1, "genre", "rock", "pop"), true},
+		{"concert à ajouter", testOverride(1, 1, OverrideConcertAdd, ArtistConcert{"paris-france", "13-07-2019"}, nil), false},
+		{"concert publié depuis", testOverride(1, 1, OverrideConcertAdd, ArtistConcert{"paris-france", "12-07-2019"}, nil), true},
+		{"concert à retirer", testOverride(1, 1, OverrideConcertRemove, ArtistConcert{"paris-france", "12-07-2019"}, nil), false},
+		{"concert déjà retiré", testOverride(1, 1, OverrideConcertRemove, ArtistConcert{"paris-france", "13-07-2019"}, nil), true},
+		{"concert illisible", ArtistOverride{Field: OverrideConcertAdd, Value: "{"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overrideStale(queen, tt.o); got != tt.want {
+				t.Errorf("overrideStale = %v, attendu %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package src
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("lien de réinitialisation invalide ou expiré")
+
+type ResetPasswordPageData struct {
+	Token string
+	Valid bool
+	Error string
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// CreatePasswordReset crée un jeton à usage unique ; les demandes précédentes
+// encore valides sont annulées
+func CreatePasswordReset(db *sql.DB, userID int) (string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.Exec("UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL", time.Now(), userID); err != nil {
+		return "", fmt.Errorf("annulation réinitialisations: %w", err)
+	}
+	const query = `INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES (?, ?, ?)`
+	if _, err := db.Exec(query, userID, hashToken(token), time.Now().Add(PasswordResetTTL)); err != nil {
+		return "", fmt.Errorf("création réinitialisation: %w", err)
+	}
+	return token, nil
+}
+
+// PasswordResetValid indique si le jeton peut encore être utilisé
+func PasswordResetValid(db *sql.DB, token string) (bool, error) {
+	var count int
+	const query = `SELECT COUNT(*) FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`
+	if err := db.QueryRow(query, hashToken(token), time.Now()).Scan(&count); err != nil {
+		return false, fmt.Errorf("lecture réinitialisation: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ResetPassword consomme le jeton, change le mot de passe et révoque
+// toutes les sessions ouvertes de l'utilisateur
+func ResetPassword(db *sql.DB, token, password string) (int, error) {
+	if err := validatePassword(password); err != nil {
+		return 0, err
+	}
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("transaction réinitialisation: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var resetID, userID int
+	const query = `SELECT id, user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ? FOR UPDATE`
+	if err := tx.QueryRow(query, hashToken(token), now).Scan(&resetID, &userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidResetToken
+		}
+		return 0, fmt.Errorf("lecture réinitialisation: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL", now, userID); err != nil {
+		return 0, fmt.Errorf("consommation réinitialisation: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE users SET password_hash = ?, updated_at = NOW() WHERE id = ?", hashed, userID); err != nil {
+		return 0, fmt.Errorf("mise à jour mot de passe: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return 0, fmt.Errorf("révocation sessions: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("validation réinitialisation: %w", err)
+	}
+	return userID, nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.Render(w, r, "forgot-password.html", LoginPageData{})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.Render(w, r, "forgot-password.html", LoginPageData{Error: "Erreur lors du traitement du formulaire"})
+		return
+	}
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email == "" {
+		s.Render(w, r, "forgot-password.html", LoginPageData{Error: "Veuillez indiquer votre email"})
+		return
+	}
+
+	// Même réponse que le compte existe ou non, et envoi hors requête
+	// pour ne pas révéler les adresses inscrites
+	if user, err := GetUserByEmail(DB, email); err == nil {
+		go sendPasswordReset(user)
+	}
+	s.Render(w, r, "forgot-password.html", LoginPageData{
+		Message: "Si un compte correspond à cette adresse, un lien de réinitialisation vient d'être envoyé.",
+	})
+}
+
+func sendPasswordReset(user User) {
+	token, err := CreatePasswordReset(DB, user.ID)
+	if err != nil {
+		log.Printf("Erreur réinitialisation pour l'utilisateur %d: %v", user.ID, err)
+		return
+	}
+	link := BaseURL + "/password/reset?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(`Bonjour %s,
+
+Une réinitialisation du mot de passe de votre compte Groupie Tracker a été demandée.
+Pour choisir un nouveau mot de passe, ouvrez ce lien (valable %d minutes, utilisable une seule fois) :
+
+%s
+
+Si vous n'êtes pas à l'origine de cette demande, ignorez ce message : votre mot de passe reste inchangé.
+`, user.Username, int(PasswordResetTTL.Minutes()), link)
+
+	if err := CurrentMailer().Send(Mail{To: user.Email, Subject: "Réinitialisation de votre mot de passe", Body: body}); err != nil {
+		log.Printf("Erreur envoi email de réinitialisation à l'utilisateur %d: %v", user.ID, err)
+	}
+}
+
+func (s *Server) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		token := r.URL.Query().Get("token")
+		valid := false
+		if token != "" {
+			var err error
+			if valid, err = PasswordResetValid(DB, token); err != nil {
+				log.Printf("Erreur vérification réinitialisation: %v", err)
+			}
+		}
+		data := ResetPasswordPageData{Token: token, Valid: valid}
+		if !valid {
+			data.Error = ErrInvalidResetToken.Error()
+		}
+		s.Render(w, r, "reset-password.html", data)
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Requête invalide", http.StatusBadRequest)
+			return
+		}
+		token := r.FormValue("token")
+		password := r.FormValue("password")
+		if password != r.FormValue("confirm_password") {
+			s.Render(w, r, "reset-password.html", ResetPasswordPageData{Token: token, Valid: true, Error: "Les mots de passe ne correspondent pas"})
+			return
+		}
+		userID, err := ResetPassword(DB, token, password)
+		if err != nil {
+			data := ResetPasswordPageData{Token: token, Valid: true, Error: err.Error()}
+			if errors.Is(err, ErrInvalidResetToken) {
+				data.Valid = false
+			} else if validatePassword(password) == nil {
+				log.Printf("Erreur réinitialisation mot de passe: %v", err)
+				data.Error = "Une erreur est survenue, veuillez réessayer"
+			}
+			s.Render(w, r, "reset-password.html", data)
+			return
+		}
+
+		// La session courante éventuelle est fermée comme toutes les autres
+		if session, err := GetSession(r); err == nil {
+			session.Values = make(map[interface{}]interface{})
+			session.Options.MaxAge = -1
+			_ = SaveSession(w, r, session)
+		}
+		Audit(r, AuditEvent{ActorID: userID, Action: AuditPasswordReset, TargetType: "user", TargetID: strconv.Itoa(userID)})
+		http.Redirect(w, r, "/login?reset=1", http.StatusSeeOther)
+	default:
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,419 @@
+package src
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrDeletionNotConfirmed = errors.New("saisissez l'adresse email de votre compte pour confirmer")
+
+// ─── Export des données (RGPD) ───────────────────────────────
+
+// UserDataExport regroupe toutes les données personnelles d'un utilisateur,
+// écrites dans data.json de l'archive téléchargée depuis le profil
+type UserDataExport struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	Profile    ExportedProfile    `json:"profile"`
+	Favorites  []ExportedFavorite `json:"favorites"`
+	Lists      []ExportedList     `json:"lists"`
+	Comments   []ExportedComment  `json:"comments"`
+	Orders     []Order            `json:"orders"`
+	Sessions   []ExportedSession  `json:"sessions"`
+	Identities []ExportedIdentity `json:"linked_accounts"`
+	APITokens  []ExportedAPIToken `json:"api_tokens"`
+}
+
+type ExportedProfile struct {
+	ID                  int        `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	Pseudo              string     `json:"pseudo,omitempty"`
+	Bio                 string     `json:"bio,omitempty"`
+	PhotoProfil         string     `json:"photo_profil,omitempty"`
+	Role                string     `json:"role"`
+	CreatedAt           time.Time  `json:"created_at"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+}
+
+type ExportedFavorite struct {
+	ArtistID   int       `json:"artist_id"`
+	ArtistName string    `json:"artist_name,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ExportedComment struct {
+	ID        int       `json:"id"`
+	ArtistID  int       `json:"artist_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedSession struct {
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+type ExportedIdentity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedAPIToken struct {
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+func getFavoritesForExport(db *sql.DB, userID int) ([]ExportedFavorite, error) {
+	rows, err := db.Query("SELECT artist_id, COALESCE(note, ''), created_at FROM favorites WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture favoris: %w", err)
+	}
+	defer rows.Close()
+
+	var favorites []ExportedFavorite
+	for rows.Next() {
+		var f ExportedFavorite
+		if err := rows.Scan(&f.ArtistID, &f.Note, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan favori: %w", err)
+		}
+		favorites = append(favorites, f)
+	}
+	return favorites, rows.Err()
+}
+
+func getCommentsForExport(db *sql.DB, userID int) ([]ExportedComment, error) {
+	rows, err := db.Query("SELECT id, artist_id, content, created_at FROM comments WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("lecture commentaires: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []ExportedComment
+	for rows.Next() {
+		var c ExportedComment
+		if err := rows.Scan(&c.ID, &c.ArtistID, &c.Content, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan commentaire: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// ExportUserData rassemble les données de l'utilisateur ; artistName sert à
+// nommer les artistes des favoris et des listes
+func ExportUserData(db *sql.DB, user User, artistName func(int) string) (*UserDataExport, error) {
+	export := &UserDataExport{
+		ExportedAt: time.Now(),
+		Profile: ExportedProfile{
+			ID:                  user.ID,
+			Username:            user.Username,
+			Email:               user.Email,
+			Pseudo:              getStringValue(user.Pseudo),
+			Bio:                 getStringValue(user.Bio),
+			PhotoProfil:         getStringValue(user.PhotoProfil),
+			Role:                user.Role,
+			CreatedAt:           user.CreatedAt,
+			EmailVerifiedAt:     nullTimePtr(user.EmailVerifiedAt),
+			TwoFactorEnabled:    user.TOTPEnabledAt.Valid,
+			DeletionRequestedAt: nullTimePtr(user.DeletionRequestedAt),
+		},
+	}
+
+	var err error
+	if export.Favorites, err = getFavoritesForExport(db, user.ID); err != nil {
+		return nil, err
+	}
+	for i := range export.Favorites {
+		export.Favorites[i].ArtistName = artistName(export.Favorites[i].ArtistID)
+	}
+	lists, err := GetUserLists(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lists {
+		exported, err := ExportList(db, l, artistName)
+		if err != nil {
+			return nil, err
+		}
+		export.Lists = append(export.Lists, exported)
+	}
+	if export.Comments, err = getCommentsForExport(db, user.ID); err != nil {
+		return nil, err
+	}
+	if export.Orders, err = GetUserOrders(db, user.ID); err != nil {
+		return nil, err
+	}
+
+	sessions, err := GetUserSessions(db, user.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range sessions {
+		export.Sessions = append(export.Sessions, ExportedSession{IP: d.IP, UserAgent: d.UserAgent, CreatedAt: d.CreatedAt, LastSeenAt: d.LastSeenAt})
+	}
+
+	identities, err := GetUserIdentities(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range identities {
+		export.Identities = append(export.Identities, ExportedIdentity{Provider: id.Provider, Email: id.Email, CreatedAt: id.CreatedAt})
+	}
+
+	tokens, err := GetUserAPITokens(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		export.APITokens = append(export.APITokens, ExportedAPIToken{
+			Name:       t.Name,
+			Prefix:     t.Prefix,
+			Scopes:     t.Scopes,
+			CreatedAt:  t.CreatedAt,
+			LastUsedAt: nullTimePtr(t.LastUsedAt),
+			ExpiresAt:  nullTimePtr(t.ExpiresAt),
+			RevokedAt:  nullTimePtr(t.RevokedAt),
+		})
+	}
+	return export, nil
+}
+
+// ScheduleAccountDeletion programme la suppression du compte au terme de
+// AccountDeletionGrace. Un compte sans mot de passe confirme avec son email.
+func ScheduleAccountDeletion(db *sql.DB, user User, confirmation string) (time.Time, error) {
+	if user.PasswordHash == noPasswordHash {
+		if !strings.EqualFold(strings.TrimSpace(confirmation), user.Email) {
+			return time.Time{}, ErrDeletionNotConfirmed
+		}
+	} else if checkPassword(user.PasswordHash, confirmation) != nil {
+		return time.Time{}, ErrWrongPassword
+	}
+
+	now := time.Now()
+	if _, err := db.Exec("UPDATE users SET deletion_requested_at = ? WHERE id = ?", now, user.ID); err != nil {
+		return time.Time{}, fmt.Errorf("demande de suppression: %w", err)
+	}
+	return now.Add(AccountDeletionGrace), nil
+}
+
+func CancelAccountDeletion(db *sql.DB, userID int) error {
+	if _, err := db.Exec("UPDATE users SET deletion_requested_at = NULL WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("annulation suppression: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeletedAccounts supprime les comptes dont le délai de grâce est écoulé
+// et renvoie le nombre de comptes supprimés
+func PurgeDeletedAccounts(db *sql.DB, now time.Time) (int, error) {
+	rows, err := db.Query("SELECT id, COALESCE(photo_profil, '') FROM users WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= ?", now.Add(-AccountDeletionGrace))
+	if err != nil {
+		return 0, fmt.Errorf("lecture comptes à supprimer: %w", err)
+	}
+	type pending struct {
+		id    int
+		photo string
+	}
+	var users []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.photo); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan compte à supprimer: %w", err)
+		}
+		users = append(users, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("lecture comptes à supprimer: %w", err)
+	}
+
+	deleted := 0
+	for _, p := range users {
+		if err := DeleteUser(db, p.id); err != nil {
+			return deleted, err
+		}
+		removeUploadedPhoto(p.photo)
+		log.Printf("Compte %d supprimé à la demande de l'utilisateur", p.id)
+		Audit(nil, AuditEvent{ActorName: "système", Action: AuditAccountPurged, TargetType: "user", TargetID: strconv.Itoa(p.id)})
+		deleted++
+	}
+	return deleted, nil
+}
+
+// removeUploadedPhoto efface la photo de profil si elle a été téléversée
+func removeUploadedPhoto(photo string) {
+	const uploadsPrefix = "/static/uploads/"
+	if !strings.HasPrefix(photo, uploadsPrefix) {
+		return
+	}
+	path := filepath.Join("static", "uploads", filepath.Base(photo))
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Erreur suppression photo %s: %v", path, err)
+	}
+}
+
+// RunAccountPurge supprime périodiquement les comptes arrivés à échéance
+func RunAccountPurge(db *sql.DB) {
+	for {
+		if _, err := PurgeDeletedAccounts(db, time.Now()); err != nil {
+			log.Printf("Erreur purge des comptes supprimés: %v", err)
+		}
+		time.Sleep(AccountPurgeInterval)
+	}
+}
+
+func notifyAccountDeletion(user User, deleteAt time.Time) {
+	body := fmt.Sprintf(`Bonjour %s,
+
+La suppression de votre compte Groupie Tracker a été demandée. Elle deviendra définitive le %s.
+
+Jusqu'à cette date, vous pouvez l'annuler depuis votre profil :
+
+%s/profile#privacy
+
+Vos favoris, sessions et données de profil seront alors effacés ; vos commentaires resteront visibles sans votre nom.
+`, user.Username, deleteAt.Format("02/01/2006 à 15:04"), BaseURL)
+
+	if err := CurrentMailer().Send(Mail{To: user.Email, Subject: "Suppression de votre compte programmée", Body: body}); err != nil {
+		log.Printf("Erreur envoi notification suppression à l'utilisateur %d: %v", user.ID, err)
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// HandleExportMyData télécharge une archive ZIP contenant data.json et la
+// photo de profil téléversée
+func (s *Server) HandleExportMyData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := ExportUserData(DB, user, func(id int) string {
+		art, _ := s.FindArtist(id)
+		return art.Name
+	})
+	if err != nil {
+		log.Printf("Erreur export données utilisateur %d: %v", user.ID, err)
+		http.Error(w, "Erreur lors de l'export de vos données", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("groupietracker-%s-%s.zip", user.Username, export.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("data.json")
+	if err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(export)
+	}
+	if err == nil {
+		err = addPhotoToArchive(zw, export.Profile.PhotoProfil)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		log.Printf("Erreur écriture archive utilisateur %d: %v", user.ID, err)
+	}
+}
+
+func addPhotoToArchive(zw *zip.Writer, photo string) error {
+	if !strings.HasPrefix(photo, "/static/uploads/") {
+		return nil
+	}
+	src, err := os.Open(filepath.Join("static", "uploads", filepath.Base(photo)))
+	if err != nil {
+		return nil
+	}
+	defer src.Close()
+	dst, err := zw.Create("photo/" + filepath.Base(photo))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func (s *Server) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	deleteAt, err := ScheduleAccountDeletion(DB, user, r.FormValue("confirmation"))
+	if err != nil {
+		notice := err.Error()
+		if !errors.Is(err, ErrWrongPassword) && !errors.Is(err, ErrDeletionNotConfirmed) {
+			log.Printf("Erreur demande suppression utilisateur %d: %v", user.ID, err)
+			notice = "Une erreur est survenue, veuillez réessayer"
+		}
+		profileNotice(w, r, notice, "#privacy")
+		return
+	}
+	log.Printf("Suppression du compte %d programmée pour le %s", user.ID, deleteAt.Format(time.RFC3339))
+	Audit(r, AuditEvent{Action: AuditDeletionRequest, TargetType: "user", TargetID: strconv.Itoa(user.ID), After: deleteAt.Format(time.RFC3339)})
+	go notifyAccountDeletion(user, deleteAt)
+	profileNotice(w, r, "Votre compte sera supprimé le "+deleteAt.Format("02/01/2006")+". Vous pouvez annuler d'ici là.", "#privacy")
+}
+
+func (s *Server) HandleCancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	if err := CancelAccountDeletion(DB, user.ID); err != nil {
+		log.Printf("Erreur annulation suppression utilisateur %d: %v", user.ID, err)
+		profileNotice(w, r, "Une erreur est survenue, veuillez réessayer", "#privacy")
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditDeletionCancel, TargetType: "user", TargetID: strconv.Itoa(user.ID)})
+	profileNotice(w, r, "La suppression de votre compte a été annulée", "#privacy")
+}
@@ -0,0 +1,207 @@
+package src
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy décrit un seau à jetons : Burst requêtes d'avance,
+// rechargées au rythme de Rate requêtes par seconde
+type RateLimitPolicy struct {
+	Name       string
+	Rate       float64
+	Burst      int
+	WritesOnly bool // n'applique la limite qu'aux méthodes non sûres (POST...)
+	ByIP       bool // toujours par IP : routes d'authentification, que ni jeton ni session ne doivent contourner
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore conserve l'état des seaux. L'implémentation mémoire suffit
+// pour une instance ; une implémentation partagée (Redis...) peut la remplacer
+// via SetRateLimitStore quand plusieurs instances tournent derrière un proxy.
+type RateLimitStore interface {
+	Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+var (
+	rateLimitStore   RateLimitStore = NewMemoryRateLimitStore()
+	rateLimitStoreMu sync.RWMutex
+)
+
+func SetRateLimitStore(store RateLimitStore) {
+	rateLimitStoreMu.Lock()
+	defer rateLimitStoreMu.Unlock()
+	rateLimitStore = store
+}
+
+func currentRateLimitStore() RateLimitStore {
+	rateLimitStoreMu.RLock()
+	defer rateLimitStoreMu.RUnlock()
+	return rateLimitStore
+}
+
+// ─── Stockage mémoire ────────────────────────────────────────
+
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	capacity float64
+	rate     float64
+}
+
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	calls   int
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (m *MemoryRateLimitStore) Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls%1000 == 0 {
+		m.prune(now)
+	}
+
+	capacity := float64(policy.Burst)
+	b, exists := m.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: capacity, updated: now, capacity: capacity, rate: policy.Rate}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*policy.Rate)
+	b.updated = now
+
+	result := RateLimitResult{Limit: policy.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / policy.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = secondsToDuration((capacity - b.tokens) / policy.Rate)
+	return result, nil
+}
+
+// prune supprime les seaux redevenus pleins, inutiles à conserver
+func (m *MemoryRateLimitStore) prune(now time.Time) {
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.rate >= b.capacity {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// ─── Middleware ──────────────────────────────────────────────
+
+// RateLimit applique la politique nommée (voir RateLimitPolicies) à la route
+func RateLimit(policyName string, next http.HandlerFunc) http.HandlerFunc {
+	policy, ok := RateLimitPolicies[policyName]
+	if !ok {
+		panic(fmt.Sprintf("politique de limitation inconnue: %s", policyName))
+	}
+	policy.Name = policyName
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if policy.WritesOnly && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := "ip:" + ClientIP(r)
+		if !policy.ByIP {
+			key = RateLimitKey(r)
+		}
+		result, err := currentRateLimitStore().Take(policy.Name+":"+key, policy, time.Now())
+		if err != nil {
+			// En cas de panne du stockage partagé, on laisse passer plutôt que de bloquer le site
+			log.Printf("Erreur limitation de débit (%s): %v", policy.Name, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+		if !result.Allowed {
+			retry := int(math.Ceil(result.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			log.Printf("Limite %s atteinte pour %s", policy.Name, key)
+			message := fmt.Sprintf("Trop de requêtes, réessayez dans %d s", retry)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				WriteAPIError(w, http.StatusTooManyRequests, "rate_limited", message)
+				return
+			}
+			http.Error(w, message, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// RateLimitKey identifie le client : jeton d'API valide, sinon utilisateur
+// connecté, sinon IP. Un en-tête Bearer invalide retombe sur l'IP : chaque
+// valeur inventée ouvrirait sinon un seau neuf.
+func RateLimitKey(r *http.Request) string {
+	if token, ok := CurrentAPIToken(r); ok {
+		return "token:" + strconv.Itoa(token.ID)
+	}
+	if raw, ok := BearerToken(r); ok {
+		if token, err := GetAPITokenByValue(DB, raw); err == nil {
+			return "token:" + strconv.Itoa(token.ID)
+		}
+		return "ip:" + ClientIP(r)
+	}
+	if userID, ok := CurrentUserID(r); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// ClientIP renvoie l'adresse du client. Derrière TrustedProxyHops proxys de
+// confiance, chacun ajoute à droite de X-Forwarded-For l'adresse qui l'a
+// contacté : seule l'entrée posée par le premier d'entre eux est fiable, celles
+// qui la précèdent viennent du client. Une entrée illisible est ignorée.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if TrustedProxyHops <= 0 {
+		return host
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if i := len(hops) - TrustedProxyHops; i >= 0 {
+		if ip := net.ParseIP(strings.TrimSpace(hops[i])); ip != nil {
+			return ip.String()
+		}
+	}
+	return host
+}
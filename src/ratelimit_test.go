@@ -0,0 +1,168 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	policy := RateLimitPolicy{Name: "test", Rate: 1, Burst: 2}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Take("k", policy, now); !res.Allowed {
+			t.Fatalf("requête %d refusée dans la rafale", i+1)
+		}
+	}
+	res, _ := store.Take("k", policy, now)
+	if res.Allowed {
+		t.Fatal("requête acceptée au-delà de la rafale")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, attendu 1s", res.RetryAfter)
+	}
+	if res, _ := store.Take("autre", policy, now); !res.Allowed {
+		t.Error("les seaux de clés différentes ne sont pas indépendants")
+	}
+	if res, _ := store.Take("k", policy, now.Add(time.Second)); !res.Allowed {
+		t.Error("le seau ne s'est pas rechargé")
+	}
+}
+
+// withTestPolicy enregistre une politique et un stockage neufs pour le test
+func withTestPolicy(t *testing.T, policy RateLimitPolicy) string {
+	t.Helper()
+	name := "test-" + t.Name()
+	RateLimitPolicies[name] = policy
+	SetRateLimitStore(NewMemoryRateLimitStore())
+	t.Cleanup(func() {
+		delete(RateLimitPolicies, name)
+		SetRateLimitStore(NewMemoryRateLimitStore())
+	})
+	return name
+}
+
+func rateLimitedStatus(handler http.Handler, mutate func(*http.Request)) int {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "203.0.113.7:4242"
+	if mutate != nil {
+		mutate(req)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRateLimitIgnoresInvalidBearerTokens(t *testing.T) {
+	name := withTestPolicy(t, RateLimitPolicy{Rate: 0.001, Burst: 3})
+	handler := RateLimit(name, func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 5; i++ {
+		status := rateLimitedStatus(handler, func(r *http.Request) {
+			r.Header.Set("Authorization", fmt.Sprintf("Bearer aleatoire-%d", i))
+		})
+		want := http.StatusOK
+		if i >= 3 {
+			want = http.StatusTooManyRequests
+		}
+		if status != want {
+			t.Fatalf("requête %d: statut %d, attendu %d", i+1, status, want)
+		}
+	}
+}
+
+func TestRateLimitByIPPolicy(t *testing.T) {
+	name := withTestPolicy(t, RateLimitPolicy{Rate: 0.001, Burst: 1, ByIP: true})
+	handler := RateLimit(name, func(w http.ResponseWriter, r *http.Request) {})
+
+	withToken := func(id int) func(*http.Request) {
+		return func(r *http.Request) {
+			ctx := context.WithValue(r.Context(), ctxAPIToken, APIToken{ID: id})
+			*r = *r.WithContext(ctx)
+		}
+	}
+	if status := rateLimitedStatus(handler, withToken(1)); status != http.StatusOK {
+		t.Fatalf("première requête: statut %d", status)
+	}
+	if status := rateLimitedStatus(handler, withToken(2)); status != http.StatusTooManyRequests {
+		t.Fatalf("un autre jeton contourne la limite par IP: statut %d", status)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	if got := RateLimitKey(req); got != "ip:198.51.100.1" {
+		t.Errorf("anonyme: %q", got)
+	}
+
+	req.Header.Set("Authorization", "Bearer pas-un-jeton")
+	if got := RateLimitKey(req); got != "ip:198.51.100.1" {
+		t.Errorf("jeton invalide: %q, attendu la clé IP", got)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), ctxAPIToken, APIToken{ID: 42}))
+	if got := RateLimitKey(req); got != "token:42" {
+		t.Errorf("jeton authentifié: %q", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	defer func(hops int) { TrustedProxyHops = hops }(TrustedProxyHops)
+	tests := []struct {
+		name      string
+		hops      int
+		forwarded []string
+		want      string
+	}{
+		{"sans proxy de confiance", 0, []string{"1.2.3.4"}, "192.0.2.10"},
+		{"entrée posée par le proxy", 1, []string{"198.51.100.7"}, "198.51.100.7"},
+		{"entrée forgée par le client", 1, []string{"1.2.3.4, 198.51.100.7"}, "198.51.100.7"},
+		{"en-têtes multiples", 1, []string{"1.2.3.4", "198.51.100.7"}, "198.51.100.7"},
+		{"deux proxys", 2, []string{"1.2.3.4, 198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"IPv6", 1, []string{"2001:db8::1"}, "2001:db8::1"},
+		{"moins d'entrées que de proxys", 2, []string{"198.51.100.7"}, "192.0.2.10"},
+		{"entrée illisible", 1, []string{"1.2.3.4, pas-une-ip"}, "192.0.2.10"},
+		{"entrée trop longue", 1, []string{strings.Repeat("1", 100)}, "192.0.2.10"},
+		{"sans en-tête", 1, nil, "192.0.2.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TrustedProxyHops = tt.hops
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.10:5555"
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP = %q, attendu %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	defer func(hops int) { TrustedProxyHops = hops }(TrustedProxyHops)
+	TrustedProxyHops = 1
+	name := withTestPolicy(t, RateLimitPolicy{Rate: 0.001, Burst: 2, ByIP: true})
+	handler := RateLimit(name, func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 3; i++ {
+		status := rateLimitedStatus(handler, func(r *http.Request) {
+			r.Header.Set("X-Forwarded-For", fmt.Sprintf("1.2.3.%d, 198.51.100.7", i))
+		})
+		want := http.StatusOK
+		if i >= 2 {
+			want = http.StatusTooManyRequests
+		}
+		if status != want {
+			t.Fatalf("requête %d: statut %d, attendu %d", i+1, status, want)
+		}
+	}
+}
@@ -0,0 +1,567 @@
+package src
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // dispose de toutes les permissions
+
+	PermManageUsers    = "users.manage"
+	PermManageRoles    = "roles.manage"
+	PermDeleteComments = "comments.delete"
+	PermViewOrders     = "orders.view"
+	PermRefreshData    = "data.refresh"
+	PermViewAudit      = "audit.view"
+	PermImpersonate    = "users.impersonate"
+	PermViewDashboard  = "dashboard.view"
+	PermEditArtists    = "artists.edit"
+)
+
+var (
+	ErrBuiltinRole       = errors.New("ce rôle est prédéfini et ne peut pas être modifié")
+	ErrRoleEscalation    = errors.New("ce rôle accorde des permissions que vous n'avez pas")
+	ErrAdminRoleReserved = errors.New("seul un administrateur peut attribuer, retirer ou supprimer le rôle admin")
+)
+
+// Permission est un droit attribuable à un rôle depuis /admin/roles
+type Permission struct {
+	Name  string
+	Label string
+}
+
+var Permissions = []Permission{
+	{Name: PermManageUsers, Label: "Gérer les utilisateurs (rôles, déverrouillage, suppression)"},
+	{Name: PermManageRoles, Label: "Gérer les rôles et leurs permissions"},
+	{Name: PermDeleteComments, Label: "Supprimer les commentaires des autres utilisateurs"},
+	{Name: PermViewOrders, Label: "Consulter les commandes"},
+	{Name: PermRefreshData, Label: "Actualiser les données depuis l'API"},
+	{Name: PermViewAudit, Label: "Consulter et exporter le journal d'audit"},
+	{Name: PermImpersonate, Label: "Voir le site en tant qu'un utilisateur (support)"},
+	{Name: PermViewDashboard, Label: "Consulter le tableau de bord et les statistiques"},
+	{Name: PermEditArtists, Label: "Corriger les données des artistes"},
+}
+
+// Role regroupe des permissions ; users.role contient son nom
+type Role struct {
+	Name        string
+	Label       string
+	Builtin     bool // user et admin ne peuvent être ni modifiés ni supprimés
+	Permissions []string
+	Users       int
+}
+
+func (r Role) Has(perm string) bool {
+	if r.Name == RoleAdmin {
+		return true
+	}
+	for _, p := range r.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Rôles créés à la première migration, modifiables ensuite sauf user et admin
+var defaultRoles = []Role{
+	{Name: RoleUser, Label: "Utilisateur", Builtin: true},
+	{Name: RoleAdmin, Label: "Administrateur", Builtin: true},
+	{Name: "moderator", Label: "Modérateur", Permissions: []string{PermDeleteComments}},
+	{Name: "support", Label: "Support", Permissions: []string{PermViewOrders}},
+	{Name: "data-admin", Label: "Administrateur des données", Permissions: []string{PermRefreshData}},
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{1,19}$`)
+
+func validPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions des rôles gardées en mémoire : elles sont lues à chaque
+// requête et ne changent que depuis /admin/roles
+var permissionCache struct {
+	sync.RWMutex
+	byRole map[string]map[string]bool
+}
+
+func invalidatePermissions() {
+	permissionCache.Lock()
+	permissionCache.byRole = nil
+	permissionCache.Unlock()
+}
+
+func rolePermissions() (map[string]map[string]bool, error) {
+	permissionCache.RLock()
+	byRole := permissionCache.byRole
+	permissionCache.RUnlock()
+	if byRole != nil {
+		return byRole, nil
+	}
+
+	rows, err := DB.Query("SELECT role, permission FROM role_permissions")
+	if err != nil {
+		return nil, fmt.Errorf("lecture permissions: %w", err)
+	}
+	defer rows.Close()
+
+	byRole = make(map[string]map[string]bool)
+	for rows.Next() {
+		var role, perm string
+		if err := rows.Scan(&role, &perm); err != nil {
+			return nil, fmt.Errorf("scan permission: %w", err)
+		}
+		if byRole[role] == nil {
+			byRole[role] = make(map[string]bool)
+		}
+		byRole[role][perm] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lecture permissions: %w", err)
+	}
+
+	permissionCache.Lock()
+	permissionCache.byRole = byRole
+	permissionCache.Unlock()
+	return byRole, nil
+}
+
+// RoleHasPermission indique si le rôle accorde la permission
+func RoleHasPermission(role, perm string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	byRole, err := rolePermissions()
+	if err != nil {
+		log.Printf("Erreur chargement permissions: %v", err)
+		return false
+	}
+	return byRole[role][perm]
+}
+
+// IsStaffRole indique si le rôle donne accès à une fonction d'administration
+func IsStaffRole(role string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	byRole, err := rolePermissions()
+	if err != nil {
+		log.Printf("Erreur chargement permissions: %v", err)
+		return false
+	}
+	return len(byRole[role]) > 0
+}
+
+// CurrentUserRole renvoie le rôle de l'utilisateur de la requête. Celui de la
+// session suffit : un changement de rôle ferme les sessions de l'utilisateur.
+func CurrentUserRole(r *http.Request) string {
+	if _, ok := CurrentAPIToken(r); !ok {
+		if session, err := GetSession(r); err == nil {
+			if role, ok := session.Values["role"].(string); ok && role != "" {
+				return role
+			}
+		}
+	}
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		return ""
+	}
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		return ""
+	}
+	return user.Role
+}
+
+// HasPermission indique si l'utilisateur de la requête dispose de la permission
+func HasPermission(r *http.Request, perm string) bool {
+	role := CurrentUserRole(r)
+	return role != "" && RoleHasPermission(role, perm)
+}
+
+// checkRoleChange vérifie qu'un utilisateur de rôle actor peut faire passer
+// un compte du rôle from au rôle to (to vide : suppression du compte). Seul
+// admin touche au rôle admin ; les autres rôles ne manipulent que des rôles
+// dont les permissions sont incluses dans les leurs.
+func checkRoleChange(byRole map[string]map[string]bool, actor, from, to string) error {
+	if actor == RoleAdmin {
+		return nil
+	}
+	if from == RoleAdmin || to == RoleAdmin {
+		return ErrAdminRoleReserved
+	}
+	for _, role := range []string{from, to} {
+		for perm := range byRole[role] {
+			if !byRole[actor][perm] {
+				return ErrRoleEscalation
+			}
+		}
+	}
+	return nil
+}
+
+// CanChangeRole applique checkRoleChange aux permissions enregistrées
+func CanChangeRole(actor, from, to string) error {
+	byRole, err := rolePermissions()
+	if err != nil {
+		return err
+	}
+	return checkRoleChange(byRole, actor, from, to)
+}
+
+// checkRoleEdit vérifie qu'un utilisateur de rôle actor peut donner au rôle
+// name les permissions perms (nil : suppression du rôle). Hors admin, le rôle
+// ne doit pas déjà dépasser les permissions de l'acteur ni en recevoir une
+// qu'il n'a pas : on ne s'attribue pas de droits en modifiant son propre rôle.
+func checkRoleEdit(byRole map[string]map[string]bool, actor, name string, perms []string) error {
+	if actor == RoleAdmin {
+		return nil
+	}
+	for perm := range byRole[name] {
+		if !byRole[actor][perm] {
+			return ErrRoleEscalation
+		}
+	}
+	for _, perm := range perms {
+		if !byRole[actor][perm] {
+			return ErrRoleEscalation
+		}
+	}
+	return nil
+}
+
+// CanEditRole applique checkRoleEdit aux permissions enregistrées
+func CanEditRole(actor, name string, perms []string) error {
+	byRole, err := rolePermissions()
+	if err != nil {
+		return err
+	}
+	return checkRoleEdit(byRole, actor, name, perms)
+}
+
+// roleChangeDenied indique si l'erreur de CanChangeRole est un refus et non
+// un incident technique
+func roleChangeDenied(err error) bool {
+	return errors.Is(err, ErrRoleEscalation) || errors.Is(err, ErrAdminRoleReserved)
+}
+
+// RequirePermission remplace RequireAdmin : l'accès dépend d'une permission
+// du rôle et non plus du seul rôle admin
+func RequirePermission(perm string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsAuthenticated(r) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if enforceSanction(w, r) {
+			return
+		}
+		if !HasPermission(r, perm) {
+			http.Error(w, "Accès refusé: permission requise", http.StatusForbidden)
+			return
+		}
+		if RequireAdmin2FA && !adminHasTwoFactor(r) {
+			session, err := GetSession(r)
+			if err == nil {
+				session.AddFlash("Activez la double authentification pour accéder à l'administration", profileNoticeKey)
+				_ = SaveSession(w, r, session)
+			}
+			http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// SeedRoles crée les rôles par défaut absents, sans toucher aux permissions
+// des rôles existants
+func SeedRoles(db *sql.DB) error {
+	for _, role := range defaultRoles {
+		res, err := db.Exec("INSERT IGNORE INTO roles (name, label, builtin) VALUES (?, ?, ?)", role.Name, role.Label, role.Builtin)
+		if err != nil {
+			return fmt.Errorf("création rôle %s: %w", role.Name, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		for _, perm := range role.Permissions {
+			if _, err := db.Exec("INSERT IGNORE INTO role_permissions (role, permission) VALUES (?, ?)", role.Name, perm); err != nil {
+				return fmt.Errorf("permission rôle %s: %w", role.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetRoles liste les rôles avec leurs permissions et leur nombre d'utilisateurs
+func GetRoles(db *sql.DB) ([]Role, error) {
+	const query = `SELECT r.name, r.label, r.builtin, COUNT(u.id)
+FROM roles r LEFT JOIN users u ON u.role = r.name
+GROUP BY r.name, r.label, r.builtin
+ORDER BY r.builtin DESC, r.name`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("lecture rôles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.Name, &role.Label, &role.Builtin, &role.Users); err != nil {
+			return nil, fmt.Errorf("scan rôle: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lecture rôles: %w", err)
+	}
+
+	byRole, err := rolePermissions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		for _, p := range Permissions {
+			if roles[i].Name == RoleAdmin || byRole[roles[i].Name][p.Name] {
+				roles[i].Permissions = append(roles[i].Permissions, p.Name)
+			}
+		}
+	}
+	return roles, nil
+}
+
+func RoleExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM roles WHERE name = ?", name).Scan(&count); err != nil {
+		return false, fmt.Errorf("vérification rôle: %w", err)
+	}
+	return count > 0, nil
+}
+
+func CreateRole(db *sql.DB, name, label string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	label = strings.TrimSpace(label)
+	if !roleNamePattern.MatchString(name) {
+		return errors.New("nom de rôle invalide : 2 à 20 caractères, lettres minuscules, chiffres et tirets")
+	}
+	if label == "" {
+		label = name
+	}
+	if exists, err := RoleExists(db, name); err != nil {
+		return err
+	} else if exists {
+		return errors.New("ce rôle existe déjà")
+	}
+	if _, err := db.Exec("INSERT INTO roles (name, label, builtin) VALUES (?, ?, FALSE)", name, label); err != nil {
+		return fmt.Errorf("création rôle: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole remplace le libellé et les permissions d'un rôle, dans la limite
+// des permissions du rôle actor de l'auteur de la modification
+func UpdateRole(db *sql.DB, actor, name, label string, perms []string) error {
+	var builtin bool
+	if err := db.QueryRow("SELECT builtin FROM roles WHERE name = ?", name).Scan(&builtin); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("rôle introuvable")
+		}
+		return fmt.Errorf("lecture rôle: %w", err)
+	}
+	if builtin {
+		return ErrBuiltinRole
+	}
+	for _, perm := range perms {
+		if !validPermission(perm) {
+			return fmt.Errorf("permission inconnue: %s", perm)
+		}
+	}
+	if err := CanEditRole(actor, name, perms); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction rôle: %w", err)
+	}
+	defer tx.Rollback()
+
+	if label = strings.TrimSpace(label); label != "" {
+		if _, err := tx.Exec("UPDATE roles SET label = ? WHERE name = ?", label, name); err != nil {
+			return fmt.Errorf("mise à jour rôle: %w", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role = ?", name); err != nil {
+		return fmt.Errorf("mise à jour permissions: %w", err)
+	}
+	for _, perm := range perms {
+		if _, err := tx.Exec("INSERT IGNORE INTO role_permissions (role, permission) VALUES (?, ?)", name, perm); err != nil {
+			return fmt.Errorf("mise à jour permissions: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("validation rôle: %w", err)
+	}
+	invalidatePermissions()
+	return nil
+}
+
+// DeleteRole supprime un rôle ; ses utilisateurs redeviennent simples
+// utilisateurs. Comme un retrait de rôle, elle est réservée aux acteurs dont
+// le rôle couvre toutes les permissions du rôle supprimé.
+func DeleteRole(db *sql.DB, actor, name string) error {
+	var builtin bool
+	if err := db.QueryRow("SELECT builtin FROM roles WHERE name = ?", name).Scan(&builtin); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("rôle introuvable")
+		}
+		return fmt.Errorf("lecture rôle: %w", err)
+	}
+	if builtin {
+		return ErrBuiltinRole
+	}
+	if err := CanEditRole(actor, name, nil); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("transaction rôle: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id IN (SELECT id FROM users WHERE role = ?)", name); err != nil {
+		return fmt.Errorf("révocation sessions: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE users SET role = ? WHERE role = ?", RoleUser, name); err != nil {
+		return fmt.Errorf("réattribution rôle: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM roles WHERE name = ?", name); err != nil {
+		return fmt.Errorf("suppression rôle: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("validation suppression rôle: %w", err)
+	}
+	invalidatePermissions()
+	return nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleAdminRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	roles, err := GetRoles(DB)
+	if err != nil {
+		log.Printf("Erreur récupération rôles: %v", err)
+		http.Error(w, "Erreur lors de la récupération des rôles", http.StatusInternalServerError)
+		return
+	}
+
+	data := AdminRolesPageData{
+		Roles:       roles,
+		Permissions: Permissions,
+		User: &UserProfile{
+			ID:          admin.ID,
+			Username:    admin.Username,
+			Email:       admin.Email,
+			Pseudo:      getStringValue(admin.Pseudo),
+			PhotoProfil: getStringValue(admin.PhotoProfil),
+			Role:        admin.Role,
+		},
+	}
+	if flashes := session.Flashes(profileNoticeKey); len(flashes) > 0 {
+		data.Notice, _ = flashes[0].(string)
+		_ = SaveSession(w, r, session)
+	}
+	s.Render(w, r, "admin-roles.html", data)
+}
+
+func adminRolesNotice(w http.ResponseWriter, r *http.Request, notice string) {
+	if session, err := GetSession(r); err == nil {
+		session.AddFlash(notice, profileNoticeKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/admin/roles", http.StatusSeeOther)
+}
+
+func (s *Server) HandleAdminCreateRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(r.FormValue("name")))
+	if err := CreateRole(DB, name, r.FormValue("label")); err != nil {
+		adminRolesNotice(w, r, err.Error())
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditRoleCreate, TargetType: "role", TargetID: name, After: r.FormValue("label")})
+	adminRolesNotice(w, r, "Rôle créé")
+}
+
+func (s *Server) HandleAdminUpdateRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête invalide", http.StatusBadRequest)
+		return
+	}
+	name := r.FormValue("role")
+	var before string
+	if byRole, err := rolePermissions(); err == nil {
+		for _, p := range Permissions {
+			if byRole[name][p.Name] {
+				before += p.Name + ","
+			}
+		}
+	}
+	if err := UpdateRole(DB, CurrentUserRole(r), name, r.FormValue("label"), r.Form["permissions"]); err != nil {
+		adminRolesNotice(w, r, err.Error())
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditRoleUpdate, TargetType: "role", TargetID: name, Before: strings.TrimSuffix(before, ","), After: strings.Join(r.Form["permissions"], ",")})
+	adminRolesNotice(w, r, "Rôle « "+name+" » mis à jour")
+}
+
+func (s *Server) HandleAdminDeleteRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.FormValue("role")
+	if err := DeleteRole(DB, CurrentUserRole(r), name); err != nil {
+		adminRolesNotice(w, r, err.Error())
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditRoleDelete, TargetType: "role", TargetID: name})
+	adminRolesNotice(w, r, "Rôle « "+name+" » supprimé")
+}
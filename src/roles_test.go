@@ -0,0 +1,77 @@
+package src
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckRoleChange(t *testing.T) {
+	byRole := map[string]map[string]bool{
+		"manager":   {PermManageUsers: true, PermDeleteComments: true},
+		"moderator": {PermDeleteComments: true},
+		"support":   {PermViewOrders: true},
+		"superuser": {PermManageUsers: true, PermManageRoles: true},
+	}
+	tests := []struct {
+		name            string
+		actor, from, to string
+		want            error
+	}{
+		{"admin nomme un admin", RoleAdmin, RoleUser, RoleAdmin, nil},
+		{"admin retire admin", RoleAdmin, RoleAdmin, RoleUser, nil},
+		{"admin supprime un admin", RoleAdmin, RoleAdmin, "", nil},
+		{"sous-ensemble de permissions", "manager", RoleUser, "moderator", nil},
+		{"même rôle que l'acteur", "manager", "moderator", "manager", nil},
+		{"retour à user", "manager", "moderator", RoleUser, nil},
+		{"suppression d'un modérateur", "manager", "moderator", "", nil},
+		{"nomme un admin", "manager", RoleUser, RoleAdmin, ErrAdminRoleReserved},
+		{"retire admin", "manager", RoleAdmin, RoleUser, ErrAdminRoleReserved},
+		{"supprime un admin", "manager", RoleAdmin, "", ErrAdminRoleReserved},
+		{"permission absente", "manager", RoleUser, "support", ErrRoleEscalation},
+		{"rôle plus large", "manager", RoleUser, "superuser", ErrRoleEscalation},
+		{"rétrograde un rôle plus large", "manager", "superuser", RoleUser, ErrRoleEscalation},
+		{"supprime un rôle plus large", "manager", "superuser", "", ErrRoleEscalation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRoleChange(byRole, tt.actor, tt.from, tt.to)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("checkRoleChange(%s, %s → %s) = %v, attendu %v", tt.actor, tt.from, tt.to, err, tt.want)
+			}
+			if err != nil && !roleChangeDenied(err) {
+				t.Errorf("refus non reconnu: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckRoleEdit(t *testing.T) {
+	byRole := map[string]map[string]bool{
+		"role-admin": {PermManageRoles: true, PermDeleteComments: true},
+		"moderator":  {PermDeleteComments: true},
+		"support":    {PermViewOrders: true},
+	}
+	tests := []struct {
+		name        string
+		actor, role string
+		perms       []string
+		want        error
+	}{
+		{"admin accorde tout", RoleAdmin, "moderator", []string{PermManageUsers, PermRefreshData}, nil},
+		{"sous-ensemble", "role-admin", "moderator", []string{PermDeleteComments}, nil},
+		{"retrait de permissions", "role-admin", "moderator", nil, nil},
+		{"retrait sur son propre rôle", "role-admin", "role-admin", []string{PermManageRoles}, nil},
+		{"ajout à son propre rôle", "role-admin", "role-admin", []string{PermManageRoles, PermManageUsers}, ErrRoleEscalation},
+		{"permission absente", "role-admin", "moderator", []string{PermRefreshData}, ErrRoleEscalation},
+		{"rôle déjà plus large", "role-admin", "support", nil, ErrRoleEscalation},
+		{"rôle déjà plus large, permissions retirées", "role-admin", "support", []string{}, ErrRoleEscalation},
+		{"rôle inconnu", "role-admin", "nouveau", []string{PermDeleteComments}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRoleEdit(byRole, tt.actor, tt.role, tt.perms); err != tt.want {
+				t.Fatalf("checkRoleEdit(%s, %s, %v) = %v, attendu %v", tt.actor, tt.role, tt.perms, err, tt.want)
+			}
+		})
+	}
+}
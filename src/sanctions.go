@@ -0,0 +1,312 @@
+package src
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	sanctionFlashKey  = "sanction"
+	maxSanctionReason = 500
+	maxSuspensionDays = 365
+)
+
+var (
+	ErrSanctionReason = errors.New("indiquez le motif de la sanction (500 caractères maximum)")
+	ErrSanctionSelf   = errors.New("vous ne pouvez pas sanctionner votre propre compte")
+)
+
+// AccountSanction décrit la suspension (limitée dans le temps) ou le
+// bannissement (définitif) d'un compte
+type AccountSanction struct {
+	Banned bool
+	Until  time.Time // fin de la suspension, zéro pour un bannissement
+	Reason string
+}
+
+func (s AccountSanction) Message() string {
+	if s.Banned {
+		return "Votre compte a été banni"
+	}
+	return "Votre compte est suspendu jusqu'au " + s.Until.Format("02/01/2006 à 15:04")
+}
+
+// ActiveSanction renvoie la sanction en vigueur ; une suspension échue est levée
+func (u User) ActiveSanction(now time.Time) (AccountSanction, bool) {
+	reason := getStringValue(u.SanctionReason)
+	if u.BannedAt.Valid {
+		return AccountSanction{Banned: true, Reason: reason}, true
+	}
+	if u.SuspendedUntil.Valid && u.SuspendedUntil.Time.After(now) {
+		return AccountSanction{Until: u.SuspendedUntil.Time, Reason: reason}, true
+	}
+	return AccountSanction{}, false
+}
+
+func cleanSanctionReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxSanctionReason {
+		return "", ErrSanctionReason
+	}
+	return reason, nil
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// GetActiveSanction lit la sanction en vigueur, vérifiée à chaque requête authentifiée
+func GetActiveSanction(db *sql.DB, userID int, now time.Time) (AccountSanction, bool, error) {
+	var u User
+	const query = `SELECT suspended_until, banned_at, sanction_reason FROM users WHERE id = ?`
+	err := db.QueryRow(query, userID).Scan(&u.SuspendedUntil, &u.BannedAt, &u.SanctionReason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AccountSanction{}, false, nil
+	}
+	if err != nil {
+		return AccountSanction{}, false, fmt.Errorf("lecture sanction: %w", err)
+	}
+	sanction, ok := u.ActiveSanction(now)
+	return sanction, ok, nil
+}
+
+// SuspendUser suspend le compte jusqu'à until et ferme ses sessions
+func SuspendUser(db *sql.DB, userID int, until time.Time, reason string) error {
+	reason, err := cleanSanctionReason(reason)
+	if err != nil {
+		return err
+	}
+	const query = `UPDATE users SET suspended_until = ?, banned_at = NULL, sanction_reason = ? WHERE id = ?`
+	if _, err := db.Exec(query, until, reason, userID); err != nil {
+		return fmt.Errorf("suspension compte: %w", err)
+	}
+	return RevokeUserSessions(db, userID)
+}
+
+// BanUser bannit le compte sans limite de durée et ferme ses sessions
+func BanUser(db *sql.DB, userID int, reason string) error {
+	reason, err := cleanSanctionReason(reason)
+	if err != nil {
+		return err
+	}
+	const query = `UPDATE users SET banned_at = ?, suspended_until = NULL, sanction_reason = ? WHERE id = ?`
+	if _, err := db.Exec(query, time.Now(), reason, userID); err != nil {
+		return fmt.Errorf("bannissement compte: %w", err)
+	}
+	return RevokeUserSessions(db, userID)
+}
+
+// LiftSanction lève la suspension ou le bannissement du compte
+func LiftSanction(db *sql.DB, userID int) error {
+	const query = `UPDATE users SET suspended_until = NULL, banned_at = NULL, sanction_reason = NULL WHERE id = ?`
+	if _, err := db.Exec(query, userID); err != nil {
+		return fmt.Errorf("levée sanction: %w", err)
+	}
+	return nil
+}
+
+// LiftExpiredSuspensions efface les suspensions échues et renvoie les comptes concernés
+func LiftExpiredSuspensions(db *sql.DB, now time.Time) ([]int, error) {
+	rows, err := db.Query("SELECT id FROM users WHERE suspended_until <= ? AND banned_at IS NULL", now)
+	if err != nil {
+		return nil, fmt.Errorf("lecture suspensions échues: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan suspension: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lecture suspensions échues: %w", err)
+	}
+
+	var lifted []int
+	for _, id := range ids {
+		const query = `UPDATE users SET suspended_until = NULL, sanction_reason = NULL WHERE id = ? AND suspended_until <= ? AND banned_at IS NULL`
+		res, err := db.Exec(query, id, now)
+		if err != nil {
+			return lifted, fmt.Errorf("levée suspension %d: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			lifted = append(lifted, id)
+		}
+	}
+	return lifted, nil
+}
+
+func RunSuspensionExpiry(db *sql.DB) {
+	for {
+		lifted, err := LiftExpiredSuspensions(db, time.Now())
+		if err != nil {
+			log.Printf("Erreur levée des suspensions: %v", err)
+		}
+		for _, id := range lifted {
+			log.Printf("Suspension du compte %d levée à échéance", id)
+			Audit(nil, AuditEvent{ActorName: "système", Action: AuditSuspensionEnded, TargetType: "user", TargetID: strconv.Itoa(id)})
+		}
+		time.Sleep(SuspensionCheckInterval)
+	}
+}
+
+// ─── Middleware ──────────────────────────────────────────────
+
+// enforceSanction ferme la session d'un compte suspendu ou banni et renvoie
+// vers la page d'explication ; true si la requête a été interrompue
+func enforceSanction(w http.ResponseWriter, r *http.Request) bool {
+	// Le support doit pouvoir voir le site comme un compte sanctionné
+	if _, ok := CurrentImpersonation(r); ok {
+		return false
+	}
+	userID, ok := SessionUserID(r)
+	if !ok {
+		return false
+	}
+	sanction, found, err := GetActiveSanction(DB, userID, time.Now())
+	if err != nil {
+		log.Printf("Erreur vérification sanction utilisateur %d: %v", userID, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	if session, err := GetSession(r); err == nil {
+		session.Values = make(map[interface{}]interface{})
+		session.AddFlash([]string{strconv.FormatBool(sanction.Banned), strconv.FormatInt(sanction.Until.Unix(), 10), sanction.Reason}, sanctionFlashKey)
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/account/suspended", http.StatusSeeOther)
+	return true
+}
+
+func (s *Server) renderSanction(w http.ResponseWriter, r *http.Request, sanction AccountSanction) {
+	data := SanctionPageData{Banned: sanction.Banned, Message: sanction.Message(), Reason: sanction.Reason}
+	s.renderStatus(w, r, http.StatusForbidden, "account-suspended.html", data)
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// HandleAccountSuspended explique au titulaire pourquoi sa session a été fermée
+func (s *Server) HandleAccountSuspended(w http.ResponseWriter, r *http.Request) {
+	session, err := GetSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	flashes := session.Flashes(sanctionFlashKey)
+	if len(flashes) == 0 {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	_ = SaveSession(w, r, session)
+	values, ok := flashes[0].([]string)
+	if !ok || len(values) != 3 {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	banned, _ := strconv.ParseBool(values[0])
+	until, _ := strconv.ParseInt(values[1], 10, 64)
+	s.renderSanction(w, r, AccountSanction{Banned: banned, Until: time.Unix(until, 0), Reason: values[2]})
+}
+
+// sanctionTarget lit et contrôle l'utilisateur visé par une action de sanction
+func sanctionTarget(w http.ResponseWriter, r *http.Request) (User, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return User{}, false
+	}
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "ID utilisateur invalide", http.StatusBadRequest)
+		return User{}, false
+	}
+	if currentID, ok := SessionUserID(r); ok && currentID == userID {
+		http.Error(w, ErrSanctionSelf.Error(), http.StatusForbidden)
+		return User{}, false
+	}
+	target, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return User{}, false
+	}
+	// Une sanction ferme les sessions comme une suppression : mêmes limites
+	if err := CanChangeRole(CurrentUserRole(r), target.Role, ""); err != nil {
+		if !roleChangeDenied(err) {
+			log.Printf("Erreur vérification sanction utilisateur %d: %v", userID, err)
+			http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+			return User{}, false
+		}
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return User{}, false
+	}
+	return target, true
+}
+
+func (s *Server) HandleAdminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	target, ok := sanctionTarget(w, r)
+	if !ok {
+		return
+	}
+	days, err := strconv.Atoi(r.FormValue("days"))
+	if err != nil || days < 1 || days > maxSuspensionDays {
+		http.Error(w, "Durée de suspension invalide", http.StatusBadRequest)
+		return
+	}
+	until := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	if err := SuspendUser(DB, target.ID, until, r.FormValue("reason")); err != nil {
+		if errors.Is(err, ErrSanctionReason) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Erreur suspension utilisateur %d: %v", target.ID, err)
+		http.Error(w, "Erreur lors de la suspension", http.StatusInternalServerError)
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditUserSuspend, TargetType: "user", TargetID: strconv.Itoa(target.ID), After: until.Format(time.RFC3339) + " · " + strings.TrimSpace(r.FormValue("reason"))})
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+func (s *Server) HandleAdminBanUser(w http.ResponseWriter, r *http.Request) {
+	target, ok := sanctionTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := BanUser(DB, target.ID, r.FormValue("reason")); err != nil {
+		if errors.Is(err, ErrSanctionReason) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Erreur bannissement utilisateur %d: %v", target.ID, err)
+		http.Error(w, "Erreur lors du bannissement", http.StatusInternalServerError)
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditUserBan, TargetType: "user", TargetID: strconv.Itoa(target.ID), After: strings.TrimSpace(r.FormValue("reason"))})
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+func (s *Server) HandleAdminReinstateUser(w http.ResponseWriter, r *http.Request) {
+	target, ok := sanctionTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := LiftSanction(DB, target.ID); err != nil {
+		log.Printf("Erreur levée sanction utilisateur %d: %v", target.ID, err)
+		http.Error(w, "Erreur lors de la levée de la sanction", http.StatusInternalServerError)
+		return
+	}
+	before := "suspendu"
+	if target.BannedAt.Valid {
+		before = "banni"
+	}
+	Audit(r, AuditEvent{Action: AuditUserReinstate, TargetType: "user", TargetID: strconv.Itoa(target.ID), Before: before + " · " + getStringValue(target.SanctionReason)})
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
@@ -1,22 +1,24 @@
 package src
 
 import (
+	"bytes"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/sessions"
 )
 
 var (
-	store *sessions.CookieStore
+	store *DBStore
 )
 
 func init() {
-	store = sessions.NewCookieStore([]byte(SessionSecret))
+	store = NewDBStore([]byte(SessionSecret))
 	store.Options = &sessions.Options{
 		Path:     "/",
 		MaxAge:   SessionMaxAge,
@@ -30,7 +32,15 @@ type Server struct {
 	client    *http.Client
 	templates *template.Template
 	mu        sync.RWMutex
-	artists   []Artist
+	artists   []Artist // données de l'API et artistes locaux, corrigés par artist_overrides
+	upstream  []Artist // données de l'API telles quelles
+	local     []Artist // artistes créés sur le site (local_artists)
+
+	rebuildMu sync.Mutex // sérialise le recalcul de artists
+
+	// Dernière actualisation des données, pour le tableau de bord
+	refreshedAt time.Time
+	refreshErr  error
 }
 
 func NewServer() (*Server, error) {
@@ -40,6 +50,15 @@ func NewServer() (*Server, error) {
 		"joinMembers": func(members []string) string {
 			return strings.Join(members, ", ")
 		},
+		"join": strings.Join,
+		// Remplacées à chaque rendu par Render
+		"csrfToken":     func() string { return "" },
+		"csrfField":     func() template.HTML { return "" },
+		"can":           func(perm string) bool { return false },
+		"impersonation": func() *Impersonation { return nil },
+		"oidcProviders": func() []OIDCProviderConfig {
+			return OIDCProviders
+		},
 		"sub": func(a, b int) int {
 			return a - b
 		},
@@ -71,36 +90,115 @@ func NewServer() (*Server, error) {
 	return srv, nil
 }
 
-func (s *Server) Start() error {
+// Routes renvoie le routeur complet, middlewares globaux compris
+func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/", s.HandleRoot)
-	mux.HandleFunc("/login", s.HandleLogin)
-	mux.HandleFunc("/register", s.HandleRegister)
+	mux.HandleFunc("/login", RateLimit("login", s.HandleLogin))
+	mux.HandleFunc("/register", RateLimit("register", s.HandleRegister))
+	mux.HandleFunc("/login/2fa", RateLimit("login", s.HandleLogin2FA))
+	mux.HandleFunc("/auth/oidc/{provider}/login", RateLimit("login", s.HandleOIDCLogin))
+	mux.HandleFunc("/auth/oidc/{provider}/callback", RateLimit("login", s.HandleOIDCCallback))
+	mux.HandleFunc("/account/unlock", RateLimit("verify", s.HandleAccountUnlock))
+	mux.HandleFunc("/account/suspended", s.HandleAccountSuspended)
+	mux.HandleFunc("/password/forgot", RateLimit("password", s.HandleForgotPassword))
+	mux.HandleFunc("/password/reset", RateLimit("login", s.HandleResetPassword))
+	mux.HandleFunc("/email/verify", s.HandleVerifyEmail)
+	mux.HandleFunc("/email/verify/resend", RequireAuth(RateLimit("verify", s.HandleResendVerification)))
 	mux.HandleFunc("/home", RequireAuth(s.HandleIndex))
 	mux.HandleFunc("/profile", RequireAuth(s.HandleProfile))
 	mux.HandleFunc("/artist", RequireAuth(s.HandleArtist))
-	mux.HandleFunc("/api/favorite/toggle", RequireAuth(s.HandleToggleFavorite))
-	mux.HandleFunc("/api/comment/add", RequireAuth(s.HandleAddComment))
-	mux.HandleFunc("/api/comment/delete", RequireAuth(s.HandleDeleteComment))
-	mux.HandleFunc(RefreshPath, RequireAuth(s.HandleRefresh))
-	mux.HandleFunc("/api/geocode", RequireAuth(s.HandleGeocode))
-	mux.HandleFunc("/api/paypal/create-order", RequireAuth(s.HandleCreateOrder))
-	mux.HandleFunc("/api/paypal/capture-order", RequireAuth(s.HandleCaptureOrder))
+	mux.HandleFunc(ExportPath, RequireAuth(RateLimit("export", s.HandleExportArtists)))
+	mux.HandleFunc("/favorites", RequireAuth(s.HandleFavorites))
+	mux.HandleFunc("/favorites/reorder", RequireAuth(s.HandleReorderFavorites))
+	mux.HandleFunc("/favorites/note", RequireAuth(s.HandleFavoriteNote))
+	mux.HandleFunc("/api/favorite/toggle", RequireAuthOrToken(ScopeFavorites, s.HandleToggleFavorite))
+	mux.HandleFunc("/lists", RequireAuth(s.HandleLists))
+	mux.HandleFunc("/lists/create", RequireAuth(s.HandleCreateList))
+	mux.HandleFunc("/lists/update", RequireAuth(s.HandleUpdateList))
+	mux.HandleFunc("/lists/delete", RequireAuth(s.HandleDeleteList))
+	mux.HandleFunc("/lists/reorder", RequireAuth(s.HandleReorderLists))
+	mux.HandleFunc("/lists/items/reorder", RequireAuth(s.HandleReorderListItems))
+	mux.HandleFunc("GET /lists/share/{token}", s.HandleSharedList)
+	mux.HandleFunc("GET /lists/share/{token}/export", s.HandleExportList)
+	mux.HandleFunc("/api/list/toggle", RequireAuthOrToken(ScopeFavorites, s.HandleToggleListItem))
+	mux.HandleFunc("/api/comment/add", RequireAuthOrToken(ScopeComments, RequireVerified(RateLimit("comment", s.HandleAddComment))))
+	mux.HandleFunc("/api/comment/delete", RequireAuthOrToken(ScopeComments, s.HandleDeleteComment))
+	mux.HandleFunc(RefreshPath, RequirePermission(PermRefreshData, s.HandleRefresh))
+	mux.HandleFunc("/api/geocode", RequireAuth(RateLimit("geocode", s.HandleGeocode)))
+	mux.HandleFunc("/api/paypal/create-order", RequireAuth(RequireVerified(RateLimit("payment", s.HandleCreateOrder))))
+	mux.HandleFunc("/api/paypal/capture-order", RequireAuth(RequireVerified(s.HandleCaptureOrder)))
 	mux.HandleFunc("/paypal/success", RequireAuth(s.HandlePayPalSuccess))
 	mux.HandleFunc("/profile/update", RequireAuth(s.HandleUpdateProfile))
+	mux.HandleFunc("/profile/password", RequireAuth(RateLimit("login", s.HandleChangePassword)))
+	mux.HandleFunc("/profile/email", RequireAuth(RateLimit("verify", s.HandleChangeEmail)))
+	mux.HandleFunc("/profile/2fa/setup", RequireAuth(s.HandleTOTPSetup))
+	mux.HandleFunc("/profile/2fa/enable", RequireAuth(RateLimit("login", s.HandleTOTPEnable)))
+	mux.HandleFunc("/profile/2fa/disable", RequireAuth(RateLimit("login", s.HandleTOTPDisable)))
+	mux.HandleFunc("/profile/2fa/recovery-codes", RequireAuth(RateLimit("login", s.HandleRecoveryCodesRegenerate)))
+	mux.HandleFunc("/profile/sessions/revoke", RequireAuth(s.HandleRevokeSession))
+	mux.HandleFunc("/profile/sessions/revoke-all", RequireAuth(s.HandleLogoutEverywhere))
+	mux.HandleFunc("/profile/identities/unlink", RequireAuth(s.HandleUnlinkIdentity))
+	mux.HandleFunc("/profile/export", RequireAuth(RateLimit("export", s.HandleExportMyData)))
+	mux.HandleFunc("/profile/delete", RequireAuth(RateLimit("login", s.HandleDeleteAccount)))
+	mux.HandleFunc("/profile/delete/cancel", RequireAuth(s.HandleCancelAccountDeletion))
+	mux.HandleFunc("/profile/tokens/create", RequireAuth(s.HandleCreateAPIToken))
+	mux.HandleFunc("/profile/tokens/revoke", RequireAuth(s.HandleRevokeAPIToken))
 	mux.HandleFunc("/logout", s.HandleLogout)
-	mux.HandleFunc("/admin/users", RequireAdmin(s.HandleAdminUsers))
-	mux.HandleFunc("/admin/users/update-role", RequireAdmin(s.HandleAdminUpdateUserRole))
-	mux.HandleFunc("/admin/users/delete", RequireAdmin(s.HandleAdminDeleteUser))
+	mux.HandleFunc("/admin", RequirePermission(PermViewDashboard, s.HandleAdminDashboard))
+	mux.HandleFunc("GET /admin/stats/{chart}", RequirePermission(PermViewDashboard, s.HandleAdminStats))
+	mux.HandleFunc("/admin/users", RequirePermission(PermManageUsers, s.HandleAdminUsers))
+	mux.HandleFunc("/admin/users/update-role", RequirePermission(PermManageUsers, s.HandleAdminUpdateUserRole))
+	mux.HandleFunc("/admin/users/unlock", RequirePermission(PermManageUsers, s.HandleAdminUnlockUser))
+	mux.HandleFunc("/admin/users/delete", RequirePermission(PermManageUsers, s.HandleAdminDeleteUser))
+	mux.HandleFunc("/admin/users/bulk", RequirePermission(PermManageUsers, s.HandleAdminBulkUsers))
+	mux.HandleFunc("/admin/users/export", RequirePermission(PermManageUsers, RateLimit("export", s.HandleAdminUsersExport)))
+	mux.HandleFunc("/admin/users/suspend", RequirePermission(PermManageUsers, s.HandleAdminSuspendUser))
+	mux.HandleFunc("/admin/users/ban", RequirePermission(PermManageUsers, s.HandleAdminBanUser))
+	mux.HandleFunc("/admin/users/reinstate", RequirePermission(PermManageUsers, s.HandleAdminReinstateUser))
+	mux.HandleFunc("/admin/users/impersonate", RequirePermission(PermImpersonate, s.HandleAdminImpersonate))
+	mux.HandleFunc("/impersonate/stop", RequireAuth(s.HandleStopImpersonation))
+	mux.HandleFunc("/admin/roles", RequirePermission(PermManageRoles, s.HandleAdminRoles))
+	mux.HandleFunc("/admin/roles/create", RequirePermission(PermManageRoles, s.HandleAdminCreateRole))
+	mux.HandleFunc("/admin/roles/update", RequirePermission(PermManageRoles, s.HandleAdminUpdateRole))
+	mux.HandleFunc("/admin/roles/delete", RequirePermission(PermManageRoles, s.HandleAdminDeleteRole))
+	mux.HandleFunc("/admin/artists", RequirePermission(PermEditArtists, s.HandleAdminArtists))
+	mux.HandleFunc("/admin/artists/edit", RequirePermission(PermEditArtists, s.HandleAdminArtistEdit))
+	mux.HandleFunc("/admin/artists/save", RequirePermission(PermEditArtists, s.HandleAdminArtistSave))
+	mux.HandleFunc("/admin/artists/concert", RequirePermission(PermEditArtists, s.HandleAdminArtistConcert))
+	mux.HandleFunc("/admin/artists/revert", RequirePermission(PermEditArtists, s.HandleAdminArtistRevert))
+	mux.HandleFunc("/admin/artists/restore", RequirePermission(PermEditArtists, s.HandleAdminArtistRestore))
+	mux.HandleFunc("/admin/artists/local", RequirePermission(PermEditArtists, s.HandleAdminLocalArtist))
+	mux.HandleFunc("/admin/artists/local/save", RequirePermission(PermEditArtists, s.HandleAdminLocalArtistSave))
+	mux.HandleFunc("/admin/artists/local/delete", RequirePermission(PermEditArtists, s.HandleAdminLocalArtistDelete))
+	mux.HandleFunc("/admin/orders", RequirePermission(PermViewOrders, s.HandleAdminOrders))
+	mux.HandleFunc("/admin/audit", RequirePermission(PermViewAudit, s.HandleAdminAudit))
+	mux.HandleFunc("/admin/audit/export", RequirePermission(PermViewAudit, RateLimit("export", s.HandleAdminAuditExport)))
 	mux.HandleFunc("/legal/conditions", s.HandleLegalConditions)
 	mux.HandleFunc("/legal/privacy", s.HandleLegalPrivacy)
 	mux.HandleFunc("/legal/cookies", s.HandleLegalCookies)
 	mux.HandleFunc("/legal/mentions", s.HandleLegalMentions)
 
+	mux.HandleFunc("GET "+APIVersionPrefix+"/artists", RateLimit("api", s.HandleAPIArtists))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/artists/{id}", RateLimit("api", s.HandleAPIArtist))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/locations", RateLimit("api", s.HandleAPILocations))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/concerts", RateLimit("api", s.HandleAPIConcerts))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/members", RateLimit("api", s.HandleAPIMembers))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/search", RateLimit("api", s.HandleAPISearch))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/me", RequireAuthOrToken(ScopeRead, RateLimit("api", s.HandleAPIMe)))
+	mux.HandleFunc("GET "+APIVersionPrefix+"/me/favorites", RequireAuthOrToken(ScopeRead, RateLimit("api", s.HandleAPIMyFavorites)))
+	mux.HandleFunc(APIVersionPrefix+"/", s.HandleAPINotFound)
+	mux.HandleFunc("GET "+OpenAPIPath, s.HandleOpenAPI)
+	mux.HandleFunc("/graphql", RateLimit("api", s.HandleGraphQL))
+
 	fileServer := http.FileServer(http.Dir("static"))
 	mux.Handle(StaticPrefix, http.StripPrefix(StaticPrefix, fileServer))
 
+	return s.CSRFProtect(s.ImpersonationGuard(mux))
+}
+
+func (s *Server) Start() error {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -108,10 +206,13 @@ func (s *Server) Start() error {
 
 	server := &http.Server{
 		Addr:              ":" + port,
-		Handler:           mux,
+		Handler:           s.Routes(),
 		ReadHeaderTimeout: ReadHeaderTimeout,
 	}
 
+	go RunAccountPurge(DB)
+	go RunSuspensionExpiry(DB)
+
 	log.Printf("Serveur lancé sur le port %s", port)
 
 	return server.ListenAndServe()
@@ -119,15 +220,28 @@ func (s *Server) Start() error {
 
 func (s *Server) RefreshData() error {
 	artists, err := FetchArtistsData(s.client)
+	s.mu.Lock()
+	s.refreshedAt, s.refreshErr = time.Now(), err
+	if err == nil {
+		s.upstream = artists
+	}
+	s.mu.Unlock()
 	if err != nil {
 		return err
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.artists = artists
+	if err := s.RebuildArtists(); err != nil {
+		log.Printf("Erreur fusion des artistes locaux et des corrections: %v", err)
+	}
 	return nil
 }
 
+// RefreshStatus renvoie la date et l'erreur éventuelle de la dernière actualisation
+func (s *Server) RefreshStatus() (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshedAt, s.refreshErr
+}
+
 func (s *Server) ListArtists() []Artist {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -147,12 +261,57 @@ func (s *Server) FindArtist(id int) (Artist, bool) {
 	return Artist{}, false
 }
 
-func (s *Server) Render(w http.ResponseWriter, name string, data interface{}) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, name, data); err != nil {
+// Render exécute le template avec les fonctions liées à la requête
+// (jeton CSRF, permissions). La page est construite en mémoire pour que la session
+// puisse encore poser son cookie.
+func (s *Server) Render(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	s.renderStatus(w, r, http.StatusOK, name, data)
+}
+
+// RenderError affiche la page d'erreur avec le statut donné
+func (s *Server) RenderError(w http.ResponseWriter, r *http.Request, status int, title, message string) {
+	s.renderStatus(w, r, status, "error.html", ErrorPageData{Status: status, Title: title, Message: message})
+}
+
+func (s *Server) renderStatus(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) {
+	tmpl, err := s.templates.Clone()
+	if err != nil {
 		log.Printf("template %s failed: %v", name, err)
 		http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+		return
+	}
+	csrfToken := func() string {
+		token, err := CSRFToken(w, r)
+		if err != nil {
+			log.Printf("Erreur jeton CSRF: %v", err)
+		}
+		return token
 	}
+	tmpl.Funcs(template.FuncMap{
+		"csrfToken": csrfToken,
+		"csrfField": func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + csrfFieldName + `" value="` + template.HTMLEscapeString(csrfToken()) + `">`)
+		},
+		"can": func(perm string) bool {
+			return HasPermission(r, perm)
+		},
+		"impersonation": func() *Impersonation {
+			if imp, ok := CurrentImpersonation(r); ok {
+				return &imp
+			}
+			return nil
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Printf("template %s failed: %v", name, err)
+		http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
 }
 
 func fileExists(filename string) bool {
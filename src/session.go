@@ -2,6 +2,7 @@ package src
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/sessions"
 )
@@ -20,52 +21,60 @@ func SessionMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func IsAuthenticated(r *http.Request) bool {
+// StartUserSession ouvre la session de l'utilisateur après authentification
+func StartUserSession(w http.ResponseWriter, r *http.Request, user User) error {
 	session, err := GetSession(r)
 	if err != nil {
-		return false
+		return err
 	}
-	_, ok := session.Values["user_id"]
-	return ok
-}
-
-func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if !IsAuthenticated(r) {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
-			return
-		}
-		next.ServeHTTP(w, r)
+	if err := renewSession(session); err != nil {
+		return err
 	}
+	session.Values["user_id"] = user.ID
+	session.Values["email"] = user.Email
+	session.Values["username"] = user.Username
+	session.Values["role"] = user.Role
+	RecordUserActivity(DB, user.ID, time.Now())
+	return SaveSession(w, r, session)
 }
 
-func IsAdmin(r *http.Request) bool {
+// SessionUserID renvoie l'utilisateur de la session. Une session révoquée
+// (déconnexion à distance, changement de mot de passe...) n'existe plus en
+// base et se recharge vide.
+func SessionUserID(r *http.Request) (int, bool) {
 	session, err := GetSession(r)
 	if err != nil {
-		return false
+		return 0, false
 	}
 	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		return false
-	}
-	user, err := GetUserByID(DB, userID)
-	if err != nil {
-		return false
-	}
-	return user.Role == "admin"
+	return userID, ok
 }
 
-func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+func IsAuthenticated(r *http.Request) bool {
+	_, ok := SessionUserID(r)
+	return ok
+}
+
+func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !IsAuthenticated(r) {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
-		if !IsAdmin(r) {
-			http.Error(w, "Accès refusé: droits administrateur requis", http.StatusForbidden)
+		if enforceSanction(w, r) {
 			return
 		}
 		next.ServeHTTP(w, r)
 	}
 }
 
+// adminHasTwoFactor indique si l'utilisateur connecté a activé la 2FA,
+// exigée pour l'administration quand REQUIRE_ADMIN_2FA est actif
+func adminHasTwoFactor(r *http.Request) bool {
+	userID, ok := SessionUserID(r)
+	if !ok {
+		return false
+	}
+	user, err := GetUserByID(DB, userID)
+	return err == nil && user.TOTPEnabledAt.Valid
+}
@@ -0,0 +1,329 @@
+package src
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// Intervalle minimal entre deux mises à jour de last_seen_at d'une session
+const sessionTouchInterval = time.Minute
+
+func init() {
+	// Valeurs stockées dans les flashes (codes de secours...)
+	gob.Register([]string{})
+}
+
+// DeviceSession décrit une session ouverte, affichée dans « Vos appareils »
+type DeviceSession struct {
+	ID         int
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	Current    bool
+}
+
+// Device résume le user agent en « navigateur · système »
+func (d DeviceSession) Device() string {
+	ua := d.UserAgent
+	browser := "Navigateur inconnu"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			system = o.name
+			break
+		}
+	}
+	if system == "" {
+		return browser
+	}
+	return browser + " · " + system
+}
+
+// DBStore implémente sessions.Store : le cookie ne contient qu'un identifiant
+// aléatoire signé, les valeurs sont conservées dans la table sessions. On peut
+// ainsi lister et révoquer les sessions d'un utilisateur.
+type DBStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	saves atomic.Int64
+}
+
+func NewDBStore(keyPairs ...[]byte) *DBStore {
+	return &DBStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: SessionMaxAge,
+		},
+	}
+}
+
+// Get renvoie la session mise en cache pour la requête, ou la charge
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New charge la session désignée par le cookie ; une session absente,
+// expirée ou révoquée donne une nouvelle session vide
+func (s *DBStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...); err != nil {
+		return session, nil
+	}
+
+	var data []byte
+	var lastSeen time.Time
+	const query = `SELECT data, last_seen_at FROM sessions WHERE token_hash = ? AND expires_at > ?`
+	err = DB.QueryRow(query, hashToken(id), time.Now()).Scan(&data, &lastSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, nil
+	}
+	if err != nil {
+		return session, fmt.Errorf("lecture session: %w", err)
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		log.Printf("Session illisible ignorée: %v", err)
+		return session, nil
+	}
+	session.ID = id
+	session.IsNew = false
+
+	if time.Since(lastSeen) > sessionTouchInterval {
+		const touch = `UPDATE sessions SET last_seen_at = ?, ip = ?, user_agent = ? WHERE token_hash = ?`
+		if _, err := DB.Exec(touch, time.Now(), ClientIP(r), truncateUserAgent(r.UserAgent()), hashToken(id)); err != nil {
+			log.Printf("Erreur mise à jour session: %v", err)
+		}
+		if userID, ok := session.Values["user_id"].(int); ok {
+			RecordUserActivity(DB, userID, time.Now())
+		}
+	}
+	return session, nil
+}
+
+// Save enregistre la session et pose le cookie ; MaxAge < 0 la supprime
+func (s *DBStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if _, err := DB.Exec("DELETE FROM sessions WHERE token_hash = ?", hashToken(session.ID)); err != nil {
+				return fmt.Errorf("suppression session: %w", err)
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		id, err := generateSecret()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return fmt.Errorf("encodage session: %w", err)
+	}
+	var userID interface{}
+	if id, ok := session.Values["user_id"].(int); ok {
+		userID = id
+	}
+	now := time.Now()
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = SessionMaxAge
+	}
+
+	expiresAt := now.Add(time.Duration(maxAge) * time.Second)
+
+	// Une session existante n'est que mise à jour : si elle a été révoquée
+	// pendant la requête, elle ne doit pas être recréée
+	var err error
+	if session.IsNew {
+		const query = `INSERT INTO sessions (token_hash, user_id, data, ip, user_agent, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err = DB.Exec(query, hashToken(session.ID), userID, buf.Bytes(), ClientIP(r), truncateUserAgent(r.UserAgent()), now, now, expiresAt)
+		session.IsNew = false
+	} else {
+		const query = `UPDATE sessions SET user_id = ?, data = ?, last_seen_at = ?, expires_at = ? WHERE token_hash = ?`
+		_, err = DB.Exec(query, userID, buf.Bytes(), now, expiresAt, hashToken(session.ID))
+	}
+	if err != nil {
+		return fmt.Errorf("enregistrement session: %w", err)
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return fmt.Errorf("encodage cookie session: %w", err)
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+
+	if s.saves.Add(1)%1000 == 0 {
+		go PurgeExpiredSessions(DB)
+	}
+	return nil
+}
+
+// renewSession donne un nouvel identifiant à la session (après connexion),
+// pour qu'un identifiant connu avant l'authentification ne serve plus
+func renewSession(session *sessions.Session) error {
+	if session.ID != "" && !session.IsNew {
+		if _, err := DB.Exec("DELETE FROM sessions WHERE token_hash = ?", hashToken(session.ID)); err != nil {
+			return fmt.Errorf("renouvellement session: %w", err)
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+	return nil
+}
+
+func truncateUserAgent(ua string) string {
+	if len(ua) > 255 {
+		return ua[:255]
+	}
+	return ua
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+func PurgeExpiredSessions(db *sql.DB) {
+	if _, err := db.Exec("DELETE FROM sessions WHERE expires_at <= ?", time.Now()); err != nil {
+		log.Printf("Erreur purge sessions: %v", err)
+	}
+}
+
+// GetUserSessions liste les sessions ouvertes ; currentID est l'identifiant
+// de la session de la requête, pour la signaler
+func GetUserSessions(db *sql.DB, userID int, currentID string) ([]DeviceSession, error) {
+	const query = `SELECT id, token_hash, ip, user_agent, created_at, last_seen_at FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_seen_at DESC`
+	rows, err := db.Query(query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("liste sessions: %w", err)
+	}
+	defer rows.Close()
+
+	currentHash := hashToken(currentID)
+	var list []DeviceSession
+	for rows.Next() {
+		var d DeviceSession
+		var tokenHash string
+		if err := rows.Scan(&d.ID, &tokenHash, &d.IP, &d.UserAgent, &d.CreatedAt, &d.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		d.Current = currentID != "" && tokenHash == currentHash
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func RevokeUserSession(db *sql.DB, userID, sessionID int) error {
+	res, err := db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("révocation session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("session introuvable")
+	}
+	return nil
+}
+
+// RevokeUserSessions ferme toutes les sessions ouvertes de l'utilisateur
+func RevokeUserSessions(db *sql.DB, userID int) error {
+	if _, err := db.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("révocation sessions: %w", err)
+	}
+	return nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	var sessionID int
+	if _, err := fmt.Sscan(r.FormValue("session_id"), &sessionID); err != nil {
+		http.Error(w, "Identifiant invalide", http.StatusBadRequest)
+		return
+	}
+
+	notice := "Appareil déconnecté"
+	if err := RevokeUserSession(DB, userID, sessionID); err != nil {
+		notice = err.Error()
+	} else {
+		Audit(r, AuditEvent{Action: AuditSessionRevoke, TargetType: "session", TargetID: strconv.Itoa(sessionID)})
+	}
+	session.AddFlash(notice, profileNoticeKey)
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#devices", http.StatusSeeOther)
+}
+
+// HandleLogoutEverywhere ferme toutes les sessions, y compris la courante
+func (s *Server) HandleLogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := SessionUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if err := RevokeUserSessions(DB, userID); err != nil {
+		log.Printf("Erreur déconnexion globale utilisateur %d: %v", userID, err)
+		http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+		return
+	}
+	Audit(r, AuditEvent{ActorID: userID, Action: AuditLogoutAll})
+	if session, err := GetSession(r); err == nil {
+		session.Values = make(map[interface{}]interface{})
+		session.Options.MaxAge = -1
+		_ = SaveSession(w, r, session)
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -0,0 +1,350 @@
+package src
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ChartData est le format JSON lu par les graphiques de /admin
+type ChartData struct {
+	Labels []string      `json:"labels"`
+	Series []ChartSeries `json:"series"`
+}
+
+type ChartSeries struct {
+	Label string    `json:"label"`
+	Data  []float64 `json:"data"`
+}
+
+// DashboardSummary regroupe les indicateurs affichés en tête du tableau de bord
+type DashboardSummary struct {
+	Users          int
+	NewUsers       int // sur StatsDays jours
+	ActiveToday    int
+	Orders         int
+	Revenue        float64
+	Artists        int
+	GeocodeHits    int64
+	GeocodeMisses  int64
+	GeocodeCached  int
+	GeocodeHitRate float64 // en pourcentage, -1 sans aucun accès
+	RefreshedAt    string
+	RefreshError   string
+}
+
+type artistCount struct {
+	ArtistID int
+	Count    int
+}
+
+// dayLabels renvoie les days derniers jours, aujourd'hui compris
+func dayLabels(now time.Time, days int) []string {
+	labels := make([]string, days)
+	start := now.AddDate(0, 0, -(days - 1))
+	for i := range labels {
+		labels[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return labels
+}
+
+// monthLabels renvoie les months derniers mois, le mois courant compris
+func monthLabels(now time.Time, months int) []string {
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	labels := make([]string, months)
+	for i := range labels {
+		labels[i] = first.AddDate(0, i-(months-1), 0).Format("2006-01")
+	}
+	return labels
+}
+
+func seriesFromCounts(labels []string, counts map[string]float64) []float64 {
+	data := make([]float64, len(labels))
+	for i, label := range labels {
+		data[i] = counts[label]
+	}
+	return data
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+func countsByLabel(db *sql.DB, query string, args ...interface{}) (map[string]float64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]float64)
+	for rows.Next() {
+		var label string
+		var value float64
+		if err := rows.Scan(&label, &value); err != nil {
+			return nil, err
+		}
+		counts[label] = value
+	}
+	return counts, rows.Err()
+}
+
+// RegistrationStats renvoie les inscriptions par jour et le total cumulé
+func RegistrationStats(db *sql.DB, now time.Time, days int) (ChartData, error) {
+	labels := dayLabels(now, days)
+	var before int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE created_at < ?", labels[0]).Scan(&before); err != nil {
+		return ChartData{}, fmt.Errorf("comptage utilisateurs: %w", err)
+	}
+	const query = `SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS day, COUNT(*) FROM users WHERE created_at >= ? GROUP BY day`
+	counts, err := countsByLabel(db, query, labels[0])
+	if err != nil {
+		return ChartData{}, fmt.Errorf("statistiques inscriptions: %w", err)
+	}
+
+	daily := seriesFromCounts(labels, counts)
+	total := make([]float64, len(daily))
+	running := float64(before)
+	for i, n := range daily {
+		running += n
+		total[i] = running
+	}
+	return ChartData{Labels: labels, Series: []ChartSeries{
+		{Label: "Inscriptions", Data: daily},
+		{Label: "Utilisateurs inscrits", Data: total},
+	}}, nil
+}
+
+// ActivityStats renvoie le nombre d'utilisateurs connectés chaque jour
+func ActivityStats(db *sql.DB, now time.Time, days int) (ChartData, error) {
+	labels := dayLabels(now, days)
+	const query = `SELECT DATE_FORMAT(day, '%Y-%m-%d') AS d, COUNT(*) FROM user_activity WHERE day >= ? GROUP BY d`
+	counts, err := countsByLabel(db, query, labels[0])
+	if err != nil {
+		return ChartData{}, fmt.Errorf("statistiques activité: %w", err)
+	}
+	return ChartData{Labels: labels, Series: []ChartSeries{
+		{Label: "Utilisateurs actifs", Data: seriesFromCounts(labels, counts)},
+	}}, nil
+}
+
+func topArtists(db *sql.DB, query string, limit int) ([]artistCount, error) {
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var top []artistCount
+	for rows.Next() {
+		var c artistCount
+		if err := rows.Scan(&c.ArtistID, &c.Count); err != nil {
+			return nil, err
+		}
+		top = append(top, c)
+	}
+	return top, rows.Err()
+}
+
+func MostFavoritedArtists(db *sql.DB, limit int) ([]artistCount, error) {
+	const query = `SELECT artist_id, COUNT(*) AS n FROM favorites GROUP BY artist_id ORDER BY n DESC, artist_id LIMIT ?`
+	top, err := topArtists(db, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("artistes favoris: %w", err)
+	}
+	return top, nil
+}
+
+// MostCommentedArtists ignore les commentaires masqués des comptes bannis
+func MostCommentedArtists(db *sql.DB, limit int) ([]artistCount, error) {
+	const query = `SELECT c.artist_id, COUNT(*) AS n FROM comments c
+LEFT JOIN users u ON u.id = c.user_id
+WHERE u.banned_at IS NULL
+GROUP BY c.artist_id ORDER BY n DESC, c.artist_id LIMIT ?`
+	top, err := topArtists(db, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("artistes commentés: %w", err)
+	}
+	return top, nil
+}
+
+// RevenueStats renvoie le chiffre d'affaires mensuel des commandes payées,
+// une série par artiste parmi les limit premiers, les autres regroupés
+func RevenueStats(db *sql.DB, now time.Time, months, limit int) (ChartData, error) {
+	labels := monthLabels(now, months)
+	const query = `SELECT artist_name, DATE_FORMAT(COALESCE(captured_at, created_at), '%Y-%m') AS month, SUM(amount)
+FROM orders
+WHERE status = 'COMPLETED' AND COALESCE(captured_at, created_at) >= ?
+GROUP BY artist_name, month`
+	rows, err := db.Query(query, labels[0]+"-01")
+	if err != nil {
+		return ChartData{}, fmt.Errorf("statistiques chiffre d'affaires: %w", err)
+	}
+	defer rows.Close()
+
+	byArtist := make(map[string]map[string]float64)
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var artist, month string
+		var amount float64
+		if err := rows.Scan(&artist, &month, &amount); err != nil {
+			return ChartData{}, fmt.Errorf("scan chiffre d'affaires: %w", err)
+		}
+		if byArtist[artist] == nil {
+			byArtist[artist] = make(map[string]float64)
+		}
+		byArtist[artist][month] += amount
+		totals[artist] += amount
+	}
+	if err := rows.Err(); err != nil {
+		return ChartData{}, fmt.Errorf("statistiques chiffre d'affaires: %w", err)
+	}
+
+	artists := make([]string, 0, len(totals))
+	for artist := range totals {
+		artists = append(artists, artist)
+	}
+	sort.Slice(artists, func(i, j int) bool {
+		if totals[artists[i]] != totals[artists[j]] {
+			return totals[artists[i]] > totals[artists[j]]
+		}
+		return artists[i] < artists[j]
+	})
+
+	chart := ChartData{Labels: labels}
+	others := make(map[string]float64)
+	for i, artist := range artists {
+		if i < limit {
+			chart.Series = append(chart.Series, ChartSeries{Label: artist, Data: seriesFromCounts(labels, byArtist[artist])})
+			continue
+		}
+		for month, amount := range byArtist[artist] {
+			others[month] += amount
+		}
+	}
+	if len(others) > 0 {
+		chart.Series = append(chart.Series, ChartSeries{Label: "Autres", Data: seriesFromCounts(labels, others)})
+	}
+	return chart, nil
+}
+
+func (s *Server) dashboardSummary(db *sql.DB, now time.Time) (DashboardSummary, error) {
+	var sum DashboardSummary
+	const usersQuery = `SELECT COUNT(*), COALESCE(SUM(created_at >= ?), 0) FROM users`
+	if err := db.QueryRow(usersQuery, now.AddDate(0, 0, -StatsDays)).Scan(&sum.Users, &sum.NewUsers); err != nil {
+		return sum, fmt.Errorf("comptage utilisateurs: %w", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM user_activity WHERE day = ?", now.Format("2006-01-02")).Scan(&sum.ActiveToday); err != nil {
+		return sum, fmt.Errorf("comptage utilisateurs actifs: %w", err)
+	}
+	const ordersQuery = `SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM orders WHERE status = 'COMPLETED'`
+	if err := db.QueryRow(ordersQuery).Scan(&sum.Orders, &sum.Revenue); err != nil {
+		return sum, fmt.Errorf("comptage commandes: %w", err)
+	}
+
+	sum.Artists = len(s.ListArtists())
+	sum.GeocodeHits, sum.GeocodeMisses, sum.GeocodeCached = GeocodeCacheStats()
+	sum.GeocodeHitRate = -1
+	if lookups := sum.GeocodeHits + sum.GeocodeMisses; lookups > 0 {
+		sum.GeocodeHitRate = float64(sum.GeocodeHits) * 100 / float64(lookups)
+	}
+	refreshedAt, refreshErr := s.RefreshStatus()
+	if !refreshedAt.IsZero() {
+		sum.RefreshedAt = refreshedAt.Format("02/01/2006 15:04")
+	}
+	if refreshErr != nil {
+		sum.RefreshError = refreshErr.Error()
+	}
+	return sum, nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// HandleAdminDashboard affiche /admin ; les graphiques chargent /admin/stats/{chart}
+func (s *Server) HandleAdminDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	admin, ok := profileUser(w, r)
+	if !ok {
+		return
+	}
+	summary, err := s.dashboardSummary(DB, time.Now())
+	if err != nil {
+		log.Printf("Erreur tableau de bord: %v", err)
+		http.Error(w, "Erreur lors du calcul des statistiques", http.StatusInternalServerError)
+		return
+	}
+	s.Render(w, r, "admin-dashboard.html", AdminDashboardPageData{
+		Summary: summary,
+		User: &UserProfile{
+			ID:          admin.ID,
+			Username:    admin.Username,
+			Email:       admin.Email,
+			Pseudo:      getStringValue(admin.Pseudo),
+			PhotoProfil: getStringValue(admin.PhotoProfil),
+			Role:        admin.Role,
+		},
+	})
+}
+
+// artistChart nomme les artistes d'un classement
+func (s *Server) artistChart(label string, top []artistCount) ChartData {
+	chart := ChartData{Series: []ChartSeries{{Label: label}}}
+	for _, c := range top {
+		name := fmt.Sprintf("Artiste %d", c.ArtistID)
+		if artist, ok := s.FindArtist(c.ArtistID); ok {
+			name = artist.Name
+		}
+		chart.Labels = append(chart.Labels, name)
+		chart.Series[0].Data = append(chart.Series[0].Data, float64(c.Count))
+	}
+	return chart
+}
+
+func (s *Server) HandleAdminStats(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	var chart ChartData
+	var err error
+	switch r.PathValue("chart") {
+	case "registrations":
+		chart, err = RegistrationStats(DB, now, StatsDays)
+	case "activity":
+		chart, err = ActivityStats(DB, now, StatsDays)
+	case "favorites":
+		var top []artistCount
+		if top, err = MostFavoritedArtists(DB, StatsTopArtists); err == nil {
+			chart = s.artistChart("Favoris", top)
+		}
+	case "comments":
+		var top []artistCount
+		if top, err = MostCommentedArtists(DB, StatsTopArtists); err == nil {
+			chart = s.artistChart("Commentaires", top)
+		}
+	case "revenue":
+		chart, err = RevenueStats(DB, now, StatsMonths, StatsTopArtists)
+	default:
+		WriteAPIError(w, http.StatusNotFound, "not_found", "Statistique inconnue")
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur statistiques %s: %v", r.PathValue("chart"), err)
+		WriteAPIError(w, http.StatusInternalServerError, "internal_error", "Erreur lors du calcul des statistiques")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(chart); err != nil {
+		log.Printf("encodage statistiques: %v", err)
+	}
+}
+
+// RecordUserActivity note que l'utilisateur a utilisé le site ce jour-là
+func RecordUserActivity(db *sql.DB, userID int, now time.Time) {
+	if _, err := db.Exec("INSERT IGNORE INTO user_activity (user_id, day) VALUES (?, ?)", userID, now.Format("2006-01-02")); err != nil {
+		log.Printf("Erreur enregistrement activité utilisateur %d: %v", userID, err)
+	}
+}
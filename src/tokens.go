@@ -0,0 +1,416 @@
+package src
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TokenPrefix       = "gt_"
+	ScopeRead         = "read"
+	ScopeFavorites    = "favorites"
+	ScopeComments     = "comments"
+	MaxTokensPerUser  = 20
+	MaxTokenDays      = 365 // durée de validité maximale proposée sur le profil
+	tokenFlashKey     = "new_api_token"
+	tokenPrefixLength = 10
+)
+
+// TokenScopes liste les portées proposées à la création d'un jeton
+var TokenScopes = []TokenScope{
+	{Name: ScopeRead, Label: "Lecture seule (profil, favoris)"},
+	{Name: ScopeFavorites, Label: "Gestion des favoris"},
+	{Name: ScopeComments, Label: "Publication de commentaires"},
+}
+
+type TokenScope struct {
+	Name  string
+	Label string
+}
+
+// APIToken est un jeton d'accès personnel ; seul le hash SHA-256 est stocké
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	Prefix     string
+	Scopes     []string
+	LastUsedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
+func (t APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt.Valid && now.After(t.ExpiresAt.Time)
+}
+
+type contextKey string
+
+const (
+	ctxUserID   contextKey = "user_id"
+	ctxAPIToken contextKey = "api_token"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateToken() (string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	return TokenPrefix + secret, nil
+}
+
+// generateSecret renvoie 32 octets aléatoires encodés pour une URL
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("génération jeton: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// parseTokenExpiry lit la durée de validité choisie : 0 ou vide pour un jeton
+// sans expiration, sinon de 1 à MaxTokenDays jours
+func parseTokenExpiry(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 || days > MaxTokenDays {
+		return 0, fmt.Errorf("durée de validité invalide (0 à %d jours)", MaxTokenDays)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// CreateAPIToken crée un jeton et renvoie sa valeur en clair, affichée une seule fois
+func CreateAPIToken(db *sql.DB, userID int, name string, scopes []string, expiresIn time.Duration) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("nom du jeton requis")
+	}
+	if len(name) > 100 {
+		return "", errors.New("nom du jeton trop long (100 caractères max)")
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("au moins une portée est requise")
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", fmt.Errorf("portée invalide: %s", scope)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE user_id = ? AND revoked_at IS NULL", userID).Scan(&count); err != nil {
+		return "", fmt.Errorf("comptage jetons: %w", err)
+	}
+	if count >= MaxTokensPerUser {
+		return "", fmt.Errorf("nombre maximal de jetons atteint (%d)", MaxTokensPerUser)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	var expiresAt sql.NullTime
+	if expiresIn > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().Add(expiresIn), Valid: true}
+	}
+
+	const query = `INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.Exec(query, userID, name, hashToken(token), token[:tokenPrefixLength], strings.Join(scopes, ","), expiresAt); err != nil {
+		return "", fmt.Errorf("création jeton: %w", err)
+	}
+	return token, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s.Name == scope {
+			return true
+		}
+	}
+	return false
+}
+
+const apiTokenColumns = `id, user_id, name, token_prefix, scopes, last_used_at, expires_at, revoked_at, created_at`
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (APIToken, error) {
+	var t APIToken
+	var scopes string
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.LastUsedAt, &t.ExpiresAt, &t.RevokedAt, &t.CreatedAt); err != nil {
+		return APIToken{}, err
+	}
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	return t, nil
+}
+
+// GetAPITokenByValue retrouve un jeton actif à partir de sa valeur en clair
+func GetAPITokenByValue(db *sql.DB, token string) (APIToken, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return APIToken{}, errors.New("jeton invalide")
+	}
+	row := db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ? LIMIT 1`, hashToken(token))
+	t, err := scanAPIToken(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIToken{}, errors.New("jeton invalide")
+		}
+		return APIToken{}, fmt.Errorf("lecture jeton: %w", err)
+	}
+	if t.RevokedAt.Valid {
+		return APIToken{}, errors.New("jeton révoqué")
+	}
+	if t.Expired(time.Now()) {
+		return APIToken{}, errors.New("jeton expiré")
+	}
+	return t, nil
+}
+
+func GetUserAPITokens(db *sql.DB, userID int) ([]APIToken, error) {
+	rows, err := db.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("liste jetons: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan jeton: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func RevokeAPIToken(db *sql.DB, tokenID, userID int) error {
+	_, err := db.Exec("UPDATE api_tokens SET revoked_at = NOW() WHERE id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("révocation jeton: %w", err)
+	}
+	return nil
+}
+
+func TouchAPIToken(db *sql.DB, tokenID int) error {
+	_, err := db.Exec("UPDATE api_tokens SET last_used_at = NOW() WHERE id = ?", tokenID)
+	return err
+}
+
+// ─── Middleware ──────────────────────────────────────────────
+
+// BearerToken extrait le jeton de l'en-tête Authorization, s'il est présent
+func BearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// CurrentUserID renvoie l'utilisateur authentifié par jeton ou par session
+func CurrentUserID(r *http.Request) (int, bool) {
+	if userID, ok := r.Context().Value(ctxUserID).(int); ok {
+		return userID, true
+	}
+	return SessionUserID(r)
+}
+
+// CurrentAPIToken renvoie le jeton ayant authentifié la requête, le cas échéant
+func CurrentAPIToken(r *http.Request) (APIToken, bool) {
+	t, ok := r.Context().Value(ctxAPIToken).(APIToken)
+	return t, ok
+}
+
+// RequireAuthOrToken accepte un jeton Bearer portant la portée demandée,
+// sinon retombe sur l'authentification par session de RequireAuth
+func RequireAuthOrToken(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := BearerToken(r)
+		if !ok {
+			if strings.HasPrefix(r.URL.Path, APIVersionPrefix+"/") && !IsAuthenticated(r) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				WriteAPIError(w, http.StatusUnauthorized, "unauthorized", "Jeton d'accès requis")
+				return
+			}
+			RequireAuth(next).ServeHTTP(w, r)
+			return
+		}
+		token, err := GetAPITokenByValue(DB, raw)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			WriteAPIError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		}
+		if !token.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+			WriteAPIError(w, http.StatusForbidden, "insufficient_scope", "Portée requise: "+scope)
+			return
+		}
+		if sanction, suspended, err := GetActiveSanction(DB, token.UserID, time.Now()); err != nil {
+			log.Printf("Erreur vérification sanction utilisateur %d: %v", token.UserID, err)
+		} else if suspended {
+			WriteAPIError(w, http.StatusForbidden, "account_suspended", sanction.Message())
+			return
+		}
+		if err := TouchAPIToken(DB, token.ID); err != nil {
+			log.Printf("Erreur mise à jour jeton %d: %v", token.ID, err)
+		}
+		ctx := context.WithValue(r.Context(), ctxUserID, token.UserID)
+		ctx = context.WithValue(ctx, ctxAPIToken, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+// HandleCreateAPIToken crée un jeton depuis la page profil
+func (s *Server) HandleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête invalide", http.StatusBadRequest)
+		return
+	}
+
+	expiresIn, err := parseTokenExpiry(r.FormValue("expires_in_days"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := CreateAPIToken(DB, userID, r.FormValue("name"), r.Form["scopes"], expiresIn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditTokenCreate, TargetType: "token", TargetID: token[:tokenPrefixLength], After: strings.Join(r.Form["scopes"], ",")})
+
+	session.AddFlash(token, tokenFlashKey)
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#tokens", http.StatusSeeOther)
+}
+
+// HandleRevokeAPIToken révoque un jeton de l'utilisateur connecté
+func (s *Server) HandleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Error(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête invalide", http.StatusBadRequest)
+		return
+	}
+	tokenID, err := strconv.Atoi(r.FormValue("token_id"))
+	if err != nil || tokenID <= 0 {
+		http.Error(w, "ID jeton invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := RevokeAPIToken(DB, tokenID, userID); err != nil {
+		log.Printf("Erreur révocation jeton: %v", err)
+		http.Error(w, "Erreur lors de la révocation du jeton", http.StatusInternalServerError)
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditTokenRevoke, TargetType: "token", TargetID: strconv.Itoa(tokenID)})
+	http.Redirect(w, r, "/profile#tokens", http.StatusSeeOther)
+}
+
+// HandleAPIMe renvoie le profil du porteur du jeton (portée read)
+func (s *Server) HandleAPIMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		WriteAPIError(w, http.StatusUnauthorized, "unauthorized", "Non authentifié")
+		return
+	}
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		WriteAPIError(w, http.StatusNotFound, "not_found", "Utilisateur introuvable")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	WriteAPIJSON(w, r, http.StatusOK, APIItem{Data: APIUser{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Pseudo:      getStringValue(user.Pseudo),
+		Bio:         getStringValue(user.Bio),
+		PhotoProfil: getStringValue(user.PhotoProfil),
+		Role:        user.Role,
+	}})
+}
+
+// HandleAPIMyFavorites renvoie les artistes favoris du porteur du jeton (portée read)
+func (s *Server) HandleAPIMyFavorites(w http.ResponseWriter, r *http.Request) {
+	userID, ok := CurrentUserID(r)
+	if !ok {
+		WriteAPIError(w, http.StatusUnauthorized, "unauthorized", "Non authentifié")
+		return
+	}
+	ids, err := GetUserFavorites(DB, userID)
+	if err != nil {
+		log.Printf("Erreur lecture favoris: %v", err)
+		WriteAPIError(w, http.StatusInternalServerError, "internal_error", "Une erreur est survenue")
+		return
+	}
+	items := make([]APIArtist, 0, len(ids))
+	for _, id := range ids {
+		if art, found := s.FindArtist(id); found {
+			items = append(items, NewAPIArtist(art))
+		}
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	WriteAPIJSON(w, r, http.StatusOK, APIItem{Data: items})
+}
@@ -0,0 +1,33 @@
+package src
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTokenExpiry(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"1", 24 * time.Hour, false},
+		{"365", 365 * 24 * time.Hour, false},
+		{"366", 0, true},
+		{"-1", 0, true},
+		{"abc", 0, true},
+		{"106751991167300", 0, true}, // dépasserait time.Duration
+	}
+	for _, tt := range tests {
+		got, err := parseTokenExpiry(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTokenExpiry(%q): erreur %v, attendu erreur=%v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTokenExpiry(%q) = %v, attendu %v", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package src
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	TOTPIssuer         = "Groupie Tracker"
+	totpPeriod         = 30
+	totpDigits         = 6
+	totpSkew           = 1 // pas de 30 s acceptés avant/après l'heure courante
+	RecoveryCodeCount  = 10
+	login2FATimeout    = 5 * time.Minute
+	totpPendingKey     = "totp_pending_secret"
+	recoveryCodesFlash = "recovery_codes"
+)
+
+var (
+	ErrInvalidTOTPCode = errors.New("code de vérification invalide")
+	totpEncoding       = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// TOTPSetup contient ce qu'il faut afficher pendant l'enrôlement
+type TOTPSetup struct {
+	Secret string
+	URI    string
+}
+
+type TwoFactorPageData struct {
+	Error string
+}
+
+// ─── RFC 6238 ────────────────────────────────────────────────
+
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("génération secret TOTP: %w", err)
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI renvoie l'URI otpauth:// encodée dans le QR code
+func TOTPProvisioningURI(secret, account string) string {
+	label := url.PathEscape(TOTPIssuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", TOTPIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("secret TOTP invalide: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Troncature dynamique (RFC 4226 §5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// ValidateTOTP vérifie le code et renvoie le pas de temps correspondant ;
+// un pas déjà utilisé (lastStep) est refusé pour empêcher le rejeu
+func ValidateTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for delta := int64(-totpSkew); delta <= totpSkew; delta++ {
+		step := current + delta
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	codes := make([]string, RecoveryCodeCount)
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("génération codes de secours: %w", err)
+		}
+		var b strings.Builder
+		for j, c := range buf {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[int(c)%len(alphabet)])
+		}
+		codes[i] = b.String()
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == 10 && !strings.Contains(code, "-") {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
+
+// ─── Base de données ─────────────────────────────────────────
+
+// EnableTOTP active la double authentification et remplace les codes de secours
+func EnableTOTP(db *sql.DB, userID int, secret string, step int64) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction 2FA: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET totp_secret = ?, totp_enabled_at = ?, totp_last_step = ? WHERE id = ?", secret, time.Now(), step, userID); err != nil {
+		return nil, fmt.Errorf("activation 2FA: %w", err)
+	}
+	if err := replaceRecoveryCodes(tx, userID, codes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("validation 2FA: %w", err)
+	}
+	return codes, nil
+}
+
+func DisableTOTP(db *sql.DB, userID int) error {
+	if _, err := db.Exec("UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("désactivation 2FA: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("suppression codes de secours: %w", err)
+	}
+	return nil
+}
+
+func RegenerateRecoveryCodes(db *sql.DB, userID int) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction codes de secours: %w", err)
+	}
+	defer tx.Rollback()
+	if err := replaceRecoveryCodes(tx, userID, codes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("validation codes de secours: %w", err)
+	}
+	return codes, nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codes []string) error {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("suppression codes de secours: %w", err)
+	}
+	for _, code := range codes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hashToken(code)); err != nil {
+			return fmt.Errorf("création code de secours: %w", err)
+		}
+	}
+	return nil
+}
+
+func CountRecoveryCodes(db *sql.DB, userID int) (int, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("comptage codes de secours: %w", err)
+	}
+	return count, nil
+}
+
+// VerifySecondFactor accepte un code TOTP ou un code de secours (consommé)
+func VerifySecondFactor(db *sql.DB, user User, code string) error {
+	if !user.TOTPEnabledAt.Valid || !user.TOTPSecret.Valid {
+		return errors.New("double authentification non activée")
+	}
+
+	var lastStep int64
+	if err := db.QueryRow("SELECT totp_last_step FROM users WHERE id = ?", user.ID).Scan(&lastStep); err != nil {
+		return fmt.Errorf("lecture 2FA: %w", err)
+	}
+	if step, ok := ValidateTOTP(user.TOTPSecret.String, code, time.Now(), lastStep); ok {
+		// La condition sur le pas évite qu'une requête concurrente rejoue le même code
+		res, err := db.Exec("UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?", step, user.ID, step)
+		if err != nil {
+			return fmt.Errorf("mise à jour 2FA: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInvalidTOTPCode
+		}
+		return nil
+	}
+
+	res, err := db.Exec("UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now(), user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("vérification code de secours: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		log.Printf("Code de secours utilisé par l'utilisateur %d", user.ID)
+		return nil
+	}
+	return ErrInvalidTOTPCode
+}
+
+// ─── Politique ───────────────────────────────────────────────
+
+// TwoFactorRequired indique si le rôle de l'utilisateur impose la 2FA :
+// c'est le cas de tout rôle donnant accès à l'administration
+func TwoFactorRequired(user User) bool {
+	return RequireAdmin2FA && IsStaffRole(user.Role)
+}
+
+// ─── Handlers : connexion ────────────────────────────────────
+
+// startLogin2FA mémorise l'utilisateur dont le mot de passe est validé,
+// sans lui ouvrir de session tant que le second facteur manque
+func startLogin2FA(w http.ResponseWriter, r *http.Request, user User) error {
+	session, err := GetSession(r)
+	if err != nil {
+		return err
+	}
+	session.Values = make(map[interface{}]interface{})
+	session.Values["pending_2fa_user_id"] = user.ID
+	session.Values["pending_2fa_at"] = time.Now().Unix()
+	session.Values["pending_2fa_failures"] = 0
+	return SaveSession(w, r, session)
+}
+
+// clearLogin2FA abandonne la connexion en attente du second facteur
+func clearLogin2FA(session *sessions.Session) {
+	delete(session.Values, "pending_2fa_user_id")
+	delete(session.Values, "pending_2fa_at")
+	delete(session.Values, "pending_2fa_failures")
+}
+
+func (s *Server) HandleLogin2FA(w http.ResponseWriter, r *http.Request) {
+	session, err := GetSession(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	userID, ok := session.Values["pending_2fa_user_id"].(int)
+	startedAt, _ := session.Values["pending_2fa_at"].(int64)
+	if !ok || time.Since(time.Unix(startedAt, 0)) > login2FATimeout {
+		clearLogin2FA(session)
+		_ = SaveSession(w, r, session)
+		s.Render(w, r, "login.html", LoginPageData{Error: "Session de connexion expirée, veuillez recommencer"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.Render(w, r, "login-2fa.html", TwoFactorPageData{})
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			s.Render(w, r, "login-2fa.html", TwoFactorPageData{Error: "Erreur lors du traitement du formulaire"})
+			return
+		}
+		user, err := GetUserByID(DB, userID)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		// Les codes erronés suivent les délais et le verrouillage de la connexion
+		ip, now := ClientIP(r), time.Now()
+		if err := CheckLoginAllowed(DB, &user, user.Email, ip, now); err != nil {
+			var throttled *LoginThrottledError
+			switch {
+			case errors.Is(err, ErrAccountLocked):
+				clearLogin2FA(session)
+				_ = SaveSession(w, r, session)
+				s.Render(w, r, "login.html", LoginPageData{Error: err.Error()})
+				return
+			case errors.As(err, &throttled):
+				s.Render(w, r, "login-2fa.html", TwoFactorPageData{Error: err.Error()})
+				return
+			}
+			log.Printf("Erreur vérification tentatives 2FA: %v", err)
+		}
+
+		if err := VerifySecondFactor(DB, user, r.FormValue("code")); err != nil {
+			if !errors.Is(err, ErrInvalidTOTPCode) {
+				log.Printf("Erreur 2FA utilisateur %d: %v", userID, err)
+			}
+			s.login2FAFailed(w, r, session, user, ip, now)
+			return
+		}
+		clearLogin2FA(session)
+		if err := RecordLoginSuccess(DB, user.Email, ip, now); err != nil {
+			log.Printf("Erreur enregistrement connexion: %v", err)
+		}
+		if err := StartUserSession(w, r, user); err != nil {
+			s.Render(w, r, "login.html", LoginPageData{Error: "Impossible de sauvegarder la session"})
+			return
+		}
+		Audit(r, AuditEvent{Action: AuditLogin, After: "2fa"})
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	default:
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+	}
+}
+
+// login2FAFailed compte un code erroné : au-delà de Login2FAAttempts, ou si
+// le compte vient d'être verrouillé, il faut recommencer depuis le mot de passe
+func (s *Server) login2FAFailed(w http.ResponseWriter, r *http.Request, session *sessions.Session, user User, ip string, now time.Time) {
+	locked, err := RecordLoginFailure(DB, &user, user.Email, ip, now)
+	if err != nil {
+		log.Printf("Erreur enregistrement échec 2FA: %v", err)
+	}
+	failures, _ := session.Values["pending_2fa_failures"].(int)
+	failures++
+	session.Values["pending_2fa_failures"] = failures
+
+	switch {
+	case locked:
+		clearLogin2FA(session)
+		_ = SaveSession(w, r, session)
+		Audit(r, AuditEvent{ActorName: user.Email, Action: AuditLoginLocked, TargetType: "user", TargetID: strconv.Itoa(user.ID)})
+		s.Render(w, r, "login.html", LoginPageData{Error: ErrAccountLocked.Error()})
+	case failures >= Login2FAAttempts:
+		clearLogin2FA(session)
+		_ = SaveSession(w, r, session)
+		log.Printf("Connexion 2FA abandonnée pour l'utilisateur %d après %d codes erronés", user.ID, failures)
+		s.Render(w, r, "login.html", LoginPageData{Error: "Trop de codes incorrects, veuillez vous reconnecter"})
+	default:
+		_ = SaveSession(w, r, session)
+		s.Render(w, r, "login-2fa.html", TwoFactorPageData{Error: ErrInvalidTOTPCode.Error()})
+	}
+}
+
+// ─── Handlers : profil ───────────────────────────────────────
+
+// HandleTOTPSetup génère un secret en attente de confirmation (gardé en session)
+func (s *Server) HandleTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Erreur génération secret TOTP: %v", err)
+		http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+		return
+	}
+	session.Values[totpPendingKey] = secret
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+}
+
+func (s *Server) HandleTOTPEnable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	secret, ok := session.Values[totpPendingKey].(string)
+	if !ok {
+		http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête invalide", http.StatusBadRequest)
+		return
+	}
+
+	step, valid := ValidateTOTP(secret, r.FormValue("code"), time.Now(), 0)
+	if !valid {
+		session.AddFlash("Code incorrect : vérifiez l'heure de votre téléphone et réessayez", profileNoticeKey)
+		_ = SaveSession(w, r, session)
+		http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+		return
+	}
+	codes, err := EnableTOTP(DB, userID, secret, step)
+	if err != nil {
+		log.Printf("Erreur activation 2FA: %v", err)
+		http.Error(w, "Impossible d'activer la double authentification", http.StatusInternalServerError)
+		return
+	}
+	Audit(r, AuditEvent{Action: AuditTwoFactorOn, TargetType: "user", TargetID: strconv.Itoa(userID)})
+	delete(session.Values, totpPendingKey)
+	session.AddFlash(codes, recoveryCodesFlash)
+	session.AddFlash("Double authentification activée", profileNoticeKey)
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+}
+
+// HandleTOTPDisable désactive la 2FA après confirmation du mot de passe
+func (s *Server) HandleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return
+	}
+
+	notice := "Double authentification désactivée"
+	if TwoFactorRequired(user) {
+		notice = "La double authentification est obligatoire pour votre rôle"
+	} else if checkPassword(user.PasswordHash, r.FormValue("password")) != nil {
+		notice = "Mot de passe incorrect"
+	} else if err := DisableTOTP(DB, userID); err != nil {
+		log.Printf("Erreur désactivation 2FA: %v", err)
+		notice = "Impossible de désactiver la double authentification"
+	} else {
+		Audit(r, AuditEvent{Action: AuditTwoFactorOff, TargetType: "user", TargetID: strconv.Itoa(userID)})
+	}
+	session.AddFlash(notice, profileNoticeKey)
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+}
+
+// HandleRecoveryCodesRegenerate remplace les codes de secours (code TOTP requis)
+func (s *Server) HandleRecoveryCodesRegenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return
+	}
+
+	if err := VerifySecondFactor(DB, user, r.FormValue("code")); err != nil {
+		session.AddFlash(ErrInvalidTOTPCode.Error(), profileNoticeKey)
+	} else if codes, err := RegenerateRecoveryCodes(DB, userID); err != nil {
+		log.Printf("Erreur régénération codes de secours: %v", err)
+		session.AddFlash("Impossible de générer de nouveaux codes", profileNoticeKey)
+	} else {
+		session.AddFlash(codes, recoveryCodesFlash)
+	}
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile#2fa", http.StatusSeeOther)
+}
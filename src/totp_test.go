@@ -0,0 +1,87 @@
+package src
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret SHA1 des vecteurs de test de la RFC 6238 : "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Codes à 8 chiffres de l'annexe B, tronqués aux 6 chiffres utilisés ici
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.want[len(tt.want)-totpDigits:]; got != want {
+			t.Errorf("T=%d: code %s, attendu %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestTOTPCodeLowercaseSecret(t *testing.T) {
+	upper, _ := totpCode(rfc6238Secret, 1)
+	lower, err := totpCode(strings.ToLower(rfc6238Secret), 1)
+	if err != nil || lower != upper {
+		t.Errorf("secret en minuscules: %q %v, attendu %q", lower, err, upper)
+	}
+	if _, err := totpCode("pas du base32 !", 1); err == nil {
+		t.Error("secret invalide accepté")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	code := func(step int64) string {
+		c, _ := totpCode(rfc6238Secret, step)
+		return c
+	}
+
+	if got, ok := ValidateTOTP(rfc6238Secret, code(step), now, 0); !ok || got != step {
+		t.Errorf("code courant refusé: %d %v", got, ok)
+	}
+	previous := code(step - 1)
+	if got, ok := ValidateTOTP(rfc6238Secret, " "+previous[:3]+" "+previous[3:], now, 0); !ok || got != step-1 {
+		t.Errorf("code précédent avec espaces refusé: %d %v", got, ok)
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code(step+1), now, 0); !ok {
+		t.Error("code suivant refusé malgré la tolérance")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code(step+2), now, 0); ok {
+		t.Error("code hors tolérance accepté")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code(step), now, step); ok {
+		t.Error("code rejoué accepté")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code(step)+"0", now, 0); ok {
+		t.Error("code de mauvaise longueur accepté")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := map[string]string{
+		"abcde-fghjk":   "abcde-fghjk",
+		" ABCDE FGHJK ": "abcde-fghjk",
+		"abcdefghjk":    "abcde-fghjk",
+	}
+	for in, want := range tests {
+		if got := normalizeRecoveryCode(in); got != want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, attendu %q", in, got, want)
+		}
+	}
+}
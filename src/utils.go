@@ -1,9 +1,12 @@
 package src
 
 import (
+	"fmt"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func BuildLocationDates(relations map[string][]string) []LocationDates {
@@ -40,6 +43,159 @@ func FilterArtists(artists []Artist, query string) []Artist {
 	return matches
 }
 
+// ParseArtistFilters lit les critères de filtrage depuis les paramètres d'URL
+func ParseArtistFilters(values url.Values) (ArtistFilters, error) {
+	filters := ArtistFilters{
+		Query:     strings.TrimSpace(values.Get("q")),
+		Location:  strings.TrimSpace(values.Get("location")),
+		Favorites: values.Get("favorites") == "1",
+	}
+	intParams := []struct {
+		name   string
+		target *int
+	}{
+		{"min_creation", &filters.MinCreation},
+		{"max_creation", &filters.MaxCreation},
+		{"min_first_album", &filters.MinFirstAlbum},
+		{"max_first_album", &filters.MaxFirstAlbum},
+	}
+	for _, param := range intParams {
+		raw := strings.TrimSpace(values.Get(param.name))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return ArtistFilters{}, fmt.Errorf("paramètre %s invalide", param.name)
+		}
+		*param.target = n
+	}
+	for _, raw := range values["members"] {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil || n <= 0 {
+				return ArtistFilters{}, fmt.Errorf("paramètre members invalide")
+			}
+			filters.MembersCount = append(filters.MembersCount, n)
+		}
+	}
+	return filters, nil
+}
+
+// Values reconstruit les paramètres d'URL correspondant aux filtres
+func (f ArtistFilters) Values() url.Values {
+	values := url.Values{}
+	if f.Query != "" {
+		values.Set("q", f.Query)
+	}
+	if f.Location != "" {
+		values.Set("location", f.Location)
+	}
+	if f.Favorites {
+		values.Set("favorites", "1")
+	}
+	intParams := map[string]int{
+		"min_creation":    f.MinCreation,
+		"max_creation":    f.MaxCreation,
+		"min_first_album": f.MinFirstAlbum,
+		"max_first_album": f.MaxFirstAlbum,
+	}
+	for name, n := range intParams {
+		if n > 0 {
+			values.Set(name, strconv.Itoa(n))
+		}
+	}
+	if len(f.MembersCount) > 0 {
+		parts := make([]string, len(f.MembersCount))
+		for i, n := range f.MembersCount {
+			parts[i] = strconv.Itoa(n)
+		}
+		values.Set("members", strings.Join(parts, ","))
+	}
+	return values
+}
+
+// HasFilters indique si un filtre autre que la recherche textuelle est actif
+func (f ArtistFilters) HasFilters() bool {
+	return f.MinCreation > 0 || f.MaxCreation > 0 || f.MinFirstAlbum > 0 || f.MaxFirstAlbum > 0 ||
+		len(f.MembersCount) > 0 || f.Location != "" || f.Favorites
+}
+
+// ApplyArtistFilters applique la recherche textuelle puis les filtres
+func ApplyArtistFilters(artists []Artist, filters ArtistFilters) []Artist {
+	matches := FilterArtists(artists, filters.Query)
+	result := make([]Artist, 0, len(matches))
+	for _, art := range matches {
+		if ArtistPassesFilters(art, filters) {
+			result = append(result, art)
+		}
+	}
+	return result
+}
+
+func ArtistPassesFilters(art Artist, filters ArtistFilters) bool {
+	if filters.MinCreation > 0 && art.CreationDate < filters.MinCreation {
+		return false
+	}
+	if filters.MaxCreation > 0 && art.CreationDate > filters.MaxCreation {
+		return false
+	}
+	if filters.MinFirstAlbum > 0 || filters.MaxFirstAlbum > 0 {
+		year := FirstAlbumYear(art.FirstAlbum)
+		if filters.MinFirstAlbum > 0 && year < filters.MinFirstAlbum {
+			return false
+		}
+		if filters.MaxFirstAlbum > 0 && year > filters.MaxFirstAlbum {
+			return false
+		}
+	}
+	if len(filters.MembersCount) > 0 {
+		found := false
+		for _, n := range filters.MembersCount {
+			if len(art.Members) == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filters.Location != "" {
+		needle := strings.ToLower(filters.Location)
+		found := false
+		for _, location := range art.Locations {
+			if strings.Contains(strings.ToLower(location), needle) || strings.Contains(strings.ToLower(FormatLocation(location)), needle) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FirstAlbumYear extrait l'année d'une date au format jj-mm-aaaa
+func FirstAlbumYear(value string) int {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	year, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// ParseConcertDate convertit une date de concert jj-mm-aaaa en time.Time
+func ParseConcertDate(value string) (time.Time, error) {
+	return time.Parse("02-01-2006", strings.TrimPrefix(strings.TrimSpace(value), "*"))
+}
+
 func ArtistMatches(art Artist, needle string) bool {
 	if strings.Contains(strings.ToLower(art.Name), needle) {
 		return true
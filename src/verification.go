@@ -0,0 +1,213 @@
+package src
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clé de session des messages affichés une fois sur la page profil
+const profileNoticeKey = "profile_notice"
+
+var ErrInvalidVerificationToken = errors.New("lien de vérification invalide ou expiré")
+
+// ─── Base de données ─────────────────────────────────────────
+
+// CreateEmailVerification crée un jeton confirmant que l'utilisateur
+// contrôle l'adresse email ; les demandes précédentes sont annulées
+func CreateEmailVerification(db *sql.DB, userID int, email string) (string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.Exec("UPDATE email_verifications SET used_at = ? WHERE user_id = ? AND used_at IS NULL", time.Now(), userID); err != nil {
+		return "", fmt.Errorf("annulation vérifications: %w", err)
+	}
+	const query = `INSERT INTO email_verifications (user_id, email, token_hash, expires_at) VALUES (?, ?, ?, ?)`
+	if _, err := db.Exec(query, userID, strings.ToLower(strings.TrimSpace(email)), hashToken(token), time.Now().Add(EmailVerifyTTL)); err != nil {
+		return "", fmt.Errorf("création vérification: %w", err)
+	}
+	return token, nil
+}
+
+// VerifyEmail consomme le jeton et marque l'adresse comme vérifiée.
+// Si le jeton porte une autre adresse que celle du compte, elle la remplace
+// et changed vaut true.
+func VerifyEmail(db *sql.DB, token string) (userID int, changed bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("transaction vérification: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var verificationID int
+	var email, current string
+	const query = `SELECT v.id, v.user_id, v.email, u.email FROM email_verifications v JOIN users u ON u.id = v.user_id
+WHERE v.token_hash = ? AND v.used_at IS NULL AND v.expires_at > ? FOR UPDATE`
+	if err := tx.QueryRow(query, hashToken(token), now).Scan(&verificationID, &userID, &email, &current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, ErrInvalidVerificationToken
+		}
+		return 0, false, fmt.Errorf("lecture vérification: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE email_verifications SET used_at = ? WHERE id = ?", now, verificationID); err != nil {
+		return 0, false, fmt.Errorf("consommation vérification: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE users SET email = ?, email_verified_at = ? WHERE id = ?", email, now, userID); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
+			return 0, false, fmt.Errorf("un compte existe déjà avec cet email")
+		}
+		return 0, false, fmt.Errorf("mise à jour vérification: %w", err)
+	}
+	changed = email != current
+	if changed {
+		// Les sessions ouvertes portent l'ancienne adresse
+		if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+			return 0, false, fmt.Errorf("révocation sessions: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("validation vérification: %w", err)
+	}
+	return userID, changed, nil
+}
+
+func IsEmailVerified(db *sql.DB, userID int) (bool, error) {
+	var verifiedAt sql.NullTime
+	if err := db.QueryRow("SELECT email_verified_at FROM users WHERE id = ?", userID).Scan(&verifiedAt); err != nil {
+		return false, fmt.Errorf("lecture vérification email: %w", err)
+	}
+	return verifiedAt.Valid, nil
+}
+
+// SendEmailVerification envoie le lien de vérification à l'adresse donnée
+func SendEmailVerification(user User, email string) error {
+	token, err := CreateEmailVerification(DB, user.ID, email)
+	if err != nil {
+		return err
+	}
+	link := BaseURL + "/email/verify?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(`Bonjour %s,
+
+Merci de confirmer votre adresse email pour votre compte Groupie Tracker en ouvrant ce lien (valable %d heures) :
+
+%s
+
+Si vous n'avez pas créé de compte, ignorez ce message.
+`, user.Username, int(EmailVerifyTTL.Hours()), link)
+
+	return CurrentMailer().Send(Mail{To: email, Subject: "Confirmez votre adresse email", Body: body})
+}
+
+// ─── Middleware ──────────────────────────────────────────────
+
+// RequireVerified réserve la route aux comptes dont l'email est vérifié
+// (si REQUIRE_VERIFIED_EMAIL n'est pas désactivé). À placer après
+// RequireAuth ou RequireAuthOrToken.
+func RequireVerified(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !RequireVerifiedEmail {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := CurrentUserID(r)
+		if !ok {
+			http.Error(w, "Non authentifié", http.StatusUnauthorized)
+			return
+		}
+		verified, err := IsEmailVerified(DB, userID)
+		if err != nil {
+			log.Printf("Erreur vérification email utilisateur %d: %v", userID, err)
+			http.Error(w, "Une erreur est survenue", http.StatusInternalServerError)
+			return
+		}
+		if !verified {
+			const message = "Confirmez votre adresse email pour utiliser cette fonctionnalité"
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				WriteAPIError(w, http.StatusForbidden, "email_not_verified", message)
+				return
+			}
+			http.Error(w, message, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────
+
+func (s *Server) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		s.Render(w, r, "verify-email.html", LoginPageData{Error: ErrInvalidVerificationToken.Error()})
+		return
+	}
+	sessionUserID, _ := SessionUserID(r)
+	userID, changed, err := VerifyEmail(DB, token)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidVerificationToken) {
+			log.Printf("Erreur vérification email: %v", err)
+		}
+		s.Render(w, r, "verify-email.html", LoginPageData{Error: err.Error()})
+		return
+	}
+	if !changed {
+		s.Render(w, r, "verify-email.html", LoginPageData{Message: "Votre adresse email est confirmée, merci !"})
+		return
+	}
+
+	Audit(r, AuditEvent{ActorID: userID, Action: AuditEmailChange, TargetType: "user", TargetID: strconv.Itoa(userID)})
+
+	// Changement d'adresse : les sessions ont été fermées, celle de ce
+	// navigateur est rouverte sous un nouvel identifiant
+	if sessionUserID == userID {
+		if user, err := GetUserByID(DB, userID); err == nil {
+			_ = StartUserSession(w, r, user)
+		}
+	}
+	s.Render(w, r, "verify-email.html", LoginPageData{Message: "Votre nouvelle adresse email est confirmée. Vos autres appareils ont été déconnectés."})
+}
+
+func (s *Server) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non supportée", http.StatusMethodNotAllowed)
+		return
+	}
+	session, err := GetSession(r)
+	if err != nil {
+		http.Error(w, "Session indisponible", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	user, err := GetUserByID(DB, userID)
+	if err != nil {
+		http.Error(w, "Utilisateur introuvable", http.StatusNotFound)
+		return
+	}
+
+	notice := "Un nouveau lien de vérification a été envoyé à " + user.Email
+	if user.EmailVerifiedAt.Valid {
+		notice = "Votre adresse email est déjà vérifiée"
+	} else if err := SendEmailVerification(user, user.Email); err != nil {
+		log.Printf("Erreur envoi vérification à l'utilisateur %d: %v", user.ID, err)
+		notice = "L'email de vérification n'a pas pu être envoyé, réessayez plus tard"
+	}
+	session.AddFlash(notice, profileNoticeKey)
+	_ = SaveSession(w, r, session)
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}